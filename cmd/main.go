@@ -18,6 +18,9 @@ func main() {
 	httpPort := flag.String("port", "8000", "http port")
 	tcpPort := flag.String("node-port", "8080", "tcp port")
 	dbDSN := flag.String("dsn", "blockchain_db.sqlite", "database data source name")
+	genesisVestingFile := flag.String("genesis-vesting", "", "JSON file of vesting accounts opened by a new genesis block")
+	chainId := flag.String("chain-id", blockchain.DefaultChainId, "network id every transaction signature is bound to")
+	banIPs := flag.Bool("ban-ips", false, "also refuse every connection from the IP of a banned peer")
 
 	flag.Parse()
 
@@ -39,13 +42,18 @@ func main() {
 	mempool := blockchain.NewMempool(1048576)
 
 	// Load or initialize blockchain
-	bc, err := blockchain.LoadBlockchain(dbInstance, mempool)
+	bc, err := blockchain.LoadBlockchain(dbInstance, mempool, *chainId)
 	if err != nil {
 		panic(err)
 	}
 
 	if len(bc.Blocks) == 0 {
-		bc, err = blockchain.NewBlockchain(dbInstance, mempool)
+		genesisVesting, err := blockchain.LoadGenesisVesting(*genesisVestingFile)
+		if err != nil {
+			panic(err)
+		}
+
+		bc, err = blockchain.NewBlockchain(dbInstance, mempool, *chainId, genesisVesting...)
 		if err != nil {
 			panic(err)
 		}
@@ -62,13 +70,20 @@ func main() {
 		panic(err)
 	}
 
+	node.BanIPs = *banIPs
+
 	// Load existing peers from DB if any
 	allPeers, err := node.Blockchain.Database.LoadPeers()
 	if err != nil {
 		panic(err)
 	}
 
-	node.Peers = allPeers
+	node.LoadPeers(allPeers)
+
+	// Keep refusing the peers banned before a restart
+	if err := node.LoadBans(); err != nil {
+		panic(err)
+	}
 
 	// Bootstrap node with DNS seeds
 	go node.Bootstrap()
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS channels (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_id TEXT NOT NULL UNIQUE,
+    sender TEXT NOT NULL,
+    sender_public_key TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    deposit INTEGER NOT NULL DEFAULT (0),
+    dispute_period INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT ('open') CHECK (status IN ('open', 'closing', 'settled')),
+    nonce INTEGER NOT NULL DEFAULT (0),
+    paid INTEGER NOT NULL DEFAULT (0),
+    close_fee INTEGER NOT NULL DEFAULT (0),
+    close_height INTEGER NOT NULL DEFAULT (0),
+    settle_tx TEXT NULL
+);
+CREATE INDEX idx_channels_recipient ON channels (recipient);
+CREATE INDEX idx_channels_sender ON channels (sender);
+-- +goose Down
+DROP INDEX IF EXISTS idx_channels_sender;
+DROP INDEX IF EXISTS idx_channels_recipient;
+DROP TABLE IF EXISTS channels;
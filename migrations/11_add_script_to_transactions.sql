@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN script TEXT NULL;
+ALTER TABLE transactions ADD COLUMN witness TEXT NULL;
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN witness;
+ALTER TABLE transactions DROP COLUMN script;
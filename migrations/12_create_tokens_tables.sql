@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS assets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    asset_id TEXT NOT NULL UNIQUE,
+    symbol TEXT NOT NULL,
+    decimals INTEGER NOT NULL DEFAULT (0),
+    max_supply INTEGER NOT NULL,
+    supply INTEGER NOT NULL DEFAULT (0) CHECK (supply <= max_supply),
+    issuer TEXT NOT NULL,
+    issuer_public_key TEXT NULL
+);
+CREATE INDEX idx_assets_symbol ON assets (symbol);
+CREATE TABLE IF NOT EXISTS token_balances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    address TEXT NOT NULL,
+    asset_id TEXT NOT NULL,
+    balance INTEGER NOT NULL DEFAULT (0),
+    UNIQUE (address, asset_id),
+    FOREIGN KEY (asset_id) REFERENCES assets (asset_id) ON DELETE CASCADE
+);
+-- +goose Down
+DROP TABLE IF EXISTS token_balances;
+DROP INDEX IF EXISTS idx_assets_symbol;
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS names (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    owner TEXT NOT NULL,
+    expiry INTEGER NOT NULL,
+    registered_height INTEGER NOT NULL,
+    last_tx TEXT NOT NULL
+);
+CREATE INDEX idx_names_owner ON names (owner);
+-- +goose Down
+DROP INDEX IF EXISTS idx_names_owner;
+DROP TABLE IF EXISTS names;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS vesting_schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    address TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    start_height INTEGER NOT NULL,
+    end_height INTEGER NOT NULL CHECK (end_height > start_height),
+    created_tx TEXT NOT NULL
+);
+CREATE INDEX idx_vesting_schedules_address ON vesting_schedules (address);
+-- +goose Down
+DROP INDEX IF EXISTS idx_vesting_schedules_address;
+DROP TABLE IF EXISTS vesting_schedules;
@@ -0,0 +1,4 @@
+-- +goose Up
+ALTER TABLE blocks ADD COLUMN base_fee INTEGER NOT NULL DEFAULT (0);
+-- +goose Down
+ALTER TABLE blocks DROP COLUMN base_fee;
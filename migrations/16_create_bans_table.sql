@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS bans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    address TEXT UNIQUE NOT NULL,
+    ip TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    banned_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+CREATE INDEX idx_bans_ip ON bans (ip);
+-- +goose Down
+DROP INDEX IF EXISTS idx_bans_ip;
+DROP TABLE IF EXISTS bans;
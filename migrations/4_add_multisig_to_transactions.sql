@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN multisig_keys TEXT NULL;
+ALTER TABLE transactions ADD COLUMN threshold INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE transactions ADD COLUMN signatures TEXT NULL;
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN signatures;
+ALTER TABLE transactions DROP COLUMN threshold;
+ALTER TABLE transactions DROP COLUMN multisig_keys;
@@ -0,0 +1,4 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN lock_time INTEGER NOT NULL DEFAULT (0);
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN lock_time;
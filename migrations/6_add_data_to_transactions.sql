@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN data TEXT NULL;
+CREATE INDEX idx_transactions_data ON transactions (data);
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_data;
+ALTER TABLE transactions DROP COLUMN data;
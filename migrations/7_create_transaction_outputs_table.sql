@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS transaction_outputs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id INTEGER NOT NULL,
+    output_index INTEGER NOT NULL,
+    recipient TEXT NOT NULL,
+    amount INTEGER NOT NULL DEFAULT (0),
+    UNIQUE (transaction_id, output_index),
+    FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_transaction_outputs_recipient ON transaction_outputs (recipient);
+-- +goose Down
+DROP INDEX IF EXISTS idx_transaction_outputs_recipient;
+DROP TABLE IF EXISTS transaction_outputs;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN fee_payer TEXT NULL;
+ALTER TABLE transactions ADD COLUMN fee_payer_public_key TEXT NULL;
+ALTER TABLE transactions ADD COLUMN fee_payer_signature TEXT NULL;
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN fee_payer_signature;
+ALTER TABLE transactions DROP COLUMN fee_payer_public_key;
+ALTER TABLE transactions DROP COLUMN fee_payer;
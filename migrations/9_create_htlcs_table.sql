@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN tx_type TEXT NULL;
+ALTER TABLE transactions ADD COLUMN payload TEXT NULL;
+CREATE TABLE IF NOT EXISTS htlcs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    htlc_id TEXT NOT NULL UNIQUE,
+    sender TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    amount INTEGER NOT NULL DEFAULT (0),
+    hash_lock TEXT NOT NULL,
+    expiry INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT ('locked') CHECK (status IN ('locked', 'claimed', 'refunded')),
+    preimage TEXT NULL,
+    settle_tx TEXT NULL
+);
+CREATE INDEX idx_htlcs_recipient ON htlcs (recipient);
+CREATE INDEX idx_htlcs_sender ON htlcs (sender);
+-- +goose Down
+DROP INDEX IF EXISTS idx_htlcs_sender;
+DROP INDEX IF EXISTS idx_htlcs_recipient;
+DROP TABLE IF EXISTS htlcs;
+ALTER TABLE transactions DROP COLUMN payload;
+ALTER TABLE transactions DROP COLUMN tx_type;
@@ -0,0 +1,65 @@
+package CryptoGraphy
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxMultisigKeys -> Upper bound of N in an M-of-N address
+const MaxMultisigKeys = 15
+
+// SortPublicKeys -> Validates and normalizes (lowercase, ascending) a multisig key set
+func SortPublicKeys(pubKeysHex []string) ([]string, error) {
+	if len(pubKeysHex) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+
+	if len(pubKeysHex) > MaxMultisigKeys {
+		return nil, fmt.Errorf("at most %d public keys are allowed", MaxMultisigKeys)
+	}
+
+	sortedKeys := make([]string, len(pubKeysHex))
+	for idx, pubKeyHex := range pubKeysHex {
+		pubKey, err := hex.DecodeString(pubKeyHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key hex: %w", err)
+		}
+
+		if len(pubKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key length: %d", len(pubKey))
+		}
+
+		sortedKeys[idx] = strings.ToLower(pubKeyHex)
+	}
+
+	slices.Sort(sortedKeys)
+
+	if len(slices.Compact(slices.Clone(sortedKeys))) != len(sortedKeys) {
+		return nil, errors.New("duplicate public keys are not allowed")
+	}
+
+	return sortedKeys, nil
+}
+
+// DeriveMultisigAddress -> Address of the M-of-N account, where M = threshold.
+// The key set is sorted first, so the same keys always map to the same address.
+func DeriveMultisigAddress(pubKeysHex []string, threshold int) (string, error) {
+	sortedKeys, err := SortPublicKeys(pubKeysHex)
+	if err != nil {
+		return "", err
+	}
+
+	if threshold < 1 || threshold > len(sortedKeys) {
+		return "", fmt.Errorf("threshold must be between 1 and %d", len(sortedKeys))
+	}
+
+	record := fmt.Sprintf("multisig-%d-%s", threshold, strings.Join(sortedKeys, "-"))
+
+	hash := sha256.Sum256([]byte(record))
+	return hex.EncodeToString(hash[:20]), nil
+}
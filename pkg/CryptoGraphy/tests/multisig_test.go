@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+)
+
+func generatePublicKeys(t *testing.T, count int) []string {
+	t.Helper()
+
+	keys := make([]string, count)
+	for i := range keys {
+		kp, err := CryptoGraphy.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("Failed to generate key pair: %v", err)
+		}
+		keys[i] = kp.GetPublicKeyHex()
+	}
+
+	return keys
+}
+
+// TestDeriveMultisigAddress_OrderIndependent tests that key order does not change the address
+func TestDeriveMultisigAddress_OrderIndependent(t *testing.T) {
+	keys := generatePublicKeys(t, 3)
+
+	address1, err := CryptoGraphy.DeriveMultisigAddress(keys, 2)
+	if err != nil {
+		t.Fatalf("Failed to derive multisig address: %v", err)
+	}
+
+	reversed := []string{keys[2], keys[1], strings.ToUpper(keys[0])}
+	address2, err := CryptoGraphy.DeriveMultisigAddress(reversed, 2)
+	if err != nil {
+		t.Fatalf("Failed to derive multisig address: %v", err)
+	}
+
+	if address1 != address2 {
+		t.Errorf("Address should not depend on key order: %s != %s", address1, address2)
+	}
+
+	if len(address1) != 40 {
+		t.Errorf("Expected address length 40, got %d", len(address1))
+	}
+}
+
+// TestDeriveMultisigAddress_ThresholdChangesAddress tests that M is part of the address
+func TestDeriveMultisigAddress_ThresholdChangesAddress(t *testing.T) {
+	keys := generatePublicKeys(t, 3)
+
+	address1, _ := CryptoGraphy.DeriveMultisigAddress(keys, 1)
+	address2, _ := CryptoGraphy.DeriveMultisigAddress(keys, 2)
+
+	if address1 == address2 {
+		t.Error("Different thresholds should produce different addresses")
+	}
+}
+
+// TestDeriveMultisigAddress_DiffersFromSingleKey tests domain separation from single-key addresses
+func TestDeriveMultisigAddress_DiffersFromSingleKey(t *testing.T) {
+	keys := generatePublicKeys(t, 1)
+
+	multisigAddress, err := CryptoGraphy.DeriveMultisigAddress(keys, 1)
+	if err != nil {
+		t.Fatalf("Failed to derive multisig address: %v", err)
+	}
+
+	singleAddress, _ := CryptoGraphy.DeriveAddressFromPublicKey(keys[0])
+	if multisigAddress == singleAddress {
+		t.Error("1-of-1 multisig address should differ from the single-key address")
+	}
+}
+
+// TestDeriveMultisigAddress_InvalidInput tests rejection of bad key sets and thresholds
+func TestDeriveMultisigAddress_InvalidInput(t *testing.T) {
+	keys := generatePublicKeys(t, 3)
+
+	tests := []struct {
+		name      string
+		keys      []string
+		threshold int
+	}{
+		{"No keys", nil, 1},
+		{"Zero threshold", keys, 0},
+		{"Threshold above N", keys, 4},
+		{"Duplicate keys", []string{keys[0], keys[0]}, 1},
+		{"Invalid hex", []string{"zz"}, 1},
+		{"Wrong key length", []string{"abcd"}, 1},
+		{"Too many keys", generatePublicKeys(t, CryptoGraphy.MaxMultisigKeys+1), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CryptoGraphy.DeriveMultisigAddress(tt.keys, tt.threshold); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+// TestSortPublicKeys tests canonical ordering of a key set
+func TestSortPublicKeys(t *testing.T) {
+	keys := generatePublicKeys(t, 5)
+
+	sorted, err := CryptoGraphy.SortPublicKeys(keys)
+	if err != nil {
+		t.Fatalf("Failed to sort keys: %v", err)
+	}
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1] >= sorted[i] {
+			t.Errorf("Keys are not sorted at index %d", i)
+		}
+	}
+}
@@ -16,6 +16,10 @@ func newRouter(handler *handler.Handler) *Router {
 	router.Route("/api", func(r chi.Router) {
 		r.Get("/chain", handler.GetBlockchain)
 		r.Get("/mempool", handler.GetMempool)
+		r.Get("/peers", handler.GetPeers)
+		r.Get("/peers/bans", handler.GetBans)
+		r.Delete("/peers/bans/{address}", handler.LiftBan)
+		r.Get("/sync/status", handler.GetSyncStatus)
 
 		r.Post("/mine", handler.MineBlock)
 
@@ -23,11 +27,47 @@ func newRouter(handler *handler.Handler) *Router {
 		r.Get("/blocks", handler.GetAllBlocks)
 
 		r.Get("/txs", handler.GetTransactions)
+		r.Get("/txs/search", handler.SearchTransactionsByData)
 		r.Post("/tx/send", handler.SendTransaction)
 		r.Get("/tx/fee", handler.GetCurrentTxFee)
 
 		r.Post("/keys", handler.GenerateKeys)
 
+		r.Post("/multisig/address", handler.CreateMultisigAddress)
+		r.Post("/multisig/tx", handler.CreateMultisigTransaction)
+		r.Get("/multisig/tx/{hash}", handler.GetMultisigTransaction)
+		r.Post("/multisig/tx/{hash}/sign", handler.SignMultisigTransaction)
+		r.Post("/multisig/tx/{hash}/finalize", handler.FinalizeMultisigTransaction)
+
+		r.Post("/htlc", handler.CreateHTLC)
+		r.Get("/htlc/{id}", handler.GetHTLC)
+		r.Post("/htlc/{id}/claim", handler.ClaimHTLC)
+		r.Post("/htlc/{id}/refund", handler.RefundHTLC)
+
+		r.Post("/script/address", handler.CreateScriptAddress)
+
+		r.Post("/channels", handler.OpenChannel)
+		r.Get("/channels/{id}", handler.GetChannel)
+		r.Post("/channels/{id}/state", handler.SignChannelState)
+		r.Post("/channels/{id}/state/verify", handler.VerifyChannelState)
+		r.Post("/channels/{id}/close", handler.CloseChannel)
+		r.Post("/channels/{id}/settle", handler.SettleChannel)
+
+		r.Post("/tokens", handler.IssueToken)
+		r.Get("/tokens", handler.GetTokens)
+		r.Get("/tokens/balances", handler.GetTokenBalances)
+		r.Get("/tokens/{id}", handler.GetToken)
+		r.Post("/tokens/{id}/mint", handler.MintToken)
+		r.Post("/tokens/{id}/transfer", handler.TransferToken)
+
+		r.Post("/names", handler.RegisterName)
+		r.Get("/names/{name}", handler.GetName)
+		r.Post("/names/{name}/renew", handler.RenewName)
+		r.Post("/names/{name}/transfer", handler.TransferName)
+
+		r.Post("/vesting", handler.CreateVesting)
+		r.Get("/vesting", handler.GetVesting)
+
 		r.Delete("/clear", handler.ClearDatabase)
 	})
 
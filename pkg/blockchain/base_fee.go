@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// MaxBlockSize -> Largest CalculateSize a block may have, in bytes
+	MaxBlockSize = 1_000_000
+
+	// TargetBlockSize -> Block size at which the base fee stays the same
+	TargetBlockSize = MaxBlockSize / 2
+
+	// InitialBaseFee -> Base fee per byte of the genesis block
+	InitialBaseFee = 1
+
+	// MinBaseFee -> The base fee never drops below it
+	MinBaseFee = 1
+
+	// baseFeeChangeDenominator -> A full or empty block moves the base fee by at most 1/8
+	baseFeeChangeDenominator = 8
+)
+
+// CalculateBaseFee -> Base fee per byte of the block after a parent with the given base
+// fee and size. It rises when the parent is above TargetBlockSize and falls when it is below.
+func CalculateBaseFee(parentBaseFee uint64, parentSize int64) uint64 {
+	if parentSize == TargetBlockSize {
+		return max(parentBaseFee, MinBaseFee)
+	}
+
+	var sizeDelta uint64
+	if parentSize > TargetBlockSize {
+		sizeDelta = uint64(parentSize - TargetBlockSize)
+	} else {
+		sizeDelta = uint64(TargetBlockSize - parentSize)
+	}
+
+	// parentBaseFee * sizeDelta / TargetBlockSize / 8 without overflowing. A valid
+	// block is at most MaxBlockSize, so the delta and the quotient stay in range.
+	sizeDelta = min(sizeDelta, TargetBlockSize)
+	hi, lo := bits.Mul64(parentBaseFee, sizeDelta)
+	change, _ := bits.Div64(hi, lo, TargetBlockSize*baseFeeChangeDenominator)
+
+	if parentSize > TargetBlockSize {
+		// A busy block always raises the base fee, even from its minimum
+		change = max(change, 1)
+		if parentBaseFee > math.MaxUint64-change {
+			return math.MaxUint64
+		}
+
+		return parentBaseFee + change
+	}
+
+	if parentBaseFee < MinBaseFee+change {
+		return MinBaseFee
+	}
+
+	return parentBaseFee - change
+}
+
+// MinFee -> Part of the tx's fee burned in a block with the given base fee
+func (tx *Transaction) MinFee(baseFee uint64) uint64 {
+	return baseFee * uint64(tx.Size())
+}
+
+// NextBaseFee -> Base fee per byte of the next block
+func (bc *Blockchain) NextBaseFee() uint64 {
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		return InitialBaseFee
+	}
+
+	return CalculateBaseFee(latest.BaseFee, latest.CalculateSize())
+}
+
+// expectedBaseFee -> Base fee the block at height must carry, derived from its parent
+func (bc *Blockchain) expectedBaseFee(height int64) (uint64, error) {
+	if height == 0 {
+		return InitialBaseFee, nil
+	}
+
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	for idx := len(bc.Blocks) - 1; idx >= 0; idx-- {
+		if parent := &bc.Blocks[idx]; parent.Id == height-1 {
+			return CalculateBaseFee(parent.BaseFee, parent.CalculateSize()), nil
+		}
+	}
+
+	return 0, fmt.Errorf("parent of block %d is unknown", height)
+}
+
+// CalculateFee -> Fee for tx in the next block: the next base fee, which is burned,
+// plus the mempool congestion rate as the miner's tip, both per byte
+func (bc *Blockchain) CalculateFee(tx *Transaction) uint64 {
+	return (bc.NextBaseFee() + bc.Mempool.CalculateTxFee()) * uint64(tx.SignedSize())
+}
+
+// fillBlock -> Drops txs that do not pay the base fee and keeps as many of the
+// rest, in order, as fit next to the used bytes of the block
+func fillBlock(txs []Transaction, baseFee uint64, used int64) []Transaction {
+	blockTxs := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Fee < tx.MinFee(baseFee) {
+			continue
+		}
+
+		txSize := int64(tx.Size())
+		if used+txSize > MaxBlockSize {
+			continue
+		}
+
+		used += txSize
+		blockTxs = append(blockTxs, tx)
+	}
+
+	return blockTxs
+}
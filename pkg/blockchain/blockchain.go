@@ -5,9 +5,11 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/Nikolat27/simple_blockchain/pkg/database"
@@ -21,29 +23,49 @@ type Blockchain struct {
 	Database *database.Database
 	Mempool  *Mempool `json:"mempool"`
 
+	blockIndex map[string]int // Index in Blocks of each block, by its hex hash
+
+	FeeEstimator *FeeEstimator `json:"-"`
+	Policy       *Policy       `json:"-"`
+
 	CancelMiningCh chan bool
 
-	Mutex sync.RWMutex
+	chainId string // Network the signatures of every tx are bound to, fixed for good
+
+	Mutex          sync.RWMutex
+	admissionMutex sync.Mutex // serializes mempool policy checks with insertion
 }
 
-func initBlockchain(db *database.Database, mp *Mempool) *Blockchain {
+func initBlockchain(db *database.Database, mp *Mempool, chainId string) *Blockchain {
 	return &Blockchain{
 		Blocks:   make([]Block, 0),
 		Database: db,
 		Mempool:  mp,
 
+		blockIndex: make(map[string]int),
+
+		chainId: chainId,
+
+		FeeEstimator: NewFeeEstimator(),
+		Policy:       DefaultPolicy(),
+
 		CancelMiningCh: make(chan bool, 1),
 	}
 }
 
-func NewBlockchain(db *database.Database, mp *Mempool) (*Blockchain, error) {
+// NewBlockchain -> Creates the genesis block of chainId, which opens the given vesting accounts
+func NewBlockchain(db *database.Database, mp *Mempool, chainId string, vesting ...GenesisVesting) (*Blockchain, error) {
 	if mp == nil {
 		return nil, errors.New("mempool instance is required")
 	}
 
-	bc := initBlockchain(db, mp)
+	if err := ValidateChainId(chainId); err != nil {
+		return nil, err
+	}
 
-	genesisBlock, err := createGenesisBlock()
+	bc := initBlockchain(db, mp, chainId)
+
+	genesisBlock, err := createGenesisBlock(vesting)
 	if err != nil {
 		return nil, err
 	}
@@ -67,8 +89,12 @@ func NewBlockchain(db *database.Database, mp *Mempool) (*Blockchain, error) {
 	return bc, nil
 }
 
-func LoadBlockchain(db *database.Database, mp *Mempool) (*Blockchain, error) {
-	bc := initBlockchain(db, mp)
+func LoadBlockchain(db *database.Database, mp *Mempool, chainId string) (*Blockchain, error) {
+	if err := ValidateChainId(chainId); err != nil {
+		return nil, err
+	}
+
+	bc := initBlockchain(db, mp, chainId)
 
 	blocks, err := bc.GetAllBlocks()
 	if err != nil {
@@ -82,7 +108,7 @@ func LoadBlockchain(db *database.Database, mp *Mempool) (*Blockchain, error) {
 
 	// If any block failed verification, clear database and start fresh
 	if !allBlocksValid {
-		return startFresh(db)
+		return startFresh(db, chainId)
 	}
 
 	return bc, nil
@@ -110,7 +136,7 @@ func (bc *Blockchain) VerifyBlocks(blocks []Block) (bool, error) {
 	return true, nil
 }
 
-func startFresh(db *database.Database) (*Blockchain, error) {
+func startFresh(db *database.Database, chainId string) (*Blockchain, error) {
 	log.Println("Found corrupted blockchain data, clearing database")
 
 	sqlTx, err := db.BeginTx()
@@ -127,11 +153,7 @@ func startFresh(db *database.Database) (*Blockchain, error) {
 		return nil, err
 	}
 
-	return &Blockchain{
-		Blocks:   make([]Block, 0),
-		Database: db,
-		Mempool:  NewMempool(1048576),
-	}, nil
+	return initBlockchain(db, NewMempool(1048576), chainId), nil
 }
 
 func (bc *Blockchain) AddBlock(sqlTx *sql.Tx, newBlock *Block) error {
@@ -140,7 +162,7 @@ func (bc *Blockchain) AddBlock(sqlTx *sql.Tx, newBlock *Block) error {
 	merkleRootStr := hex.EncodeToString(newBlock.MerkleRoot)
 
 	blockId, err := bc.Database.AddBlock(sqlTx, prevHashStr, hashStr, merkleRootStr,
-		newBlock.Nonce, newBlock.Timestamp, newBlock.Id)
+		newBlock.Nonce, newBlock.Timestamp, newBlock.Id, newBlock.BaseFee)
 
 	if err != nil {
 		return err
@@ -150,7 +172,7 @@ func (bc *Blockchain) AddBlock(sqlTx *sql.Tx, newBlock *Block) error {
 		return err
 	}
 
-	if err := bc.UpdateUserBalances(sqlTx, newBlock.Transactions); err != nil {
+	if err := bc.updateBalancesAt(sqlTx, newBlock.Id, newBlock.BaseFee, newBlock.Transactions); err != nil {
 		return err
 	}
 
@@ -171,6 +193,52 @@ func (bc *Blockchain) AddTransactionToDB(dbTx *sql.Tx, blockId int, txs []Transa
 			Signature:  signatureStr,
 			Status:     "confirmed",
 			IsCoinbase: tx.IsCoinbase,
+			LockTime:   tx.LockTime,
+			Data:       tx.Data,
+		}
+
+		if tx.HasFeePayer() {
+			txInstance.FeePayer = tx.FeePayer
+			txInstance.FeePayerPublicKey = tx.FeePayerPublicKey
+			txInstance.FeePayerSignature = hex.EncodeToString(tx.FeePayerSignature)
+		}
+
+		if tx.Type != "" {
+			payload, err := tx.encodePayload()
+			if err != nil {
+				return fmt.Errorf("ERROR encoding transaction payload: %w", err)
+			}
+
+			txInstance.Type = tx.Type
+			txInstance.Payload = payload
+		}
+
+		if tx.IsScript() {
+			witnessJSON, err := json.Marshal(tx.Witness)
+			if err != nil {
+				return fmt.Errorf("ERROR encoding script witness: %w", err)
+			}
+
+			txInstance.Script = tx.Script
+			txInstance.Witness = string(witnessJSON)
+		}
+
+		for _, output := range tx.Outputs {
+			txInstance.Outputs = append(txInstance.Outputs, database.DBTransactionOutput{
+				To:     output.To,
+				Amount: output.Amount,
+			})
+		}
+
+		if tx.IsMultisig() {
+			signaturesJSON, err := json.Marshal(tx.Signatures)
+			if err != nil {
+				return fmt.Errorf("ERROR encoding multisig signatures: %w", err)
+			}
+
+			txInstance.MultisigKeys = strings.Join(tx.MultisigKeys, ",")
+			txInstance.Threshold = tx.Threshold
+			txInstance.Signatures = string(signaturesJSON)
 		}
 
 		if err := bc.Database.AddTransaction(dbTx, txInstance, blockId); err != nil {
@@ -217,7 +285,7 @@ func (bc *Blockchain) parseBlock(rows *sql.Rows) (*Block, error) {
 	var dbId int // database ID (index), not used for block identification
 
 	if err := rows.Scan(&dbId, &prevHashStr, &hashStr, &merkleRootStr, &block.Nonce,
-		&block.Timestamp, &block.Id); err != nil {
+		&block.Timestamp, &block.Id, &block.BaseFee); err != nil {
 
 		return nil, err
 	}
@@ -259,6 +327,7 @@ func (bc *Blockchain) VerifyBlock(block *Block) (bool, error) {
 		Timestamp:    block.Timestamp,
 		Transactions: block.Transactions,
 		Nonce:        block.Nonce,
+		BaseFee:      block.BaseFee,
 		Hash:         nil,
 	}
 
@@ -285,6 +354,8 @@ func (bc *Blockchain) VerifyBlock(block *Block) (bool, error) {
 	return hashMatches && tempBlock.IsValidHash(), nil
 }
 
+// GetBalance -> Coins address can spend in the next block: the confirmed balance
+// minus pending outgoing txs and unvested coins
 func (bc *Blockchain) GetBalance(address string) (uint64, error) {
 	mempoolTxs := bc.Mempool.GetTransactionsCopy()
 
@@ -295,50 +366,137 @@ func (bc *Blockchain) GetBalance(address string) (uint64, error) {
 
 	pendingOutgoing := getUserPendingOutgoing(address, mempoolTxs)
 
-	if confirmedBalance < pendingOutgoing {
+	// Unvested coins cannot be spent in the next block
+	vesting, err := bc.GetVestingBalance(address, bc.GetHeight()+1)
+	if err != nil {
+		return 0, err
+	}
+
+	if confirmedBalance < pendingOutgoing+vesting.Unvested {
 		return 0, nil
 	}
 
-	effectiveBalance := confirmedBalance - pendingOutgoing
+	effectiveBalance := confirmedBalance - pendingOutgoing - vesting.Unvested
 
 	return effectiveBalance, nil
 }
 
+// UpdateUserBalances -> Applies txs as if mined in the next block
 func (bc *Blockchain) UpdateUserBalances(sqlTx *sql.Tx, txs []Transaction) error {
-	// Calculate total fees from all transactions in this block
-	var totalFees uint64
+	return bc.updateBalancesAt(sqlTx, bc.GetHeight()+1, bc.NextBaseFee(), txs)
+}
+
+// updateBalancesAt -> Applies the txs of the block at height to the balances and ledgers.
+// The base fee part of every fee is burned, the miner only gets the tips above it.
+func (bc *Blockchain) updateBalancesAt(sqlTx *sql.Tx, height int64, baseFee uint64, txs []Transaction) error {
+	var totalTips uint64
 
 	for _, tx := range txs {
-		if !tx.IsCoinbase {
-			totalFees += tx.Fee
+		if tx.IsCoinbase {
+			continue
+		}
+
+		if minFee := tx.MinFee(baseFee); tx.Fee > minFee {
+			totalTips += tx.Fee - minFee
 		}
 	}
 
 	for _, tx := range txs {
+		// Genesis vesting accounts are credited like a transfer
+		if tx.IsCoinbase && tx.Vesting != nil {
+			if err := bc.addVestingSchedule(sqlTx, &tx); err != nil {
+				return fmt.Errorf("failed to add vesting schedule: %w", err)
+			}
+
+			if err := bc.Database.IncreaseUserBalance(sqlTx, tx.To, tx.Amount); err != nil {
+				return fmt.Errorf("failed to credit vesting account %s: %w", tx.To, err)
+			}
+			continue
+		}
+
 		if tx.IsCoinbase {
-			// Credit miner with mining reward + total fees from this block
-			minerReward := tx.Amount + totalFees
+			// Credit miner with mining reward + the tips of this block
+			minerReward := tx.Amount + totalTips
 			if err := bc.Database.IncreaseUserBalance(sqlTx, tx.To, minerReward); err != nil {
 				return fmt.Errorf("failed to credit miner %s: %w", tx.To, err)
 			}
 			continue
 		}
 
-		// For regular transactions: sender pays amount + fee
+		// Settlements pay out of the HTLC and channel ledgers, not the sender's balance
+		if tx.IsSettlement() {
+			if err := bc.applySettlement(sqlTx, height, &tx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// For regular transactions: sender pays amount + fee, unless a fee payer sponsors the fee
 		totalDebit := tx.Amount + tx.Fee
-		if err := bc.Database.DecreaseUserBalance(sqlTx, tx.From, totalDebit); err != nil {
-			return fmt.Errorf("failed to debit sender %s: %w", tx.From, err)
+		if tx.HasFeePayer() {
+			totalDebit = tx.Amount
 		}
 
-		// Credit receiver with the transaction amount
-		if tx.To != "" {
-			if err := bc.Database.IncreaseUserBalance(sqlTx, tx.To, tx.Amount); err != nil {
-				return fmt.Errorf("failed to credit receiver %s: %w", tx.To, err)
+		// A sponsored token tx costs its sender no native coins
+		if totalDebit > 0 {
+			if err := bc.Database.DecreaseUserBalance(sqlTx, tx.From, totalDebit); err != nil {
+				return fmt.Errorf("failed to debit sender %s: %w", tx.From, err)
+			}
+		}
+
+		if tx.HasFeePayer() {
+			if err := bc.Database.DecreaseUserBalance(sqlTx, tx.FeePayer, tx.Fee); err != nil {
+				return fmt.Errorf("failed to debit fee payer %s: %w", tx.FeePayer, err)
+			}
+		}
+
+		// Locked funds are held in the HTLC ledger until claimed or refunded
+		if tx.Type == TxTypeHTLCLock {
+			if err := bc.lockHTLC(sqlTx, &tx); err != nil {
+				return fmt.Errorf("failed to lock htlc: %w", err)
+			}
+			continue
+		}
+
+		// Token txs move units of an asset, their native part is only the fee
+		if tx.IsToken() {
+			if err := bc.applyToken(sqlTx, &tx); err != nil {
+				return fmt.Errorf("failed to apply %s: %w", tx.Type, err)
+			}
+			continue
+		}
+
+		// Registration fees are burned, nobody is credited
+		if tx.IsNameOp() {
+			if err := bc.applyName(sqlTx, height, &tx); err != nil {
+				return fmt.Errorf("failed to apply %s: %w", tx.Type, err)
+			}
+			continue
+		}
+
+		// Channel deposits are held in the channel ledger until it settles
+		if tx.Type == TxTypeChannelOpen {
+			if err := bc.openChannel(sqlTx, &tx); err != nil {
+				return fmt.Errorf("failed to open channel: %w", err)
+			}
+			continue
+		}
+
+		if tx.Type == TxTypeVestingCreate {
+			if err := bc.addVestingSchedule(sqlTx, &tx); err != nil {
+				return fmt.Errorf("failed to add vesting schedule: %w", err)
+			}
+		}
+
+		// Credit every receiver, a batch tx has one per output
+		for _, credit := range tx.Credits() {
+			if err := bc.Database.IncreaseUserBalance(sqlTx, credit.To, credit.Amount); err != nil {
+				return fmt.Errorf("failed to credit receiver %s: %w", credit.To, err)
 			}
 		}
 	}
 
-	return nil
+	return bc.verifyVestingLocks(sqlTx, height, txs)
 }
 
 func (bc *Blockchain) ValidateTransaction(tx *Transaction) error {
@@ -346,17 +504,55 @@ func (bc *Blockchain) ValidateTransaction(tx *Transaction) error {
 		return nil
 	}
 
+	if tx.IsSettlement() {
+		return bc.validateSettlement(tx)
+	}
+
+	if tx.IsToken() {
+		if err := bc.validateToken(tx); err != nil {
+			return err
+		}
+	}
+
+	if tx.IsNameOp() {
+		if err := bc.validateNameOp(tx); err != nil {
+			return err
+		}
+	}
+
 	balance, err := bc.GetBalance(tx.From)
 	if err != nil {
 		return err
 	}
 
-	totalCost := tx.Amount + tx.Fee
-	if balance >= totalCost {
-		return nil
+	if !tx.HasFeePayer() {
+		totalCost := tx.Amount + tx.Fee
+		if balance >= totalCost {
+			return nil
+		}
+
+		return errors.New("balance is insufficient")
+	}
+
+	if balance < tx.Amount {
+		return errors.New("balance is insufficient")
 	}
 
-	return errors.New("balance is insufficient")
+	feePayerBalance, err := bc.GetBalance(tx.FeePayer)
+	if err != nil {
+		return err
+	}
+
+	// Self-sponsored: both parts come out of the same balance
+	if tx.FeePayer == tx.From && feePayerBalance < tx.Amount+tx.Fee {
+		return errors.New("balance is insufficient")
+	}
+
+	if feePayerBalance < tx.Fee {
+		return errors.New("fee payer balance is insufficient")
+	}
+
+	return nil
 }
 
 func getUserPendingOutgoing(address string, mempoolTxs map[string]Transaction) uint64 {
@@ -368,7 +564,13 @@ func getUserPendingOutgoing(address string, mempoolTxs map[string]Transaction) u
 		}
 
 		if tx.From == address {
-			pending += tx.Amount + tx.Fee // Include both amount and fee
+			pending += tx.Amount
+		}
+
+		// Fees count against whoever pays them, the sponsor if there is one.
+		// Unsponsored settlements pay their fee out of the released funds.
+		if tx.FeeSource() == address && (tx.HasFeePayer() || !tx.IsSettlement()) {
+			pending += tx.Fee
 		}
 	}
 
@@ -390,16 +592,15 @@ func (bc *Blockchain) VerifyHeaders(headers []BlockHeader) (bool, error) {
 		return false, fmt.Errorf("genesis block must have empty prev Hash")
 	}
 
-	for idx, header := range headers {
-		var prevHash []byte
-		if idx > 0 {
-			prevHash = headers[idx-1].Hash
-		} else {
-			// Genesis block has no previous Hash
-			prevHash = make([]byte, sha256.Size)
-		}
+	// Genesis block has no previous Hash
+	return bc.VerifyHeadersAfter(-1, emptyHash[:], headers)
+}
 
-		verified, err := header.Verify(int64(idx), prevHash)
+// VerifyHeadersAfter -> Verifies that headers continue, in order, the block with
+// prevHash at height prevId
+func (bc *Blockchain) VerifyHeadersAfter(prevId int64, prevHash []byte, headers []BlockHeader) (bool, error) {
+	for idx, header := range headers {
+		verified, err := header.Verify(prevId+1+int64(idx), prevHash)
 		if err != nil {
 			return false, err
 		}
@@ -407,6 +608,8 @@ func (bc *Blockchain) VerifyHeaders(headers []BlockHeader) (bool, error) {
 		if !verified {
 			return false, nil
 		}
+
+		prevHash = header.Hash
 	}
 
 	return true, nil
@@ -423,7 +626,7 @@ func (bc *Blockchain) GetBlockById(blockId int64) (*Block, error) {
 	var dbId int // database ID (index)
 
 	if err := row.Scan(&dbId, &prevHashStr, &hashStr, &merkleRootStr,
-		&block.Nonce, &block.Timestamp, &block.Id); err != nil {
+		&block.Nonce, &block.Timestamp, &block.Id, &block.BaseFee); err != nil {
 
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
@@ -471,7 +674,11 @@ func (bc *Blockchain) AddBlockToMemory(block *Block) bool {
 		}
 	}
 
+	bc.blockIndex[hex.EncodeToString(block.Hash)] = len(bc.Blocks)
 	bc.Blocks = append(bc.Blocks, *block)
+
+	bc.FeeEstimator.ProcessBlock(block.Id, block.Transactions)
+
 	return true
 }
 
@@ -485,3 +692,74 @@ func (bc *Blockchain) GetLatestBlock() *Block {
 
 	return &bc.Blocks[len(bc.Blocks)-1]
 }
+
+// GenesisHash -> Hash of the block the chain starts at, nil if the chain is empty
+func (bc *Blockchain) GenesisHash() []byte {
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	if len(bc.Blocks) == 0 {
+		return nil
+	}
+
+	return bc.Blocks[0].Hash
+}
+
+// GetHeight -> Height of the chain tip, -1 if the chain is empty
+func (bc *Blockchain) GetHeight() int64 {
+	latestBlock := bc.GetLatestBlock()
+	if latestBlock == nil {
+		return -1
+	}
+
+	return latestBlock.Id
+}
+
+// AddTransactionToMempool -> Applies the mempool policy, then adds the tx to the
+// mempool and tracks it for fee estimation. Policy rejections are *PolicyError.
+func (bc *Blockchain) AddTransactionToMempool(tx *Transaction) error {
+	bc.admissionMutex.Lock()
+	defer bc.admissionMutex.Unlock()
+
+	if bc.Mempool.HasTransaction(tx.Hash().EncodeToString()) {
+		return nil
+	}
+
+	if err := bc.Policy.Check(tx, bc.Mempool); err != nil {
+		return err
+	}
+
+	bc.Mempool.AddTransaction(tx)
+	bc.FeeEstimator.TrackTransaction(tx, bc.GetHeight())
+
+	return nil
+}
+
+// ConfirmedTransaction -> A mined tx and the height of the block it is in
+type ConfirmedTransaction struct {
+	BlockHeight int64       `json:"block_height"`
+	Transaction Transaction `json:"transaction"`
+}
+
+// FindTransactionsByDataPrefix -> Mined txs whose Data starts with prefix
+func (bc *Blockchain) FindTransactionsByDataPrefix(prefix string, limit int) ([]ConfirmedTransaction, error) {
+	matches, err := bc.Database.GetTransactionsByDataPrefix(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]ConfirmedTransaction, 0, len(matches))
+	for _, match := range matches {
+		tx, err := parseDBTransaction(match.DBTransactionSchema)
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, ConfirmedTransaction{
+			BlockHeight: match.BlockHeight,
+			Transaction: *tx,
+		})
+	}
+
+	return txs, nil
+}
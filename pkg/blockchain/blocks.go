@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -19,6 +20,7 @@ type Block struct {
 	MerkleRoot   []byte        `json:"merkle_root"`
 	Timestamp    int64         `json:"timestamp"`
 	Nonce        int64         `json:"nonce"`
+	BaseFee      uint64        `json:"base_fee"` // per byte, burned from every fee
 	Transactions []Transaction `json:"transactions,omitempty"`
 }
 
@@ -29,6 +31,7 @@ type BlockHeader struct {
 	MerkleRoot []byte `json:"merkle_root"`
 	Timestamp  int64  `json:"timestamp"`
 	Nonce      int64  `json:"nonce"`
+	BaseFee    uint64 `json:"base_fee"`
 }
 
 func (block *Block) HashBlock() error {
@@ -36,12 +39,7 @@ func (block *Block) HashBlock() error {
 		block.ComputeMerkleRoot()
 	}
 
-	merkleStr := hex.EncodeToString(block.MerkleRoot)
-
-	prevHashStr := hex.EncodeToString(block.PrevHash)
-
-	record := fmt.Sprintf("%d-%s-%s-%d-%d", block.Id, prevHashStr, merkleStr,
-		block.Timestamp, block.Nonce)
+	record := headerRecord(block.Id, block.PrevHash, block.MerkleRoot, block.Timestamp, block.Nonce, block.BaseFee)
 
 	hash := sha256.Sum256([]byte(record))
 
@@ -61,27 +59,79 @@ func (block *Block) parseDBTransactions(dbTxs []database.DBTransactionSchema) er
 	block.Transactions = make([]Transaction, len(dbTxs))
 
 	for idx, dbTx := range dbTxs {
-		decodedSignature, err := hex.DecodeString(dbTx.Signature)
+		tx, err := parseDBTransaction(dbTx)
 		if err != nil {
 			return err
 		}
 
-		block.Transactions[idx] = Transaction{
-			From:       dbTx.From,
-			To:         dbTx.To,
-			Amount:     dbTx.Amount,
-			Fee:        dbTx.Fee,
-			Timestamp:  dbTx.Timestamp,
-			PublicKey:  dbTx.PublicKey,
-			Signature:  decodedSignature,
-			Status:     dbTx.Status,
-			IsCoinbase: dbTx.IsCoinbase,
-		}
+		block.Transactions[idx] = *tx
 	}
 
 	return nil
 }
 
+func parseDBTransaction(dbTx database.DBTransactionSchema) (*Transaction, error) {
+	decodedSignature, err := hex.DecodeString(dbTx.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		From:       dbTx.From,
+		To:         dbTx.To,
+		Amount:     dbTx.Amount,
+		Fee:        dbTx.Fee,
+		Timestamp:  dbTx.Timestamp,
+		PublicKey:  dbTx.PublicKey,
+		Signature:  decodedSignature,
+		Status:     dbTx.Status,
+		IsCoinbase: dbTx.IsCoinbase,
+		LockTime:   dbTx.LockTime,
+		Data:       dbTx.Data,
+	}
+
+	if dbTx.FeePayer != "" {
+		tx.FeePayer = dbTx.FeePayer
+		tx.FeePayerPublicKey = dbTx.FeePayerPublicKey
+
+		tx.FeePayerSignature, err = hex.DecodeString(dbTx.FeePayerSignature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode fee payer signature: %w", err)
+		}
+	}
+
+	tx.Type = dbTx.Type
+	if err := tx.decodePayload(dbTx.Payload); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction payload: %w", err)
+	}
+
+	if dbTx.Script != "" {
+		tx.Script = dbTx.Script
+
+		if err := json.Unmarshal([]byte(dbTx.Witness), &tx.Witness); err != nil {
+			return nil, fmt.Errorf("failed to decode script witness: %w", err)
+		}
+	}
+
+	for _, output := range dbTx.Outputs {
+		tx.Outputs = append(tx.Outputs, TxOutput{
+			To:     output.To,
+			Amount: output.Amount,
+		})
+	}
+
+	if dbTx.MultisigKeys != "" {
+		tx.MultisigKeys = strings.Split(dbTx.MultisigKeys, ",")
+		tx.Threshold = dbTx.Threshold
+
+		if err := json.Unmarshal([]byte(dbTx.Signatures), &tx.Signatures); err != nil {
+			return nil, fmt.Errorf("failed to decode multisig signatures: %w", err)
+		}
+	}
+
+	return tx, nil
+}
+
 // SerializeTransactions -> Deterministic
 func (block *Block) SerializeTransactions() string {
 	txs := block.Transactions
@@ -113,17 +163,7 @@ func (block *Block) ComputeMerkleRoot() {
 	// build leaves: sha256 of each transaction serialization
 	leaves := make([][]byte, len(block.Transactions))
 	for i, tx := range block.Transactions {
-		var s string
-		if tx.IsCoinbase {
-			// For coinbase transactions, include timestamp for uniqueness
-			s = fmt.Sprintf("%s-%s-%d-%d-%t",
-				tx.From, tx.To, tx.Amount, tx.Timestamp, tx.IsCoinbase)
-		} else {
-			// For regular transactions, use signature for uniqueness
-			s = fmt.Sprintf("%s-%s-%d-%x-%d-%t",
-				tx.From, tx.To, tx.Amount, tx.Signature, tx.Timestamp, tx.IsCoinbase)
-		}
-		h := sha256.Sum256([]byte(s))
+		h := sha256.Sum256([]byte(tx.merkleLeaf()))
 		leaf := make([]byte, sha256.Size)
 		copy(leaf, h[:])
 		leaves[i] = leaf
@@ -159,16 +199,28 @@ func (block *Block) GetHeader() *BlockHeader {
 		MerkleRoot: block.MerkleRoot,
 		Timestamp:  block.Timestamp,
 		Nonce:      block.Nonce,
+		BaseFee:    block.BaseFee,
 	}
 }
 
-func createGenesisBlock() (*Block, error) {
+func createGenesisBlock(vesting []GenesisVesting) (*Block, error) {
+	transactions := make([]Transaction, 0, len(vesting))
+	for _, grant := range vesting {
+		tx := newGenesisVestingTx(grant)
+		if err := tx.validateVestingPayload(); err != nil {
+			return nil, fmt.Errorf("genesis vesting for %q: %w", grant.Address, err)
+		}
+
+		transactions = append(transactions, *tx)
+	}
+
 	block := &Block{
 		Id:           0,
 		PrevHash:     make([]byte, 32),
 		Timestamp:    utils.GetTimestamp(),
-		Transactions: []Transaction{},
+		Transactions: transactions,
 		Nonce:        0,
+		BaseFee:      InitialBaseFee,
 	}
 
 	block.ComputeMerkleRoot()
@@ -225,6 +277,7 @@ func (header *BlockHeader) verifyHash() bool {
 		MerkleRoot: header.MerkleRoot,
 		Timestamp:  header.Timestamp,
 		Nonce:      header.Nonce,
+		BaseFee:    header.BaseFee,
 		Hash:       nil, // Will be calculated
 	}
 
@@ -234,16 +287,26 @@ func (header *BlockHeader) verifyHash() bool {
 }
 
 func (header *BlockHeader) computeHeaderHash() []byte {
-	prevHashStr := hex.EncodeToString(header.PrevHash)
-	merkleStr := hex.EncodeToString(header.MerkleRoot)
-
-	record := fmt.Sprintf("%d-%s-%s-%d-%d", header.Id, prevHashStr, merkleStr,
-		header.Timestamp, header.Nonce)
+	record := headerRecord(header.Id, header.PrevHash, header.MerkleRoot, header.Timestamp, header.Nonce, header.BaseFee)
 
 	hash := sha256.Sum256([]byte(record))
 	return hash[:]
 }
 
+// headerRecord -> Serialization of a block header that its hash commits to
+func headerRecord(id int64, prevHash, merkleRoot []byte, timestamp, nonce int64, baseFee uint64) string {
+	record := fmt.Sprintf("%d-%s-%s-%d-%d", id, hex.EncodeToString(prevHash), hex.EncodeToString(merkleRoot),
+		timestamp, nonce)
+
+	// Blocks from before the base fee carry 0 and keep the original encoding, so
+	// they still verify
+	if baseFee != 0 {
+		record += fmt.Sprintf("-%d", baseFee)
+	}
+
+	return record
+}
+
 // AddTransaction -> It`s for unit tests
 func (block *Block) AddTransaction(tx Transaction) {
 	block.Transactions = append(block.Transactions, tx)
@@ -272,6 +335,7 @@ func (block *Block) CalculateSize() int64 {
 	size += int64(len(block.MerkleRoot))
 	size += 8 // Timestamp (int64)
 	size += 8 // Nonce (int64)
+	size += 8 // BaseFee (uint64)
 
 	for _, tx := range block.Transactions {
 		size += int64(tx.Size())
@@ -0,0 +1,34 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+)
+
+// DefaultChainId -> Chain id of nodes started without --chain-id
+const DefaultChainId = "mainnet"
+
+var chainIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidateChainId -> A chain id is 1-32 lower case letters, digits or hyphens
+func ValidateChainId(id string) error {
+	if !chainIdPattern.MatchString(id) {
+		return fmt.Errorf("invalid chain id %q: must be 1-32 lower case letters, digits or hyphens", id)
+	}
+
+	return nil
+}
+
+// ChainId -> Network every signature made or verified by this blockchain is bound to
+func (bc *Blockchain) ChainId() string {
+	return bc.chainId
+}
+
+// SigHash -> Digest every signature of the tx covers: its content bound to chainId,
+// so a tx signed for one chain is invalid on every other. The local Status is left
+// out, a tx stays valid once it is stored as confirmed.
+func (tx *Transaction) SigHash(chainId string) []byte {
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s|%x", chainId, tx.contentHash()))
+	return hash[:]
+}
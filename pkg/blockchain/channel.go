@@ -0,0 +1,435 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// MaxChannelDisputePeriod -> Upper bound (in blocks) of a channel's dispute period
+const MaxChannelDisputePeriod = 10_000
+
+const (
+	ChannelStatusOpen    = "open"
+	ChannelStatusClosing = "closing"
+	ChannelStatusSettled = "settled"
+)
+
+var ErrChannelNotFound = errors.New("channel not found")
+
+// ChannelPayload -> Fields of the channel_open, channel_close and channel_settle tx types
+type ChannelPayload struct {
+	DisputePeriod int64         `json:"dispute_period,omitempty"` // open: blocks the recipient has to answer a sender's close
+	ChannelId     string        `json:"channel_id,omitempty"`     // close, settle: LedgerId of the open tx
+	State         *ChannelState `json:"state,omitempty"`          // close: latest balance update
+}
+
+// ChannelState -> Off-chain balance update signed by the channel sender.
+// Paid is cumulative, a later state has a higher Nonce and replaces the earlier ones.
+type ChannelState struct {
+	ChannelId string `json:"channel_id"`
+	Nonce     uint64 `json:"nonce"`
+	Paid      uint64 `json:"paid"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// Channel -> Funds escrowed by a channel_open tx, as kept in the ledger
+type Channel struct {
+	Id              string `json:"id"`
+	Sender          string `json:"sender"`
+	SenderPublicKey string `json:"sender_public_key"`
+	Recipient       string `json:"recipient"`
+	Deposit         uint64 `json:"deposit"`
+	DisputePeriod   int64  `json:"dispute_period"`
+	Status          string `json:"status"`
+	Nonce           uint64 `json:"nonce"`
+	Paid            uint64 `json:"paid"`
+	CloseFee        uint64 `json:"close_fee,omitempty"`
+	CloseHeight     int64  `json:"close_height,omitempty"`
+	SettleTx        string `json:"settle_tx,omitempty"`
+}
+
+// channelPayout -> Funds released by a channel close or settle
+type channelPayout struct {
+	sender    uint64
+	recipient uint64
+}
+
+// NewChannelOpenTransaction -> Unsigned tx escrowing deposit for payments from `from` to `to`
+func NewChannelOpenTransaction(from, to string, deposit uint64, disputePeriod int64) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Amount:    deposit,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeChannelOpen,
+		Channel: &ChannelPayload{
+			DisputePeriod: disputePeriod,
+		},
+	}
+}
+
+// NewChannelCloseTransaction -> Unsigned tx closing a channel with state, which is
+// required from the recipient and optional for the sender
+func NewChannelCloseTransaction(from, channelId string, state *ChannelState) *Transaction {
+	return &Transaction{
+		From:      from,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeChannelClose,
+		Channel: &ChannelPayload{
+			ChannelId: channelId,
+			State:     state,
+		},
+	}
+}
+
+// NewChannelSettleTransaction -> Unsigned tx paying out a channel whose dispute period has passed
+func NewChannelSettleTransaction(from, channelId string) *Transaction {
+	return &Transaction{
+		From:      from,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeChannelSettle,
+		Channel: &ChannelPayload{
+			ChannelId: channelId,
+		},
+	}
+}
+
+// NewChannelState -> Unsigned balance update paying `paid` in total to the recipient
+func NewChannelState(channelId string, nonce, paid uint64) *ChannelState {
+	return &ChannelState{
+		ChannelId: channelId,
+		Nonce:     nonce,
+		Paid:      paid,
+	}
+}
+
+// Hash -> Digest the sender signs, it does not depend on the signature and is bound
+// to chainId like tx signatures
+func (state *ChannelState) Hash(chainId string) []byte {
+	data := fmt.Sprintf("channel-state|%s|%s|%d|%d", chainId, state.ChannelId, state.Nonce, state.Paid)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+func (state *ChannelState) Sign(keyPair *CryptoGraphy.KeyPair, chainId string) {
+	state.Signature = keyPair.Sign(state.Hash(chainId))
+}
+
+func (state *ChannelState) SignWithHexKeys(privateKeyHex, publicKeyHex, chainId string) error {
+	keyPair, err := CryptoGraphy.LoadKeyPairFromHex(privateKeyHex, publicKeyHex)
+	if err != nil {
+		return err
+	}
+
+	state.Sign(keyPair, chainId)
+	return nil
+}
+
+// Verify -> Whether the state is signed by the key senderPublicKey for chainId
+func (state *ChannelState) Verify(senderPublicKey, chainId string) bool {
+	if state.Signature == nil {
+		return false
+	}
+
+	return CryptoGraphy.VerifySignature(senderPublicKey, state.Hash(chainId), state.Signature)
+}
+
+// VerifyChannelState -> Checks an off-chain balance update against its channel.
+// The recipient should run it on every update before delivering what it pays for.
+func VerifyChannelState(channel *Channel, state *ChannelState, chainId string) error {
+	if state.ChannelId != channel.Id {
+		return fmt.Errorf("state belongs to channel %s, not %s", state.ChannelId, channel.Id)
+	}
+
+	if !state.Verify(channel.SenderPublicKey, chainId) {
+		return errors.New("state is not signed by the channel sender")
+	}
+
+	if state.Paid > channel.Deposit {
+		return fmt.Errorf("state pays %d, more than the deposit %d", state.Paid, channel.Deposit)
+	}
+
+	return nil
+}
+
+func (tx *Transaction) isChannelSettlement() bool {
+	return tx.Type == TxTypeChannelClose || tx.Type == TxTypeChannelSettle
+}
+
+func (tx *Transaction) validateChannelPayload() error {
+	if tx.Channel == nil {
+		return fmt.Errorf("%s transaction needs a channel payload", tx.Type)
+	}
+
+	if tx.IsBatch() {
+		return fmt.Errorf("%s transaction cannot have outputs", tx.Type)
+	}
+
+	if tx.Type == TxTypeChannelOpen {
+		// Balance updates are checked against a single sender key
+		if tx.IsMultisig() || tx.IsScript() {
+			return errors.New("channel sender must sign with a single key")
+		}
+
+		if tx.To == "" {
+			return errors.New("channel needs a recipient")
+		}
+
+		if tx.To == tx.From {
+			return errors.New("channel recipient must differ from the sender")
+		}
+
+		if tx.Channel.DisputePeriod < 1 || tx.Channel.DisputePeriod > MaxChannelDisputePeriod {
+			return fmt.Errorf("dispute period must be between 1 and %d blocks", MaxChannelDisputePeriod)
+		}
+
+		if tx.Channel.ChannelId != "" || tx.Channel.State != nil {
+			return errors.New("channel open must not set channel_id or state")
+		}
+
+		return nil
+	}
+
+	// close and settle
+	if tx.Amount != 0 || tx.To != "" {
+		return fmt.Errorf("%s transaction must not set 'to' or 'amount'", tx.Type)
+	}
+
+	if tx.Channel.ChannelId == "" {
+		return errors.New("channel_id is required")
+	}
+
+	if tx.Channel.DisputePeriod != 0 {
+		return fmt.Errorf("%s transaction must not set dispute_period", tx.Type)
+	}
+
+	state := tx.Channel.State
+	if state == nil {
+		return nil
+	}
+
+	if tx.Type == TxTypeChannelSettle {
+		return errors.New("channel settle must not carry a state")
+	}
+
+	if state.ChannelId != tx.Channel.ChannelId {
+		return fmt.Errorf("state belongs to channel %s", state.ChannelId)
+	}
+
+	return nil
+}
+
+// GetChannel -> Confirmed state of a channel, ErrChannelNotFound if no mined open has this id
+func (bc *Blockchain) GetChannel(channelId string) (*Channel, error) {
+	dbChannel, err := bc.Database.GetChannel(channelId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+
+		return nil, err
+	}
+
+	return parseDBChannel(dbChannel), nil
+}
+
+// close -> Next ledger state of the channel once tx is mined at height, and the
+// funds it releases. A close by the sender only starts the dispute period.
+func (channel *Channel) close(tx *Transaction, height int64, chainId string) (*Channel, *channelPayout, error) {
+	fee := tx.settlementFee()
+	next := *channel
+
+	switch {
+	case tx.Type == TxTypeChannelSettle:
+		if channel.Status != ChannelStatusClosing {
+			return nil, nil, fmt.Errorf("channel %s is %s, not closing", channel.Id, channel.Status)
+		}
+
+		if tx.From != channel.Sender && tx.From != channel.Recipient {
+			return nil, nil, errors.New("only the channel sender or recipient can settle it")
+		}
+
+		if height < channel.CloseHeight+channel.DisputePeriod {
+			return nil, nil, fmt.Errorf("channel %s cannot be settled before height %d",
+				channel.Id, channel.CloseHeight+channel.DisputePeriod)
+		}
+
+	case tx.From == channel.Recipient:
+		if channel.Status == ChannelStatusSettled {
+			return nil, nil, fmt.Errorf("channel %s is already settled", channel.Id)
+		}
+
+		state := tx.Channel.State
+		if state == nil {
+			return nil, nil, errors.New("the channel recipient must close with a signed state")
+		}
+
+		if err := VerifyChannelState(channel, state, chainId); err != nil {
+			return nil, nil, err
+		}
+
+		if state.Nonce < channel.Nonce {
+			return nil, nil, fmt.Errorf("state nonce %d is older than the closing state %d", state.Nonce, channel.Nonce)
+		}
+
+		next.Nonce, next.Paid = state.Nonce, state.Paid
+
+	case tx.From == channel.Sender:
+		if channel.Status != ChannelStatusOpen {
+			return nil, nil, fmt.Errorf("channel %s is already %s", channel.Id, channel.Status)
+		}
+
+		if state := tx.Channel.State; state != nil {
+			if err := VerifyChannelState(channel, state, chainId); err != nil {
+				return nil, nil, err
+			}
+
+			next.Nonce, next.Paid = state.Nonce, state.Paid
+		}
+
+		// The fee of a close that pays nothing out yet comes out of the sender's share
+		if fee > channel.Deposit-next.Paid {
+			return nil, nil, fmt.Errorf("fee %d exceeds the sender's share %d", fee, channel.Deposit-next.Paid)
+		}
+
+		next.Status = ChannelStatusClosing
+		next.CloseFee = fee
+		next.CloseHeight = height
+		return &next, nil, nil
+
+	default:
+		return nil, nil, errors.New("only the channel sender or recipient can close it")
+	}
+
+	// A late balance update may pay more than the deposit left after the close fee
+	remaining := channel.Deposit - channel.CloseFee
+	payout := &channelPayout{recipient: min(next.Paid, remaining)}
+	payout.sender = remaining - payout.recipient
+
+	share := &payout.sender
+	if tx.From == channel.Recipient {
+		share = &payout.recipient
+	}
+
+	if fee > *share {
+		return nil, nil, fmt.Errorf("fee %d exceeds the closer's share %d", fee, *share)
+	}
+
+	*share -= fee
+
+	next.Status = ChannelStatusSettled
+	next.SettleTx = tx.LedgerId()
+	return &next, payout, nil
+}
+
+// verifyChannelSettlement -> Whether the close or settle may apply to its channel in a block at height
+func (bc *Blockchain) verifyChannelSettlement(tx *Transaction, height int64) (*Channel, error) {
+	channel, err := bc.GetChannel(tx.Channel.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	next, _, err := channel.close(tx, height, bc.chainId)
+	return next, err
+}
+
+// openChannel -> Records the deposit of a channel_open tx in the ledger
+func (bc *Blockchain) openChannel(sqlTx *sql.Tx, tx *Transaction) error {
+	return bc.Database.AddChannel(sqlTx, database.DBChannelSchema{
+		ChannelId:       tx.LedgerId(),
+		Sender:          tx.From,
+		SenderPublicKey: tx.PublicKey,
+		Recipient:       tx.To,
+		Deposit:         tx.Amount,
+		DisputePeriod:   tx.Channel.DisputePeriod,
+		Status:          ChannelStatusOpen,
+	})
+}
+
+// settleChannel -> Applies a close or settle mined at height and pays out what it releases
+func (bc *Blockchain) settleChannel(sqlTx *sql.Tx, height int64, tx *Transaction) error {
+	dbChannel, err := bc.Database.GetChannelTx(sqlTx, tx.Channel.ChannelId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("channel %s does not exist", tx.Channel.ChannelId)
+		}
+
+		return err
+	}
+
+	channel := parseDBChannel(dbChannel)
+
+	next, payout, err := channel.close(tx, height, bc.chainId)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.chargeSettlementFee(sqlTx, tx); err != nil {
+		return err
+	}
+
+	if err := bc.Database.UpdateChannel(sqlTx, toDBChannel(next), channel.Status); err != nil {
+		return err
+	}
+
+	if payout == nil {
+		return nil
+	}
+
+	if payout.sender > 0 {
+		if err := bc.Database.IncreaseUserBalance(sqlTx, channel.Sender, payout.sender); err != nil {
+			return err
+		}
+	}
+
+	if payout.recipient > 0 {
+		if err := bc.Database.IncreaseUserBalance(sqlTx, channel.Recipient, payout.recipient); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseDBChannel(dbChannel *database.DBChannelSchema) *Channel {
+	return &Channel{
+		Id:              dbChannel.ChannelId,
+		Sender:          dbChannel.Sender,
+		SenderPublicKey: dbChannel.SenderPublicKey,
+		Recipient:       dbChannel.Recipient,
+		Deposit:         dbChannel.Deposit,
+		DisputePeriod:   dbChannel.DisputePeriod,
+		Status:          dbChannel.Status,
+		Nonce:           dbChannel.Nonce,
+		Paid:            dbChannel.Paid,
+		CloseFee:        dbChannel.CloseFee,
+		CloseHeight:     dbChannel.CloseHeight,
+		SettleTx:        dbChannel.SettleTx,
+	}
+}
+
+func toDBChannel(channel *Channel) database.DBChannelSchema {
+	return database.DBChannelSchema{
+		ChannelId:       channel.Id,
+		Sender:          channel.Sender,
+		SenderPublicKey: channel.SenderPublicKey,
+		Recipient:       channel.Recipient,
+		Deposit:         channel.Deposit,
+		DisputePeriod:   channel.DisputePeriod,
+		Status:          channel.Status,
+		Nonce:           channel.Nonce,
+		Paid:            channel.Paid,
+		CloseFee:        channel.CloseFee,
+		CloseHeight:     channel.CloseHeight,
+		SettleTx:        channel.SettleTx,
+	}
+}
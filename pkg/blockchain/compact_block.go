@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrCompactBlockMismatch = errors.New("rebuilt block does not match its header")
+
+// CompactBlock -> A block as its header, coinbase and short ids of its other txs. A
+// peer holding those txs in its mempool rebuilds the block without receiving them.
+type CompactBlock struct {
+	Header   BlockHeader `json:"header"`
+	Coinbase Transaction `json:"coinbase"`
+	ShortIds []uint64    `json:"short_ids"` // Txs after the coinbase, in block order
+}
+
+// ShortTxId -> First 8 bytes of sha256(blockHash || txHash). Salting with the block
+// hash keeps a collision in one block from carrying over to the next.
+func ShortTxId(blockHash []byte, txHash TxHash) uint64 {
+	hash := sha256.Sum256(append(bytes.Clone(blockHash), txHash...))
+	return binary.BigEndian.Uint64(hash[:8])
+}
+
+func NewCompactBlock(block *Block) (*CompactBlock, error) {
+	if len(block.Transactions) == 0 || !block.Transactions[0].IsCoinbase {
+		return nil, fmt.Errorf("block %d does not start with a coinbase transaction", block.Id)
+	}
+
+	shortIds := make([]uint64, 0, len(block.Transactions)-1)
+	for _, tx := range block.Transactions[1:] {
+		shortIds = append(shortIds, ShortTxId(block.Hash, tx.Hash()))
+	}
+
+	return &CompactBlock{
+		Header:   *block.GetHeader(),
+		Coinbase: block.Transactions[0],
+		ShortIds: shortIds,
+	}, nil
+}
+
+// Rebuild -> The block with every tx found in mempool filled in, and the ShortIds
+// indexes of the txs that are not. A short id matching several mempool txs counts
+// as missing.
+func (compact *CompactBlock) Rebuild(mempool *Mempool) (*Block, []int) {
+	candidates := make(map[uint64]*Transaction)
+	for _, tx := range mempool.GetTransactionsCopy() {
+		shortId := ShortTxId(compact.Header.Hash, tx.Hash())
+		if _, ok := candidates[shortId]; ok {
+			candidates[shortId] = nil
+			continue
+		}
+
+		candidates[shortId] = &tx
+	}
+
+	block := &Block{
+		Id:           compact.Header.Id,
+		PrevHash:     compact.Header.PrevHash,
+		Hash:         compact.Header.Hash,
+		MerkleRoot:   compact.Header.MerkleRoot,
+		Timestamp:    compact.Header.Timestamp,
+		Nonce:        compact.Header.Nonce,
+		BaseFee:      compact.Header.BaseFee,
+		Transactions: make([]Transaction, len(compact.ShortIds)+1),
+	}
+	block.Transactions[0] = compact.Coinbase
+
+	var missing []int
+	for idx, shortId := range compact.ShortIds {
+		tx := candidates[shortId]
+		if tx == nil {
+			missing = append(missing, idx)
+			continue
+		}
+
+		block.Transactions[idx+1] = *tx
+	}
+
+	return block, missing
+}
+
+// Complete -> Puts the txs received for the missing indexes into block and checks
+// that the result hashes to the announced header
+func (compact *CompactBlock) Complete(block *Block, missing []int, txs []Transaction) error {
+	if len(txs) != len(missing) {
+		return fmt.Errorf("expected %d missing transactions, got %d", len(missing), len(txs))
+	}
+
+	for i, idx := range missing {
+		if ShortTxId(compact.Header.Hash, txs[i].Hash()) != compact.ShortIds[idx] {
+			return fmt.Errorf("transaction %d does not match its short id", idx+1)
+		}
+
+		block.Transactions[idx+1] = txs[i]
+	}
+
+	if !compact.Coinbase.IsCoinbase {
+		return errors.New("compact block has no coinbase transaction")
+	}
+
+	// The hash commits to the transactions through the merkle root
+	computed := *block
+	computed.MerkleRoot = nil
+	if err := computed.HashBlock(); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(computed.Hash, compact.Header.Hash) {
+		return ErrCompactBlockMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"errors"
+	"sync"
+)
+
+// MaxConfirmTarget -> Highest confirmation target (in blocks) the estimator can answer for
+const MaxConfirmTarget = 25
+
+const (
+	feeEstimatorDecay            = 0.998 // Older observations fade out block by block
+	feeEstimatorSuccessThreshold = 0.85  // Share of txs that must confirm within the target
+	feeEstimatorMinSamples       = 1.0   // Decayed sample count a bucket needs to be trusted
+	feeEstimatorMaxBucketRate    = 8192
+)
+
+var ErrInsufficientFeeData = errors.New("not enough confirmation history to estimate fee")
+
+type feeBucket struct {
+	MinRate uint64
+
+	// confirmed[i] -> txs from this bucket which confirmed within i+1 blocks
+	confirmed [MaxConfirmTarget]float64
+	total     float64 // txs that either confirmed or gave up waiting
+	rateSum   float64 // sum of fee rates of the txs counted in total
+}
+
+type trackedTx struct {
+	feeRate     uint64
+	bucket      int
+	entryHeight int64
+}
+
+// FeeEstimator -> Tracks how many blocks transactions of each fee rate bucket
+// needed to confirm and answers "fee rate for confirmation within N blocks"
+type FeeEstimator struct {
+	buckets []feeBucket
+	tracked map[string]trackedTx
+
+	Mutex sync.Mutex
+}
+
+type FeeEstimate struct {
+	TargetBlocks int     `json:"target_blocks"`
+	FeeRate      uint64  `json:"fee_rate"`   // per byte
+	Confidence   float64 `json:"confidence"` // 0..1
+}
+
+func NewFeeEstimator() *FeeEstimator {
+	buckets := make([]feeBucket, 0)
+	for rate := uint64(1); rate <= feeEstimatorMaxBucketRate; rate *= 2 {
+		buckets = append(buckets, feeBucket{MinRate: rate})
+	}
+
+	return &FeeEstimator{
+		buckets: buckets,
+		tracked: make(map[string]trackedTx),
+	}
+}
+
+// TrackTransaction -> Remembers the height at which tx entered the mempool
+func (fe *FeeEstimator) TrackTransaction(tx *Transaction, height int64) {
+	if tx == nil || tx.IsCoinbase {
+		return
+	}
+
+	size := tx.Size()
+	if size == 0 {
+		return
+	}
+
+	hash := tx.Hash().EncodeToString()
+	feeRate := tx.Fee / uint64(size)
+
+	fe.Mutex.Lock()
+	defer fe.Mutex.Unlock()
+
+	if _, exists := fe.tracked[hash]; exists {
+		return
+	}
+
+	fe.tracked[hash] = trackedTx{
+		feeRate:     feeRate,
+		bucket:      fe.bucketIndex(feeRate),
+		entryHeight: height,
+	}
+}
+
+// ProcessBlock -> Records confirmation times of the tracked txs included in the block
+func (fe *FeeEstimator) ProcessBlock(height int64, txs []Transaction) {
+	fe.Mutex.Lock()
+	defer fe.Mutex.Unlock()
+
+	for idx := range fe.buckets {
+		fe.buckets[idx].decay()
+	}
+
+	for _, tx := range txs {
+		if tx.IsCoinbase {
+			continue
+		}
+
+		hash := tx.Hash().EncodeToString()
+
+		entry, exists := fe.tracked[hash]
+		if !exists {
+			continue
+		}
+
+		delete(fe.tracked, hash)
+
+		blocksToConfirm := int(height - entry.entryHeight)
+		if blocksToConfirm < 1 {
+			blocksToConfirm = 1
+		}
+
+		fe.buckets[entry.bucket].record(entry.feeRate, blocksToConfirm)
+	}
+
+	// Txs that waited longer than the highest target count as failures for every target
+	for hash, entry := range fe.tracked {
+		if height-entry.entryHeight > MaxConfirmTarget {
+			delete(fe.tracked, hash)
+			fe.buckets[entry.bucket].record(entry.feeRate, MaxConfirmTarget+1)
+		}
+	}
+}
+
+// EstimateFee -> Lowest fee rate whose txs confirmed within target blocks
+// at least feeEstimatorSuccessThreshold of the time
+func (fe *FeeEstimator) EstimateFee(target int) (*FeeEstimate, error) {
+	if target < 1 || target > MaxConfirmTarget {
+		return nil, errors.New("target must be between 1 and MaxConfirmTarget blocks")
+	}
+
+	fe.Mutex.Lock()
+	defer fe.Mutex.Unlock()
+
+	var found *FeeEstimate
+
+	// Walk from the most expensive bucket down, stop at the first one that fails
+	for idx := len(fe.buckets) - 1; idx >= 0; idx-- {
+		bucket := &fe.buckets[idx]
+		if bucket.total < feeEstimatorMinSamples {
+			continue
+		}
+
+		successRate := bucket.confirmed[target-1] / bucket.total
+		if successRate < feeEstimatorSuccessThreshold {
+			break
+		}
+
+		found = &FeeEstimate{
+			TargetBlocks: target,
+			FeeRate:      bucket.averageRate(),
+			Confidence:   successRate,
+		}
+	}
+
+	if found == nil {
+		return nil, ErrInsufficientFeeData
+	}
+
+	return found, nil
+}
+
+func (fe *FeeEstimator) bucketIndex(feeRate uint64) int {
+	for idx := len(fe.buckets) - 1; idx > 0; idx-- {
+		if feeRate >= fe.buckets[idx].MinRate {
+			return idx
+		}
+	}
+
+	return 0
+}
+
+func (bucket *feeBucket) record(feeRate uint64, blocksToConfirm int) {
+	for idx := blocksToConfirm - 1; idx < MaxConfirmTarget; idx++ {
+		bucket.confirmed[idx]++
+	}
+
+	bucket.total++
+	bucket.rateSum += float64(feeRate)
+}
+
+func (bucket *feeBucket) decay() {
+	for idx := range bucket.confirmed {
+		bucket.confirmed[idx] *= feeEstimatorDecay
+	}
+
+	bucket.total *= feeEstimatorDecay
+	bucket.rateSum *= feeEstimatorDecay
+}
+
+func (bucket *feeBucket) averageRate() uint64 {
+	if bucket.total == 0 {
+		return bucket.MinRate
+	}
+
+	return uint64(bucket.rateSum/bucket.total + 0.5)
+}
@@ -0,0 +1,293 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// MaxHTLCPreimageSize -> Upper bound (in bytes) of an HTLC preimage
+const MaxHTLCPreimageSize = 256
+
+const (
+	HTLCStatusLocked   = "locked"
+	HTLCStatusClaimed  = "claimed"
+	HTLCStatusRefunded = "refunded"
+)
+
+var ErrHTLCNotFound = errors.New("htlc not found")
+
+// HTLCPayload -> Fields of the htlc_lock, htlc_claim and htlc_refund tx types
+type HTLCPayload struct {
+	HashLock string `json:"hash_lock,omitempty"` // lock: hex sha256 of the preimage
+	Expiry   int64  `json:"expiry,omitempty"`    // lock: block height from which the sender can refund
+	HTLCId   string `json:"htlc_id,omitempty"`   // claim, refund: LedgerId of the lock tx
+	Preimage string `json:"preimage,omitempty"`  // claim: hex
+}
+
+// HTLC -> Funds locked by an htlc_lock tx, as kept in the ledger
+type HTLC struct {
+	Id        string `json:"id"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount    uint64 `json:"amount"`
+	HashLock  string `json:"hash_lock"`
+	Expiry    int64  `json:"expiry"`
+	Status    string `json:"status"`
+	Preimage  string `json:"preimage,omitempty"`
+	SettleTx  string `json:"settle_tx,omitempty"`
+}
+
+// NewHTLCLockTransaction -> Unsigned tx locking amount to `to` until the preimage
+// of hashLock is revealed, refundable to `from` from height expiry on
+func NewHTLCLockTransaction(from, to string, amount uint64, hashLock string, expiry int64) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeHTLCLock,
+		HTLC: &HTLCPayload{
+			HashLock: hashLock,
+			Expiry:   expiry,
+		},
+	}
+}
+
+// NewHTLCClaimTransaction -> Unsigned tx releasing an HTLC to its recipient
+func NewHTLCClaimTransaction(from, htlcId, preimage string) *Transaction {
+	return &Transaction{
+		From:      from,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeHTLCClaim,
+		HTLC: &HTLCPayload{
+			HTLCId:   htlcId,
+			Preimage: preimage,
+		},
+	}
+}
+
+// NewHTLCRefundTransaction -> Unsigned tx returning an expired HTLC to its sender
+func NewHTLCRefundTransaction(from, htlcId string) *Transaction {
+	return &Transaction{
+		From:      from,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeHTLCRefund,
+		HTLC: &HTLCPayload{
+			HTLCId: htlcId,
+		},
+	}
+}
+
+func (tx *Transaction) isHTLCSettlement() bool {
+	return tx.Type == TxTypeHTLCClaim || tx.Type == TxTypeHTLCRefund
+}
+
+func (tx *Transaction) validateHTLCPayload() error {
+	if tx.HTLC == nil {
+		return fmt.Errorf("%s transaction needs an htlc payload", tx.Type)
+	}
+
+	if tx.IsBatch() {
+		return fmt.Errorf("%s transaction cannot have outputs", tx.Type)
+	}
+
+	if tx.Type == TxTypeHTLCLock {
+		hashLock, err := hex.DecodeString(tx.HTLC.HashLock)
+		if err != nil || len(hashLock) != sha256.Size {
+			return errors.New("hash lock must be a hex encoded sha256 hash")
+		}
+
+		if tx.HTLC.Expiry <= 0 {
+			return errors.New("htlc expiry must be a positive block height")
+		}
+
+		if tx.To == "" {
+			return errors.New("htlc needs a recipient")
+		}
+
+		if tx.HTLC.HTLCId != "" || tx.HTLC.Preimage != "" {
+			return errors.New("htlc lock must not set htlc_id or preimage")
+		}
+
+		return nil
+	}
+
+	// claim and refund
+	if tx.Amount != 0 || tx.To != "" {
+		return fmt.Errorf("%s transaction must not set 'to' or 'amount'", tx.Type)
+	}
+
+	if tx.HTLC.HTLCId == "" {
+		return errors.New("htlc_id is required")
+	}
+
+	if tx.HTLC.HashLock != "" || tx.HTLC.Expiry != 0 {
+		return fmt.Errorf("%s transaction must not set hash_lock or expiry", tx.Type)
+	}
+
+	if tx.Type == TxTypeHTLCRefund {
+		if tx.HTLC.Preimage != "" {
+			return errors.New("htlc refund must not reveal a preimage")
+		}
+
+		return nil
+	}
+
+	preimage, err := hex.DecodeString(tx.HTLC.Preimage)
+	if err != nil || len(preimage) == 0 {
+		return errors.New("preimage must be non-empty hex")
+	}
+
+	if len(preimage) > MaxHTLCPreimageSize {
+		return fmt.Errorf("preimage exceeds %d bytes", MaxHTLCPreimageSize)
+	}
+
+	return nil
+}
+
+// HashLockOf -> Hex sha256 of a hex encoded preimage
+func HashLockOf(preimageHex string) (string, error) {
+	preimage, err := hex.DecodeString(preimageHex)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(preimage)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// GetHTLC -> Confirmed state of an HTLC, ErrHTLCNotFound if no mined lock has this id
+func (bc *Blockchain) GetHTLC(htlcId string) (*HTLC, error) {
+	dbHTLC, err := bc.Database.GetHTLC(htlcId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrHTLCNotFound
+		}
+
+		return nil, err
+	}
+
+	return parseDBHTLC(dbHTLC), nil
+}
+
+// verifyHTLCSettlement -> Whether the claim or refund may settle its HTLC in a block at height
+func (bc *Blockchain) verifyHTLCSettlement(tx *Transaction, height int64) (*HTLC, error) {
+	htlc, err := bc.GetHTLC(tx.HTLC.HTLCId)
+	if err != nil {
+		return nil, err
+	}
+
+	if htlc.Status != HTLCStatusLocked {
+		return nil, fmt.Errorf("htlc %s is already %s", htlc.Id, htlc.Status)
+	}
+
+	if tx.settlementFee() > htlc.Amount {
+		return nil, fmt.Errorf("fee %d exceeds the htlc amount %d", tx.settlementFee(), htlc.Amount)
+	}
+
+	if tx.Type == TxTypeHTLCRefund {
+		if tx.From != htlc.Sender {
+			return nil, errors.New("only the htlc sender can refund it")
+		}
+
+		if height < htlc.Expiry {
+			return nil, fmt.Errorf("htlc %s cannot be refunded before height %d", htlc.Id, htlc.Expiry)
+		}
+
+		return htlc, nil
+	}
+
+	if tx.From != htlc.Recipient {
+		return nil, errors.New("only the htlc recipient can claim it")
+	}
+
+	if height >= htlc.Expiry {
+		return nil, fmt.Errorf("htlc %s expired at height %d", htlc.Id, htlc.Expiry)
+	}
+
+	hashLock, err := HashLockOf(tx.HTLC.Preimage)
+	if err != nil || hashLock != htlc.HashLock {
+		return nil, errors.New("preimage does not match the hash lock")
+	}
+
+	return htlc, nil
+}
+
+// lockHTLC -> Records the funds of an htlc_lock tx in the ledger
+func (bc *Blockchain) lockHTLC(sqlTx *sql.Tx, tx *Transaction) error {
+	return bc.Database.AddHTLC(sqlTx, database.DBHTLCSchema{
+		HTLCId:    tx.LedgerId(),
+		Sender:    tx.From,
+		Recipient: tx.To,
+		Amount:    tx.Amount,
+		HashLock:  tx.HTLC.HashLock,
+		Expiry:    tx.HTLC.Expiry,
+		Status:    HTLCStatusLocked,
+	})
+}
+
+// settleHTLC -> Marks the HTLC claimed or refunded and pays it out to tx.From
+func (bc *Blockchain) settleHTLC(sqlTx *sql.Tx, tx *Transaction) error {
+	status := HTLCStatusClaimed
+	if tx.Type == TxTypeHTLCRefund {
+		status = HTLCStatusRefunded
+	}
+
+	dbHTLC, err := bc.Database.SettleHTLC(sqlTx, tx.HTLC.HTLCId, status, tx.HTLC.Preimage, tx.LedgerId())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("htlc %s is not locked", tx.HTLC.HTLCId)
+		}
+
+		return err
+	}
+
+	payee := dbHTLC.Recipient
+	if tx.Type == TxTypeHTLCRefund {
+		payee = dbHTLC.Sender
+	}
+
+	if payee != tx.From {
+		return fmt.Errorf("%s of htlc %s signed by %s", tx.Type, dbHTLC.HTLCId, tx.From)
+	}
+
+	if err := bc.chargeSettlementFee(sqlTx, tx); err != nil {
+		return err
+	}
+
+	payout := dbHTLC.Amount
+	if tx.settlementFee() > payout {
+		return fmt.Errorf("fee %d exceeds the htlc amount %d", tx.settlementFee(), payout)
+	}
+
+	payout -= tx.settlementFee()
+
+	if payout == 0 {
+		return nil
+	}
+
+	return bc.Database.IncreaseUserBalance(sqlTx, payee, payout)
+}
+
+func parseDBHTLC(dbHTLC *database.DBHTLCSchema) *HTLC {
+	return &HTLC{
+		Id:        dbHTLC.HTLCId,
+		Sender:    dbHTLC.Sender,
+		Recipient: dbHTLC.Recipient,
+		Amount:    dbHTLC.Amount,
+		HashLock:  dbHTLC.HashLock,
+		Expiry:    dbHTLC.Expiry,
+		Status:    dbHTLC.Status,
+		Preimage:  dbHTLC.Preimage,
+		SettleTx:  dbHTLC.SettleTx,
+	}
+}
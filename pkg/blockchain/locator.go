@@ -0,0 +1,91 @@
+package blockchain
+
+import "encoding/hex"
+
+const (
+	// MaxHeadersPerMsg -> Most headers a single getheaders response carries
+	MaxHeadersPerMsg = 2000
+
+	// MaxLocatorHashes -> Most hashes a locator may carry. BlockLocator needs far fewer
+	// for any realistic chain, longer locators are refused.
+	MaxLocatorHashes = 101
+
+	// locatorDenseHashes -> Locator hashes taken one block apart before the step doubles
+	locatorDenseHashes = 10
+)
+
+// BlockLocator -> Hashes from the tip back to genesis, the first ten one block apart
+// and then exponentially spaced. A peer finds the last block both chains share in it.
+func (bc *Blockchain) BlockLocator() [][]byte {
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	if len(bc.Blocks) == 0 {
+		return nil
+	}
+
+	locator := make([][]byte, 0, locatorDenseHashes+16)
+
+	step := 1
+	for idx := len(bc.Blocks) - 1; idx > 0; idx -= step {
+		locator = append(locator, bc.Blocks[idx].Hash)
+
+		if len(locator) >= locatorDenseHashes {
+			step *= 2
+		}
+	}
+
+	return append(locator, bc.Blocks[0].Hash)
+}
+
+// HeadersAfter -> Up to limit headers following the first locator hash that is on our
+// chain, starting at genesis when none is
+func (bc *Blockchain) HeadersAfter(locator [][]byte, limit int) []BlockHeader {
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	start := 0
+	for _, hash := range locator {
+		if idx := bc.indexOfHash(hash); idx >= 0 {
+			start = idx + 1
+			break
+		}
+	}
+
+	end := min(len(bc.Blocks), start+limit)
+	if start >= end {
+		return []BlockHeader{}
+	}
+
+	headers := make([]BlockHeader, 0, end-start)
+	for idx := start; idx < end; idx++ {
+		headers = append(headers, *bc.Blocks[idx].GetHeader())
+	}
+
+	return headers
+}
+
+// GetBlockByHash -> Block with the given hash in memory, nil if it is not on our chain
+func (bc *Blockchain) GetBlockByHash(hash []byte) *Block {
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	idx := bc.indexOfHash(hash)
+	if idx < 0 {
+		return nil
+	}
+
+	block := bc.Blocks[idx]
+	return &block
+}
+
+// indexOfHash -> Index of the block with hash in bc.Blocks, -1 if there is none.
+// The caller holds bc.Mutex.
+func (bc *Blockchain) indexOfHash(hash []byte) int {
+	idx, ok := bc.blockIndex[hex.EncodeToString(hash)]
+	if !ok {
+		return -1
+	}
+
+	return idx
+}
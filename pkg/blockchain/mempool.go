@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -84,7 +85,7 @@ func (mp *Mempool) CalculateTxFee() uint64 {
 
 func (mp *Mempool) CalculateFee(tx *Transaction) uint64 {
 	txFeeRate := mp.CalculateTxFee() // Satoshis per byte
-	txSize := uint64(tx.Size())
+	txSize := uint64(tx.SignedSize())
 
 	// total fee = rate * size
 	fee := txFeeRate * txSize
@@ -95,28 +96,6 @@ func (mp *Mempool) CalculateFee(tx *Transaction) uint64 {
 	return fee
 }
 
-func (mp *Mempool) SyncMempool(syncCandidateMempool *Mempool) {
-	candidateTxs := syncCandidateMempool.GetTransactionsCopy()
-
-	mp.Mutex.Lock()
-	defer mp.Mutex.Unlock()
-
-	if len(candidateTxs) == 0 {
-		mp.Clear()
-		return
-	}
-
-	mp.syncTransactions(candidateTxs)
-}
-
-func (mp *Mempool) syncTransactions(newTxs map[string]Transaction) {
-	for hash, tx := range newTxs {
-		if _, exists := mp.Transactions[hash]; !exists {
-			mp.Transactions[hash] = tx
-		}
-	}
-}
-
 func (mp *Mempool) IsEmpty() bool {
 	return len(mp.Transactions) == 0
 }
@@ -189,10 +168,57 @@ func (mp *Mempool) WillExceedCapacity(tx *Transaction) bool {
 }
 
 func (mp *Mempool) GetTransaction(hash string) (*Transaction, bool) {
+	mp.Mutex.RLock()
+	defer mp.Mutex.RUnlock()
+
 	tx, exists := mp.Transactions[hash]
 	return &tx, exists
 }
 
 func (mp *Mempool) RemoveTransaction(hash string) {
+	mp.Mutex.Lock()
+	defer mp.Mutex.Unlock()
+
 	delete(mp.Transactions, hash)
 }
+
+func (mp *Mempool) HasTransaction(hash string) bool {
+	mp.Mutex.RLock()
+	defer mp.Mutex.RUnlock()
+
+	_, exists := mp.Transactions[hash]
+	return exists
+}
+
+// GetSenderUsage -> Number of pending txs and their total size for the sender
+func (mp *Mempool) GetSenderUsage(address string) (int, int) {
+	mp.Mutex.RLock()
+	defer mp.Mutex.RUnlock()
+
+	count, size := 0, 0
+	for _, tx := range mp.Transactions {
+		if tx.From != address {
+			continue
+		}
+
+		count++
+		size += tx.Size()
+	}
+
+	return count, size
+}
+
+// FindByDataPrefix -> Pending txs whose Data starts with prefix
+func (mp *Mempool) FindByDataPrefix(prefix string) []Transaction {
+	mp.Mutex.RLock()
+	defer mp.Mutex.RUnlock()
+
+	matches := make([]Transaction, 0)
+	for _, tx := range mp.Transactions {
+		if tx.Data != "" && strings.HasPrefix(tx.Data, prefix) {
+			matches = append(matches, tx)
+		}
+	}
+
+	return matches
+}
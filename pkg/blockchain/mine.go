@@ -3,6 +3,7 @@ package blockchain
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"sort"
@@ -16,29 +17,52 @@ func (bc *Blockchain) MineBlock(ctx context.Context, mempool *Mempool, minerAddr
 		fmt.Println("Mining cancelled")
 		return nil, nil
 	default:
+		bc.Mutex.RLock()
+		prevHash := getPreviousBlockHash(bc.Blocks)
+		blockIndex := len(bc.Blocks)
+		bc.Mutex.RUnlock()
+
+		baseFee, err := bc.expectedBaseFee(int64(blockIndex))
+		if err != nil {
+			return nil, err
+		}
+
 		transactions := mempool.GetTransactionsCopy()
 
 		// Priority based
 		sortedTxs := sortTxsByFee(transactions)
 
-		coinBaseTx := CreateCoinbaseTx(minerAddress, MiningReward)
+		// Time locked txs stay in the mempool until they become final
+		finalTxs := bc.filterFinalTransactions(sortedTxs, int64(blockIndex))
 
-		allTransactions := append([]Transaction{*coinBaseTx}, sortedTxs...)
+		// Scripts may compare against the height or time of the block
+		finalTxs = bc.filterScriptSpends(finalTxs, int64(blockIndex))
 
-		bc.Mutex.RLock()
-		prevHash := getPreviousBlockHash(bc.Blocks)
-		blockIndex := len(bc.Blocks)
-		bc.Mutex.RUnlock()
+		// Settlements and name operations that can no longer apply would invalidate the block
+		finalTxs = bc.filterSettlements(finalTxs, int64(blockIndex))
+		finalTxs = bc.filterNameOps(finalTxs, int64(blockIndex))
+
+		coinBaseTx := CreateCoinbaseTx(minerAddress, MiningReward)
 
 		newBlock := &Block{
 			Id:           int64(blockIndex),
 			PrevHash:     prevHash,
 			Hash:         nil,
 			Timestamp:    utils.GetTimestamp(),
-			Transactions: allTransactions,
+			Transactions: []Transaction{*coinBaseTx},
 			Nonce:        0,
+			BaseFee:      baseFee,
 		}
 
+		// Txs below the base fee wait for it to drop, the rest fill the block up to its
+		// max size. Hash and MerkleRoot are only set once the block is mined.
+		usedSize := newBlock.CalculateSize() + 2*sha256.Size
+		blockTxs, err := bc.filterApplicable(mempool, fillBlock(finalTxs, baseFee, usedSize), int64(blockIndex), baseFee)
+		if err != nil {
+			return nil, err
+		}
+		newBlock.Transactions = append(newBlock.Transactions, blockTxs...)
+
 		// mining started...
 		mined, err := bc.proofOfWork(ctx, newBlock)
 		if err != nil {
@@ -84,6 +108,42 @@ func (bc *Blockchain) MineBlock(ctx context.Context, mempool *Mempool, minerAddr
 	}
 }
 
+// filterApplicable -> Txs of a block at height that the ledger accepts, in order. Each
+// one is tried on a transaction that is rolled back; one that fails is dropped from
+// the mempool rather than failing every block it would be mined in.
+func (bc *Blockchain) filterApplicable(mempool *Mempool, txs []Transaction, height int64, baseFee uint64) ([]Transaction, error) {
+	sqlTx, err := bc.Database.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlTx.Rollback()
+
+	applicable := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if err := bc.Database.Savepoint(sqlTx, "apply_tx"); err != nil {
+			return nil, err
+		}
+
+		if applyErr := bc.updateBalancesAt(sqlTx, height, baseFee, []Transaction{tx}); applyErr != nil {
+			if err := bc.Database.RollbackToSavepoint(sqlTx, "apply_tx"); err != nil {
+				return nil, err
+			}
+
+			log.Printf("Dropping transaction %x, it cannot be applied: %v", tx.Hash(), applyErr)
+			mempool.RemoveTransaction(tx.Hash().EncodeToString())
+			continue
+		}
+
+		if err := bc.Database.ReleaseSavepoint(sqlTx, "apply_tx"); err != nil {
+			return nil, err
+		}
+
+		applicable = append(applicable, tx)
+	}
+
+	return applicable, nil
+}
+
 func (bc *Blockchain) proofOfWork(ctx context.Context, block *Block) (bool, error) {
 	for {
 		select {
@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// NewMultisigTransaction -> Unsigned transfer spending from the M-of-N address of pubKeys
+func NewMultisigTransaction(pubKeys []string, threshold int, to string, amount uint64) (*Transaction, error) {
+	sortedKeys, err := CryptoGraphy.SortPublicKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := CryptoGraphy.DeriveMultisigAddress(sortedKeys, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		From:         address,
+		To:           to,
+		Amount:       amount,
+		Timestamp:    utils.GetTimestamp(),
+		Status:       "pending",
+		MultisigKeys: sortedKeys,
+		Threshold:    threshold,
+	}, nil
+}
+
+// PartialTxStore -> Multisig transactions collecting signatures before they enter the mempool
+type PartialTxStore struct {
+	Transactions map[string]*Transaction
+	Mutex        sync.Mutex
+}
+
+func NewPartialTxStore() *PartialTxStore {
+	return &PartialTxStore{
+		Transactions: make(map[string]*Transaction),
+	}
+}
+
+// Add -> Stores the tx and returns its hash
+func (store *PartialTxStore) Add(tx *Transaction) string {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	hash := tx.Hash().EncodeToString()
+	store.Transactions[hash] = tx
+
+	return hash
+}
+
+// Get -> Returns a copy of the stored tx
+func (store *PartialTxStore) Get(hash string) (*Transaction, bool) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	tx, exists := store.Transactions[hash]
+	if !exists {
+		return nil, false
+	}
+
+	return copyPartialTx(tx), true
+}
+
+// AddSignature -> Adds a key holder's signature for chainId and returns a copy of the updated tx
+func (store *PartialTxStore) AddSignature(hash, privateKeyHex, publicKeyHex, chainId string) (*Transaction, error) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	tx, exists := store.Transactions[hash]
+	if !exists {
+		return nil, errors.New("multisig transaction not found")
+	}
+
+	if err := tx.AddMultisigSignatureWithHexKeys(privateKeyHex, publicKeyHex, chainId); err != nil {
+		return nil, err
+	}
+
+	return copyPartialTx(tx), nil
+}
+
+func (store *PartialTxStore) Remove(hash string) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	delete(store.Transactions, hash)
+}
+
+func copyPartialTx(tx *Transaction) *Transaction {
+	txCopy := *tx
+	txCopy.MultisigKeys = slices.Clone(tx.MultisigKeys)
+	txCopy.Signatures = slices.Clone(tx.Signatures)
+
+	return &txCopy
+}
@@ -0,0 +1,307 @@
+package blockchain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+const (
+	// NameFeePerBlock -> Registration fee per block of the registration, burned
+	NameFeePerBlock = 1
+
+	MinNameDuration = 1_000
+	MaxNameDuration = 100_000
+)
+
+var (
+	ErrNameNotFound = errors.New("name not found")
+	ErrNameExpired  = errors.New("name has expired")
+)
+
+// Names are shorter than any address, so a value is never both
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{2,31}$`)
+
+// NamePayload -> Fields of the name_register, name_renew and name_transfer tx types.
+// Register and renew burn their Amount as the registration fee, a transfer hands
+// the name to To.
+type NamePayload struct {
+	Name     string `json:"name"`
+	Duration int64  `json:"duration,omitempty"` // register, renew: blocks the name is (further) held for
+}
+
+// NameRecord -> A registered name, as kept in the ledger. It resolves to its
+// Owner below the Expiry height.
+type NameRecord struct {
+	Name             string `json:"name"`
+	Owner            string `json:"owner"`
+	Expiry           int64  `json:"expiry"`
+	RegisteredHeight int64  `json:"registered_height"`
+	LastTx           string `json:"last_tx"`
+}
+
+// IsActiveAt -> Whether the name still resolves in a block at height
+func (record *NameRecord) IsActiveAt(height int64) bool {
+	return record.Expiry > height
+}
+
+// IsValidName -> 3-32 lower case letters, digits or hyphens, starting with a letter
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// NameFee -> Registration fee for holding a name for duration blocks
+func NameFee(duration int64) uint64 {
+	return uint64(duration) * NameFeePerBlock
+}
+
+// NewNameRegisterTransaction -> Unsigned tx registering name to `from` for duration blocks
+func NewNameRegisterTransaction(from, name string, duration int64) *Transaction {
+	return &Transaction{
+		From:      from,
+		Amount:    NameFee(duration),
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeNameRegister,
+		Name: &NamePayload{
+			Name:     name,
+			Duration: duration,
+		},
+	}
+}
+
+// NewNameRenewTransaction -> Unsigned tx extending the expiry of a name by duration blocks
+func NewNameRenewTransaction(from, name string, duration int64) *Transaction {
+	tx := NewNameRegisterTransaction(from, name, duration)
+	tx.Type = TxTypeNameRenew
+	return tx
+}
+
+// NewNameTransferTransaction -> Unsigned tx handing a name to `to`, its expiry stays
+func NewNameTransferTransaction(from, to, name string) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeNameTransfer,
+		Name: &NamePayload{
+			Name: name,
+		},
+	}
+}
+
+func (tx *Transaction) IsNameOp() bool {
+	switch tx.Type {
+	case TxTypeNameRegister, TxTypeNameRenew, TxTypeNameTransfer:
+		return true
+	default:
+		return false
+	}
+}
+
+// nameKey -> Name the tx operates on. Only one operation per name may be pending
+// or mined in the same block.
+func (tx *Transaction) nameKey() string {
+	if !tx.IsNameOp() || tx.Name == nil {
+		return ""
+	}
+
+	return "name-" + tx.Name.Name
+}
+
+func (tx *Transaction) validateNamePayload() error {
+	if tx.Name == nil {
+		return fmt.Errorf("%s transaction needs a name payload", tx.Type)
+	}
+
+	if tx.IsBatch() {
+		return fmt.Errorf("%s transaction cannot have outputs", tx.Type)
+	}
+
+	if !IsValidName(tx.Name.Name) {
+		return errors.New("name must be 3-32 lower case letters, digits or hyphens, starting with a letter")
+	}
+
+	if tx.Type == TxTypeNameTransfer {
+		if tx.To == "" || tx.To == tx.From {
+			return errors.New("name transfer needs a new owner")
+		}
+
+		if tx.Amount != 0 || tx.Name.Duration != 0 {
+			return errors.New("name transfer must not set 'amount' or duration")
+		}
+
+		return nil
+	}
+
+	// register and renew
+	if tx.To != "" {
+		return fmt.Errorf("%s transaction must not set 'to'", tx.Type)
+	}
+
+	if tx.Name.Duration < MinNameDuration || tx.Name.Duration > MaxNameDuration {
+		return fmt.Errorf("name duration must be between %d and %d blocks", MinNameDuration, MaxNameDuration)
+	}
+
+	if tx.Amount != NameFee(tx.Name.Duration) {
+		return fmt.Errorf("amount must be the registration fee of %d", NameFee(tx.Name.Duration))
+	}
+
+	return nil
+}
+
+// GetName -> Ledger entry of the name, including an expired one
+func (bc *Blockchain) GetName(name string) (*NameRecord, error) {
+	dbName, err := bc.Database.GetName(name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNameNotFound
+		}
+
+		return nil, err
+	}
+
+	return parseDBName(dbName), nil
+}
+
+// GetNamesByOwner -> Names owned by owner that are active at the current tip
+func (bc *Blockchain) GetNamesByOwner(owner string) ([]NameRecord, error) {
+	dbNames, err := bc.Database.GetNamesByOwner(owner, bc.GetHeight())
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]NameRecord, 0, len(dbNames))
+	for idx := range dbNames {
+		records = append(records, *parseDBName(&dbNames[idx]))
+	}
+
+	return records, nil
+}
+
+// ResolveName -> Address the name points to at the current tip
+func (bc *Blockchain) ResolveName(name string) (string, error) {
+	record, err := bc.GetName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !record.IsActiveAt(bc.GetHeight()) {
+		return "", fmt.Errorf("%w: %s", ErrNameExpired, name)
+	}
+
+	return record.Owner, nil
+}
+
+// verifyNameOp -> Whether the name operation applies to the confirmed ledger in a block at height
+func (bc *Blockchain) verifyNameOp(tx *Transaction, height int64) error {
+	record, err := bc.GetName(tx.Name.Name)
+	if err != nil && !errors.Is(err, ErrNameNotFound) {
+		return err
+	}
+
+	if tx.Type == TxTypeNameRegister {
+		if record != nil && record.IsActiveAt(height) {
+			return fmt.Errorf("name %q is already registered until height %d", record.Name, record.Expiry)
+		}
+
+		return nil
+	}
+
+	if record == nil {
+		return ErrNameNotFound
+	}
+
+	if !record.IsActiveAt(height) {
+		return fmt.Errorf("%w: %s", ErrNameExpired, record.Name)
+	}
+
+	if record.Owner != tx.From {
+		return fmt.Errorf("name %q is owned by %s", record.Name, record.Owner)
+	}
+
+	if tx.Type == TxTypeNameRenew && record.Expiry+tx.Name.Duration-height > MaxNameDuration {
+		return fmt.Errorf("a name cannot be held for more than %d blocks ahead", MaxNameDuration)
+	}
+
+	return nil
+}
+
+// validateNameOp -> Mempool admission of a name operation, at the next block height
+func (bc *Blockchain) validateNameOp(tx *Transaction) error {
+	if err := bc.verifyNameOp(tx, bc.GetHeight()+1); err != nil {
+		return err
+	}
+
+	txHash := tx.Hash().EncodeToString()
+	key := tx.nameKey()
+
+	for hash, pendingTx := range bc.Mempool.GetTransactionsCopy() {
+		if hash != txHash && pendingTx.nameKey() == key {
+			return fmt.Errorf("name %q already has a pending operation", tx.Name.Name)
+		}
+	}
+
+	return nil
+}
+
+// filterNameOps -> Drops name operations that cannot apply at height,
+// keeping at most one operation per name
+func (bc *Blockchain) filterNameOps(txs []Transaction, height int64) []Transaction {
+	seen := make(map[string]bool)
+
+	validTxs := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsNameOp() {
+			key := tx.nameKey()
+			if seen[key] {
+				continue
+			}
+
+			if err := bc.verifyNameOp(&tx, height); err != nil {
+				continue
+			}
+
+			seen[key] = true
+		}
+
+		validTxs = append(validTxs, tx)
+	}
+
+	return validTxs
+}
+
+// applyName -> Updates the name ledger for a name operation mined at height
+func (bc *Blockchain) applyName(sqlTx *sql.Tx, height int64, tx *Transaction) error {
+	switch tx.Type {
+	case TxTypeNameRegister:
+		return bc.Database.RegisterName(sqlTx, database.DBNameSchema{
+			Name:             tx.Name.Name,
+			Owner:            tx.From,
+			Expiry:           height + tx.Name.Duration,
+			RegisteredHeight: height,
+			LastTx:           tx.LedgerId(),
+		}, height)
+	case TxTypeNameRenew:
+		return bc.Database.RenewName(sqlTx, tx.Name.Name, tx.From, tx.Name.Duration, height, tx.LedgerId())
+	case TxTypeNameTransfer:
+		return bc.Database.TransferName(sqlTx, tx.Name.Name, tx.From, tx.To, height, tx.LedgerId())
+	default:
+		return fmt.Errorf("%s is not a name transaction", tx.Type)
+	}
+}
+
+func parseDBName(dbName *database.DBNameSchema) *NameRecord {
+	return &NameRecord{
+		Name:             dbName.Name,
+		Owner:            dbName.Owner,
+		Expiry:           dbName.Expiry,
+		RegisteredHeight: dbName.RegisteredHeight,
+		LastTx:           dbName.LastTx,
+	}
+}
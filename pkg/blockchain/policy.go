@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// Policy rules decide what this node relays and keeps in its mempool.
+// They are local anti-spam limits, not consensus rules: a block may still
+// contain transactions that this policy would reject.
+type Policy struct {
+	MaxTxsPerSender   int    `json:"max_txs_per_sender"`   // pending txs per sender address
+	MaxBytesPerSender int    `json:"max_bytes_per_sender"` // pending bytes per sender address
+	DustThreshold     uint64 `json:"dust_threshold"`       // smallest accepted Amount
+	MinRelayFeeRate   uint64 `json:"min_relay_fee_rate"`   // fee per byte
+	MaxTxSize         int    `json:"max_tx_size"`          // in bytes
+}
+
+// Rejection reasons, machine-readable and stable for API and P2P callers
+const (
+	RejectSenderTxLimit   = "sender_tx_limit"
+	RejectSenderByteLimit = "sender_byte_limit"
+	RejectDust            = "dust"
+	RejectFeeTooLow       = "fee_too_low"
+	RejectTxTooLarge      = "tx_too_large"
+	RejectMempoolFull     = "mempool_full"
+)
+
+const (
+	DefaultMaxTxsPerSender   = 25
+	DefaultMaxBytesPerSender = 100_000
+	DefaultDustThreshold     = 100
+	DefaultMinRelayFeeRate   = BaseTxFee
+	DefaultMaxTxSize         = 100_000
+)
+
+type PolicyError struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (err *PolicyError) Error() string {
+	return err.Message
+}
+
+func newPolicyError(reason, format string, args ...any) *PolicyError {
+	return &PolicyError{
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxTxsPerSender:   DefaultMaxTxsPerSender,
+		MaxBytesPerSender: DefaultMaxBytesPerSender,
+		DustThreshold:     DefaultDustThreshold,
+		MinRelayFeeRate:   DefaultMinRelayFeeRate,
+		MaxTxSize:         DefaultMaxTxSize,
+	}
+}
+
+// Check -> Returns a *PolicyError if the tx may not enter the mempool
+func (policy *Policy) Check(tx *Transaction, mp *Mempool) error {
+	if tx.IsCoinbase {
+		return nil
+	}
+
+	txSize := tx.Size()
+	if txSize > policy.MaxTxSize {
+		return newPolicyError(RejectTxTooLarge,
+			"transaction size %d exceeds the maximum of %d bytes", txSize, policy.MaxTxSize)
+	}
+
+	if tx.Amount < policy.DustThreshold && tx.hasNativeAmount() {
+		return newPolicyError(RejectDust,
+			"transaction amount %d is below the dust threshold of %d", tx.Amount, policy.DustThreshold)
+	}
+
+	for idx, output := range tx.Outputs {
+		if output.Amount < policy.DustThreshold {
+			return newPolicyError(RejectDust,
+				"output %d amount %d is below the dust threshold of %d", idx, output.Amount, policy.DustThreshold)
+		}
+	}
+
+	if feeRate := tx.Fee / uint64(txSize); feeRate < policy.MinRelayFeeRate {
+		return newPolicyError(RejectFeeTooLow,
+			"transaction fee rate %d is below the minimum relay fee rate of %d", feeRate, policy.MinRelayFeeRate)
+	}
+
+	if mp.WillExceedCapacity(tx) {
+		return newPolicyError(RejectMempoolFull, "mempool capacity exceeded, try again later")
+	}
+
+	pendingCount, pendingBytes := mp.GetSenderUsage(tx.From)
+
+	if pendingCount+1 > policy.MaxTxsPerSender {
+		return newPolicyError(RejectSenderTxLimit,
+			"sender %s already has %d pending transactions", tx.From, pendingCount)
+	}
+
+	if pendingBytes+txSize > policy.MaxBytesPerSender {
+		return newPolicyError(RejectSenderByteLimit,
+			"sender %s would exceed %d pending bytes", tx.From, policy.MaxBytesPerSender)
+	}
+
+	return nil
+}
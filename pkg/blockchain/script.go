@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/script"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// NewScriptTransaction -> Transfer spending from the address of spendScript, its
+// Witness has to be set once the tx (fee included) is final
+func NewScriptTransaction(spendScript script.Script, to string, amount uint64) *Transaction {
+	return &Transaction{
+		From:      spendScript.Address(),
+		To:        to,
+		Amount:    amount,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Script:    spendScript.String(),
+	}
+}
+
+func (tx *Transaction) IsScript() bool {
+	return tx.Script != ""
+}
+
+// SetWitness -> Stack items the script runs on, e.g. signatures over tx.SigHash(chainId)
+func (tx *Transaction) SetWitness(items ...[]byte) {
+	tx.Witness = make([]string, len(items))
+	for idx, item := range items {
+		tx.Witness[idx] = hex.EncodeToString(item)
+	}
+}
+
+// VerifyScript -> Runs the script against the witness as if the tx is mined in a
+// block at height on chainId, whose median time past is medianTime
+func (tx *Transaction) VerifyScript(height, medianTime int64, chainId string) error {
+	if !tx.IsScript() {
+		return errors.New("transaction has no script")
+	}
+
+	if tx.IsMultisig() {
+		return errors.New("script spend cannot carry multisig keys")
+	}
+
+	spendScript, err := script.Decode(tx.Script)
+	if err != nil {
+		return err
+	}
+
+	if len(tx.Witness) > script.MaxStackSize {
+		return fmt.Errorf("witness exceeds %d items", script.MaxStackSize)
+	}
+
+	witness := make([][]byte, len(tx.Witness))
+	for idx, item := range tx.Witness {
+		if witness[idx], err = hex.DecodeString(item); err != nil {
+			return fmt.Errorf("invalid witness item %d: %w", idx, err)
+		}
+	}
+
+	return script.Execute(spendScript, witness, &script.Context{
+		SigHash: tx.SigHash(chainId),
+		Height:  height,
+		Time:    medianTime,
+	})
+}
+
+// filterScriptSpends -> Drops script spends whose script fails at height
+func (bc *Blockchain) filterScriptSpends(txs []Transaction, height int64) []Transaction {
+	medianTime := bc.MedianTimePast(height)
+
+	validTxs := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsScript() && tx.VerifyScript(height, medianTime, bc.chainId) != nil {
+			continue
+		}
+
+		validTxs = append(validTxs, tx)
+	}
+
+	return validTxs
+}
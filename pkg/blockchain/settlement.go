@@ -0,0 +1,139 @@
+package blockchain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// IsSettlement -> The tx pays out of a ledger entry instead of the sender's balance.
+// Its Amount is zero and, unless sponsored, the fee comes out of the released funds.
+func (tx *Transaction) IsSettlement() bool {
+	return tx.isHTLCSettlement() || tx.isChannelSettlement()
+}
+
+// settlementKey -> Ledger entry the settlement spends from. Only one settlement
+// per entry may be pending or mined in the same block.
+func (tx *Transaction) settlementKey() string {
+	switch {
+	case tx.isHTLCSettlement() && tx.HTLC != nil:
+		return "htlc-" + tx.HTLC.HTLCId
+	case tx.isChannelSettlement() && tx.Channel != nil:
+		return "channel-" + tx.Channel.ChannelId
+	default:
+		return ""
+	}
+}
+
+// verifySettlement -> Whether the settlement applies to the confirmed ledger in a block at height
+func (bc *Blockchain) verifySettlement(tx *Transaction, height int64) error {
+	switch {
+	case tx.isHTLCSettlement():
+		_, err := bc.verifyHTLCSettlement(tx, height)
+		return err
+	case tx.isChannelSettlement():
+		_, err := bc.verifyChannelSettlement(tx, height)
+		return err
+	default:
+		return fmt.Errorf("transaction type %q is not a settlement", tx.Type)
+	}
+}
+
+// validateSettlement -> Mempool admission of a settlement, at the next block height
+func (bc *Blockchain) validateSettlement(tx *Transaction) error {
+	if err := bc.verifySettlement(tx, bc.GetHeight()+1); err != nil {
+		return err
+	}
+
+	txHash := tx.Hash().EncodeToString()
+	key := tx.settlementKey()
+
+	for hash, pendingTx := range bc.Mempool.GetTransactionsCopy() {
+		if hash != txHash && pendingTx.settlementKey() == key {
+			return fmt.Errorf("%s already has a pending settlement", key)
+		}
+	}
+
+	if !tx.HasFeePayer() {
+		return nil
+	}
+
+	feePayerBalance, err := bc.GetBalance(tx.FeePayer)
+	if err != nil {
+		return err
+	}
+
+	if feePayerBalance < tx.Fee {
+		return errors.New("fee payer balance is insufficient")
+	}
+
+	return nil
+}
+
+// filterSettlements -> Drops settlements that cannot apply at height,
+// keeping at most one settlement per ledger entry
+func (bc *Blockchain) filterSettlements(txs []Transaction, height int64) []Transaction {
+	settled := make(map[string]bool)
+
+	validTxs := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsSettlement() {
+			key := tx.settlementKey()
+			if settled[key] {
+				continue
+			}
+
+			if err := bc.verifySettlement(&tx, height); err != nil {
+				continue
+			}
+
+			settled[key] = true
+		}
+
+		validTxs = append(validTxs, tx)
+	}
+
+	return validTxs
+}
+
+// applySettlement -> Updates the ledger entry and pays out the released funds
+func (bc *Blockchain) applySettlement(sqlTx *sql.Tx, height int64, tx *Transaction) error {
+	switch {
+	case tx.isHTLCSettlement():
+		if err := bc.settleHTLC(sqlTx, tx); err != nil {
+			return fmt.Errorf("failed to settle htlc: %w", err)
+		}
+
+		return nil
+	case tx.isChannelSettlement():
+		if err := bc.settleChannel(sqlTx, height, tx); err != nil {
+			return fmt.Errorf("failed to settle channel: %w", err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("transaction type %q is not a settlement", tx.Type)
+	}
+}
+
+// settlementFee -> Part of the fee that comes out of the released funds, none if sponsored
+func (tx *Transaction) settlementFee() uint64 {
+	if tx.HasFeePayer() {
+		return 0
+	}
+
+	return tx.Fee
+}
+
+// chargeSettlementFee -> Debits a sponsored fee from the fee payer
+func (bc *Blockchain) chargeSettlementFee(sqlTx *sql.Tx, tx *Transaction) error {
+	if !tx.HasFeePayer() {
+		return nil
+	}
+
+	if err := bc.Database.DecreaseUserBalance(sqlTx, tx.FeePayer, tx.Fee); err != nil {
+		return fmt.Errorf("failed to debit fee payer %s: %w", tx.FeePayer, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestBaseFee_Calculate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		parentBaseFee uint64
+		parentSize    int64
+		expected      uint64
+	}{
+		{"at target", 800, blockchain.TargetBlockSize, 800},
+		{"empty parent", 800, 0, 700},
+		{"full parent", 800, blockchain.MaxBlockSize, 900},
+		{"half way above target", 800, blockchain.TargetBlockSize * 3 / 2, 850},
+		{"never below the minimum", blockchain.MinBaseFee, 0, blockchain.MinBaseFee},
+		{"busy parent raises the minimum", blockchain.MinBaseFee, blockchain.TargetBlockSize + 1, blockchain.MinBaseFee + 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if baseFee := blockchain.CalculateBaseFee(tc.parentBaseFee, tc.parentSize); baseFee != tc.expected {
+				t.Errorf("Expected base fee %d, got %d", tc.expected, baseFee)
+			}
+		})
+	}
+}
+
+func TestBaseFee_BlockRules(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	if genesis := bc.GetLatestBlock(); genesis.BaseFee != blockchain.InitialBaseFee {
+		t.Errorf("Expected genesis base fee %d, got %d", blockchain.InitialBaseFee, genesis.BaseFee)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 10_000)
+
+	tx := signTx(t, blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp()), alice, testFee)
+
+	baseFee := bc.NextBaseFee()
+	block := &blockchain.Block{
+		Id:           1,
+		PrevHash:     bc.GetLatestBlock().Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward), *tx},
+		BaseFee:      baseFee + 1,
+	}
+
+	if err := bc.VerifyBlockTransactions(block); err == nil {
+		t.Error("Block with a base fee other than the one derived from its parent should be rejected")
+	}
+
+	underpaid := signTx(t, blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp()), alice, 10)
+	block.BaseFee = baseFee
+	block.Transactions[1] = *underpaid
+	if err := bc.VerifyBlockTransactions(block); err == nil {
+		t.Error("Tx paying less than the base fee should be rejected")
+	}
+
+	if _, err := appendBlock(t, bc, db, *tx); err != nil {
+		t.Fatalf("Tx paying the base fee should be valid: %v", err)
+	}
+
+	// The base fee part is burned, the miner only gets the tip
+	assertBalances(t, db, map[string]uint64{
+		alice.Address: 10_000 - 1_000 - testFee,
+		"bob":         1_000,
+		"miner":       blockchain.MiningReward + testFee - tx.MinFee(baseFee),
+	})
+}
+
+func TestBaseFee_CalculateFeeCoversBaseFee(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp())
+	tx.PublicKey = alice.GetPublicKeyHex()
+	signTx(t, tx, alice, bc.CalculateFee(tx))
+
+	if minFee := tx.MinFee(bc.NextBaseFee()); tx.Fee <= minFee {
+		t.Errorf("Quoted fee %d should leave a tip above the base fee of %d", tx.Fee, minFee)
+	}
+}
+
+// mineLegacyBlock -> Mines block with the header encoding used before the base fee
+func mineLegacyBlock(t *testing.T, block *blockchain.Block) {
+	t.Helper()
+
+	block.ComputeMerkleRoot()
+
+	for nonce := int64(0); nonce < 100_000_000; nonce++ {
+		block.Nonce = nonce
+		block.Hash = legacyHash(block)
+
+		if block.IsValidHash() {
+			return
+		}
+	}
+
+	t.Fatal("Failed to mine block within nonce limit")
+}
+
+func legacyHash(block *blockchain.Block) []byte {
+	record := fmt.Sprintf("%d-%x-%x-%d-%d", block.Id, block.PrevHash, block.MerkleRoot, block.Timestamp, block.Nonce)
+
+	hash := sha256.Sum256([]byte(record))
+	return hash[:]
+}
+
+func TestBaseFee_LoadsChainFromBeforeBaseFee(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	// Rewrite the genesis block the way the base fee migration left it
+	genesis := bc.GetLatestBlock()
+	genesis.BaseFee = 0
+	genesis.Hash = legacyHash(genesis)
+
+	if _, err := db.DB.Exec("UPDATE blocks SET hash = ?, base_fee = 0 WHERE block_height = 0",
+		fmt.Sprintf("%x", genesis.Hash)); err != nil {
+
+		t.Fatalf("Failed to rewrite genesis block: %v", err)
+	}
+
+	block := &blockchain.Block{
+		Id:           1,
+		PrevHash:     genesis.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)},
+	}
+	mineLegacyBlock(t, block)
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := bc.AddBlock(sqlTx, block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	loaded, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to load blockchain: %v", err)
+	}
+
+	if len(loaded.Blocks) != 2 || !bytes.Equal(loaded.GetLatestBlock().Hash, block.Hash) {
+		t.Fatalf("Blocks from before the base fee should still verify, got %d blocks", len(loaded.Blocks))
+	}
+
+	// The first block after the upgrade starts from the minimum base fee
+	if baseFee := loaded.NextBaseFee(); baseFee != blockchain.MinBaseFee {
+		t.Errorf("Expected base fee %d, got %d", blockchain.MinBaseFee, baseFee)
+	}
+}
+
+func TestBaseFee_SameAfterDatabaseRoundTrip(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 10_000)
+
+	tx := signTx(t, blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp()), alice, testFee)
+
+	mined, err := appendBlock(t, bc, db, *tx)
+	if err != nil {
+		t.Fatalf("Failed to append block: %v", err)
+	}
+
+	stored, err := bc.GetBlockById(mined.Id)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+
+	if stored.Transactions[1].Status == mined.Transactions[1].Status {
+		t.Fatalf("Expected the stored tx to have another local status than %q", stored.Transactions[1].Status)
+	}
+
+	if stored.CalculateSize() != mined.CalculateSize() {
+		t.Errorf("Block size changed from %d to %d after storing it", mined.CalculateSize(), stored.CalculateSize())
+	}
+
+	if stored.Transactions[1].MinFee(mined.BaseFee) != mined.Transactions[1].MinFee(mined.BaseFee) {
+		t.Error("Min fee of a tx should not depend on where it was read from")
+	}
+
+	if next := blockchain.CalculateBaseFee(stored.BaseFee, stored.CalculateSize()); next != bc.NextBaseFee() {
+		t.Errorf("Expected base fee %d, got %d", bc.NextBaseFee(), next)
+	}
+}
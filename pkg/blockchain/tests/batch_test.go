@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func createBatchTransaction(t *testing.T, keyPair *CryptoGraphy.KeyPair, outputs []blockchain.TxOutput) *blockchain.Transaction {
+	t.Helper()
+
+	total, err := blockchain.SumOutputs(outputs)
+	if err != nil {
+		t.Fatalf("Failed to sum outputs: %v", err)
+	}
+
+	tx := &blockchain.Transaction{
+		From:      keyPair.Address,
+		Amount:    total,
+		Fee:       testFee,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Outputs:   outputs,
+	}
+
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	return tx
+}
+
+func TestBatch_VerifyTransaction(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	outputs := []blockchain.TxOutput{{To: "bob", Amount: 100}, {To: "carol", Amount: 200}}
+
+	tx := createBatchTransaction(t, keyPair, outputs)
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Errorf("Batch transaction should be valid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(tx *blockchain.Transaction)
+	}{
+		{"Amount does not match outputs", func(tx *blockchain.Transaction) { tx.Amount = 250 }},
+		{"To is set", func(tx *blockchain.Transaction) { tx.To = "dave" }},
+		{"Zero output", func(tx *blockchain.Transaction) { tx.Outputs[1].Amount = 0; tx.Amount = 100 }},
+		{"Empty recipient", func(tx *blockchain.Transaction) { tx.Outputs[0].To = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := createBatchTransaction(t, keyPair, append([]blockchain.TxOutput(nil), outputs...))
+			tt.modify(tx)
+
+			// Re-sign so only the batch rules can fail
+			if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+				t.Fatalf("Failed to sign transaction: %v", err)
+			}
+
+			if err := bc.VerifyTransaction(tx); err == nil {
+				t.Error("Expected invalid batch transaction to be rejected")
+			}
+		})
+	}
+}
+
+func TestBatch_OutputsCoveredBySignature(t *testing.T) {
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := createBatchTransaction(t, keyPair,
+		[]blockchain.TxOutput{{To: "bob", Amount: 100}, {To: "carol", Amount: 200}})
+
+	// Swapping recipients keeps Amount but must break the signature
+	tx.Outputs[0].To, tx.Outputs[1].To = tx.Outputs[1].To, tx.Outputs[0].To
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("Changing outputs should invalidate the signature")
+	}
+}
+
+func TestBatch_PolicyDustPerOutput(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	tx := blockchain.NewTransaction("Alice", "", 1000, 1234567890)
+	tx.Outputs = []blockchain.TxOutput{
+		{To: "bob", Amount: 1000 - blockchain.DefaultDustThreshold + 1},
+		{To: "carol", Amount: blockchain.DefaultDustThreshold - 1},
+	}
+	tx.Fee = blockchain.DefaultMinRelayFeeRate * uint64(tx.Size())
+
+	assertPolicyReason(t, policy.Check(tx, mp), blockchain.RejectDust)
+}
+
+func TestBatch_BlockRoundTrip(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := createBatchTransaction(t, keyPair, []blockchain.TxOutput{
+		{To: "bob", Amount: 100},
+		{To: "carol", Amount: 200},
+		{To: "bob", Amount: 300},
+	})
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, keyPair.Address, 2000); err != nil {
+		t.Fatalf("Failed to fund sender: %v", err)
+	}
+
+	genesisBlock := bc.GetLatestBlock()
+	newBlock := &blockchain.Block{
+		Id:           1,
+		PrevHash:     genesisBlock.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward), *tx},
+		BaseFee:      bc.NextBaseFee(),
+	}
+
+	if err := bc.VerifyBlockTransactions(newBlock); err != nil {
+		t.Fatalf("Block transactions should be valid: %v", err)
+	}
+
+	if err := newBlock.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	if err := bc.AddBlock(sqlTx, newBlock); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	expectedBalances := map[string]uint64{
+		keyPair.Address: 2000 - 600 - testFee,
+		"bob":           400,
+		"carol":         200,
+	}
+
+	for address, expected := range expectedBalances {
+		balance, err := db.GetConfirmedBalance(address)
+		if err != nil {
+			t.Fatalf("Failed to get balance of %s: %v", address, err)
+		}
+
+		if balance != expected {
+			t.Errorf("Expected balance %d for %s, got %d", expected, address, balance)
+		}
+	}
+
+	loadedBlock, err := bc.GetBlockById(1)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if len(loadedTx.Outputs) != 3 || loadedTx.Outputs[2].To != "bob" || loadedTx.Outputs[2].Amount != 300 {
+		t.Fatalf("Outputs were not restored in order: %+v", loadedTx.Outputs)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, newBlock.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
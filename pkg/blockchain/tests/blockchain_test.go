@@ -53,7 +53,8 @@ func runMigrations(db *sql.DB) error {
 			merkle_root TEXT UNIQUE NOT NULL,
 			nonce INTEGER DEFAULT (0),
 			timestamp INTEGER DEFAULT (strftime('%s', 'now')),
-			block_height INTEGER DEFAULT (0)
+			block_height INTEGER DEFAULT (0),
+			base_fee INTEGER NOT NULL DEFAULT (0)
 		)`,
 		`CREATE TABLE IF NOT EXISTS transactions (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -67,9 +68,100 @@ func runMigrations(db *sql.DB) error {
 			signature TEXT NULL,
 			status TEXT NOT NULL DEFAULT ('pending') CHECK (status IN ('pending', 'confirmed')),
 			is_coin_base INTEGER NOT NULL DEFAULT (0) CHECK (is_coin_base IN (0, 1)),
+			lock_time INTEGER NOT NULL DEFAULT (0),
+			data TEXT NULL,
+			multisig_keys TEXT NULL,
+			threshold INTEGER NOT NULL DEFAULT (0),
+			signatures TEXT NULL,
+			fee_payer TEXT NULL,
+			fee_payer_public_key TEXT NULL,
+			fee_payer_signature TEXT NULL,
+			tx_type TEXT NULL,
+			payload TEXT NULL,
+			script TEXT NULL,
+			witness TEXT NULL,
 			FOREIGN KEY (block_id) REFERENCES blocks (id) ON DELETE SET NULL
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_transactions_block_id ON transactions (block_id)`,
+		`CREATE TABLE IF NOT EXISTS transaction_outputs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id INTEGER NOT NULL,
+			output_index INTEGER NOT NULL,
+			recipient TEXT NOT NULL,
+			amount INTEGER NOT NULL DEFAULT (0),
+			UNIQUE (transaction_id, output_index),
+			FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS htlcs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			htlc_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			amount INTEGER NOT NULL DEFAULT (0),
+			hash_lock TEXT NOT NULL,
+			expiry INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT ('locked') CHECK (status IN ('locked', 'claimed', 'refunded')),
+			preimage TEXT NULL,
+			settle_tx TEXT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS channels (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel_id TEXT NOT NULL UNIQUE,
+			sender TEXT NOT NULL,
+			sender_public_key TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			deposit INTEGER NOT NULL DEFAULT (0),
+			dispute_period INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT ('open') CHECK (status IN ('open', 'closing', 'settled')),
+			nonce INTEGER NOT NULL DEFAULT (0),
+			paid INTEGER NOT NULL DEFAULT (0),
+			close_fee INTEGER NOT NULL DEFAULT (0),
+			close_height INTEGER NOT NULL DEFAULT (0),
+			settle_tx TEXT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS assets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			asset_id TEXT NOT NULL UNIQUE,
+			symbol TEXT NOT NULL,
+			decimals INTEGER NOT NULL DEFAULT (0),
+			max_supply INTEGER NOT NULL,
+			supply INTEGER NOT NULL DEFAULT (0) CHECK (supply <= max_supply),
+			issuer TEXT NOT NULL,
+			issuer_public_key TEXT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS token_balances (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			address TEXT NOT NULL,
+			asset_id TEXT NOT NULL,
+			balance INTEGER NOT NULL DEFAULT (0),
+			UNIQUE (address, asset_id),
+			FOREIGN KEY (asset_id) REFERENCES assets (asset_id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS names (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			owner TEXT NOT NULL,
+			expiry INTEGER NOT NULL,
+			registered_height INTEGER NOT NULL,
+			last_tx TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS vesting_schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			address TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			start_height INTEGER NOT NULL,
+			end_height INTEGER NOT NULL CHECK (end_height > start_height),
+			created_tx TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS bans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			address TEXT NOT NULL UNIQUE,
+			ip TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			score INTEGER NOT NULL,
+			banned_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		)`,
 	}
 
 	for _, migration := range migrations {
@@ -113,7 +205,7 @@ func TestNewBlockchain(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
@@ -143,7 +235,7 @@ func TestNewBlockchain_NilMempool(t *testing.T) {
 	db, _, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	bc, err := blockchain.NewBlockchain(db, nil)
+	bc, err := blockchain.NewBlockchain(db, nil, blockchain.DefaultChainId)
 
 	if err == nil {
 		t.Error("Expected error when creating blockchain with nil mempool")
@@ -162,13 +254,13 @@ func TestLoadBlockchain(t *testing.T) {
 	mp := blockchain.NewMempool(1048576)
 
 	// Create initial blockchain
-	bc1, err := blockchain.NewBlockchain(db, mp)
+	bc1, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
 
 	// Load blockchain from database
-	bc2, err := blockchain.LoadBlockchain(db, mp)
+	bc2, err := blockchain.LoadBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to load blockchain: %v", err)
 	}
@@ -186,7 +278,7 @@ func TestLoadBlockchain_EmptyDatabase(t *testing.T) {
 	mp := blockchain.NewMempool(1048576)
 
 	// Load from empty database
-	bc, err := blockchain.LoadBlockchain(db, mp)
+	bc, err := blockchain.LoadBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to load blockchain from empty database: %v", err)
 	}
@@ -202,7 +294,7 @@ func TestAddBlock(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -259,7 +351,7 @@ func TestAddBlockToMemory(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -308,7 +400,7 @@ func TestGetLatestBlock(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -352,7 +444,7 @@ func TestVerifyBlock(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -420,7 +512,7 @@ func TestVerifyBlock_InvalidHash(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -476,7 +568,7 @@ func TestGetBalance(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -525,7 +617,7 @@ func TestGetBalance_WithPendingTransactions(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -577,7 +669,7 @@ func TestUpdateUserBalances(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -605,7 +697,7 @@ func TestUpdateUserBalances(t *testing.T) {
 			From:      "alice",
 			To:        "bob",
 			Amount:    200,
-			Fee:       10,
+			Fee:       100,
 			Timestamp: utils.GetTimestamp(),
 			Status:    "confirmed",
 		},
@@ -626,6 +718,8 @@ func TestUpdateUserBalances(t *testing.T) {
 	}
 	defer sqlTx2.Rollback()
 
+	baseFee := bc.NextBaseFee()
+
 	err = bc.UpdateUserBalances(sqlTx2, transactions)
 	if err != nil {
 		t.Fatalf("Failed to update balances: %v", err)
@@ -636,14 +730,14 @@ func TestUpdateUserBalances(t *testing.T) {
 		t.Fatalf("Failed to commit transaction: %v", err)
 	}
 
-	// Check alice's balance (1000 - 200 - 10 = 790)
+	// Check alice's balance (1000 - 200 - 100 = 700)
 	aliceBalance, err := db.GetConfirmedBalance("alice")
 	if err != nil {
 		t.Fatalf("Failed to get alice's balance: %v", err)
 	}
 
-	if aliceBalance != 790 {
-		t.Errorf("Expected alice's balance 790, got %d", aliceBalance)
+	if aliceBalance != 700 {
+		t.Errorf("Expected alice's balance 700, got %d", aliceBalance)
 	}
 
 	// Check Bob's balance (200)
@@ -656,13 +750,13 @@ func TestUpdateUserBalances(t *testing.T) {
 		t.Errorf("Expected bob's balance 200, got %d", bobBalance)
 	}
 
-	// Check miner's balance (mining reward + fee = 10000 + 10 = 10010)
+	// Check miner's balance (mining reward + the fee above the burned base fee)
 	minerBalance, err := db.GetConfirmedBalance("miner")
 	if err != nil {
 		t.Fatalf("Failed to get miner's balance: %v", err)
 	}
 
-	expectedMinerBalance := uint64(blockchain.MiningReward) + 10
+	expectedMinerBalance := uint64(blockchain.MiningReward) + 100 - transactions[0].MinFee(baseFee)
 	if minerBalance != expectedMinerBalance {
 		t.Errorf("Expected miner's balance %d, got %d", expectedMinerBalance, minerBalance)
 	}
@@ -674,7 +768,7 @@ func TestValidateTransaction(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -744,7 +838,7 @@ func TestVerifyBlocks(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -756,7 +850,7 @@ func TestVerifyBlocks(t *testing.T) {
 	}
 
 	// Create a new blockchain instance to test verification
-	bc2, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576))
+	bc2, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to load blockchain: %v", err)
 	}
@@ -778,7 +872,7 @@ func TestVerifyBlocks_EmptyChain(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.LoadBlockchain(db, mp)
+	bc, err := blockchain.LoadBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to load blockchain: %v", err)
 	}
@@ -800,7 +894,7 @@ func TestGetBlockById(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -828,7 +922,7 @@ func TestGetAllBlocks(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -892,7 +986,7 @@ func TestAddTransactionToDB(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -913,7 +1007,7 @@ func TestAddTransactionToDB(t *testing.T) {
 		Status:    "confirmed",
 	}
 
-	err = tx.Sign(keyPair)
+	err = tx.Sign(keyPair, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -964,7 +1058,7 @@ func TestVerifyHeaders(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -992,7 +1086,7 @@ func TestVerifyHeaders_EmptyList(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1014,7 +1108,7 @@ func TestVerifyHeaders_InvalidGenesisID(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1043,7 +1137,7 @@ func TestConcurrentAddBlockToMemory(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1094,7 +1188,7 @@ func TestConcurrentGetLatestBlock(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1123,7 +1217,7 @@ func TestBlockchainWithMultipleTransactions(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1162,7 +1256,7 @@ func TestBlockchainWithMultipleTransactions(t *testing.T) {
 			Timestamp: utils.GetTimestamp() + int64(i),
 			Status:    "confirmed",
 		}
-		err = tx.Sign(keyPair)
+		err = tx.Sign(keyPair, blockchain.DefaultChainId)
 		if err != nil {
 			t.Fatalf("Failed to sign transaction: %v", err)
 		}
@@ -1219,7 +1313,7 @@ func TestGetBalance_ConcurrentAccess(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1268,7 +1362,7 @@ func TestValidateTransaction_EdgeCases(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1339,7 +1433,7 @@ func TestUpdateUserBalances_WithMultipleFees(t *testing.T) {
 	defer cleanup()
 
 	mp := blockchain.NewMempool(1048576)
-	bc, err := blockchain.NewBlockchain(db, mp)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to create blockchain: %v", err)
 	}
@@ -1372,7 +1466,7 @@ func TestUpdateUserBalances_WithMultipleFees(t *testing.T) {
 			From:      "alice",
 			To:        "bob",
 			Amount:    100,
-			Fee:       5,
+			Fee:       150,
 			Timestamp: utils.GetTimestamp(),
 			Status:    "confirmed",
 		},
@@ -1380,7 +1474,7 @@ func TestUpdateUserBalances_WithMultipleFees(t *testing.T) {
 			From:      "bob",
 			To:        "alice",
 			Amount:    50,
-			Fee:       3,
+			Fee:       130,
 			Timestamp: utils.GetTimestamp(),
 			Status:    "confirmed",
 		},
@@ -1401,6 +1495,8 @@ func TestUpdateUserBalances_WithMultipleFees(t *testing.T) {
 	}
 	defer sqlTx2.Rollback()
 
+	baseFee := bc.NextBaseFee()
+
 	err = bc.UpdateUserBalances(sqlTx2, transactions)
 	if err != nil {
 		t.Fatalf("Failed to update balances: %v", err)
@@ -1411,33 +1507,34 @@ func TestUpdateUserBalances_WithMultipleFees(t *testing.T) {
 		t.Fatalf("Failed to commit transaction: %v", err)
 	}
 
-	// Check alice's balance: 1000 - 100 - 5 + 50 = 945
+	// Check alice's balance: 1000 - 100 - 150 + 50 = 800
 	aliceBalance, err := db.GetConfirmedBalance("alice")
 	if err != nil {
 		t.Fatalf("Failed to get alice's balance: %v", err)
 	}
 
-	if aliceBalance != 945 {
-		t.Errorf("Expected alice's balance 945, got %d", aliceBalance)
+	if aliceBalance != 800 {
+		t.Errorf("Expected alice's balance 800, got %d", aliceBalance)
 	}
 
-	// Check Bob's balance: 500 + 100 - 50 - 3 = 547
+	// Check Bob's balance: 500 + 100 - 50 - 130 = 420
 	bobBalance, err := db.GetConfirmedBalance("bob")
 	if err != nil {
 		t.Fatalf("Failed to get bob's balance: %v", err)
 	}
 
-	if bobBalance != 547 {
-		t.Errorf("Expected bob's balance 547, got %d", bobBalance)
+	if bobBalance != 420 {
+		t.Errorf("Expected bob's balance 420, got %d", bobBalance)
 	}
 
-	// Check miner's balance: mining reward + total fees = 10000 + 5 + 3 = 10008
+	// Check miner's balance: mining reward + total fees - burned base fees
 	minerBalance, err := db.GetConfirmedBalance("miner")
 	if err != nil {
 		t.Fatalf("Failed to get miner's balance: %v", err)
 	}
 
-	expectedMinerBalance := uint64(blockchain.MiningReward) + 5 + 3
+	burned := transactions[0].MinFee(baseFee) + transactions[1].MinFee(baseFee)
+	expectedMinerBalance := uint64(blockchain.MiningReward) + 150 + 130 - burned
 	if minerBalance != expectedMinerBalance {
 		t.Errorf("Expected miner's balance %d, got %d", expectedMinerBalance, minerBalance)
 	}
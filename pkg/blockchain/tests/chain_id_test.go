@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestChainId_SignatureNotReplayable(t *testing.T) {
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := blockchain.NewTransaction(keyPair.Address, "bob", 100, utils.GetTimestamp())
+	tx.Fee = testFee
+	if err := tx.Sign(keyPair, "testnet"); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	if !tx.Verify("testnet") {
+		t.Fatal("Tx should verify on the chain it was signed for")
+	}
+
+	state := blockchain.NewChannelState("channel", 1, 100)
+	state.Sign(keyPair, "testnet")
+	if !state.Verify(keyPair.GetPublicKeyHex(), "testnet") {
+		t.Fatal("Channel state should verify on the chain it was signed for")
+	}
+
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("Tx signed for testnet should not verify on mainnet")
+	}
+
+	if state.Verify(keyPair.GetPublicKeyHex(), blockchain.DefaultChainId) {
+		t.Error("Channel state signed for testnet should not verify on mainnet")
+	}
+}
+
+func TestChainId_PerBlockchain(t *testing.T) {
+	mainDB, _, mainCleanup := setupTestDB(t)
+	defer mainCleanup()
+
+	testDB, _, testCleanup := setupTestDB(t)
+	defer testCleanup()
+
+	mainnet, err := blockchain.NewBlockchain(mainDB, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	// A second chain in the same process leaves the first one's chain id alone
+	testnet, err := blockchain.NewBlockchain(testDB, blockchain.NewMempool(1048576), "testnet")
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	if mainnet.ChainId() != blockchain.DefaultChainId || testnet.ChainId() != "testnet" {
+		t.Fatalf("Expected chain ids %q and %q, got %q and %q",
+			blockchain.DefaultChainId, "testnet", mainnet.ChainId(), testnet.ChainId())
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := blockchain.NewTransaction(keyPair.Address, "bob", 100, utils.GetTimestamp())
+	tx.Fee = testFee
+	if err := tx.Sign(keyPair, testnet.ChainId()); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	if err := testnet.VerifyTransaction(tx); err != nil {
+		t.Errorf("Tx should verify on the chain it was signed for: %v", err)
+	}
+
+	if err := mainnet.VerifyTransaction(tx); err == nil {
+		t.Error("Tx signed for testnet should not verify on mainnet")
+	}
+
+	loaded, err := blockchain.LoadBlockchain(testDB, blockchain.NewMempool(1048576), "testnet")
+	if err != nil {
+		t.Fatalf("Failed to load blockchain: %v", err)
+	}
+
+	if err := loaded.VerifyTransaction(tx); err != nil {
+		t.Errorf("Loaded chain should keep its chain id: %v", err)
+	}
+}
+
+func TestChainId_Validation(t *testing.T) {
+	for _, id := range []string{"", "Mainnet", "-testnet", "chain id", "a-chain-id-that-is-far-too-long-to-use"} {
+		if err := blockchain.ValidateChainId(id); err == nil {
+			t.Errorf("Expected chain id %q to be rejected", id)
+		}
+	}
+
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), "Mainnet"); err == nil {
+		t.Error("A blockchain cannot be created with an invalid chain id")
+	}
+
+	if _, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576), ""); err == nil {
+		t.Error("A blockchain cannot be loaded with an invalid chain id")
+	}
+}
+
+func TestChainId_SignatureSurvivesStorage(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 10_000)
+
+	tx := signTx(t, blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp()), alice, testFee)
+
+	mined, err := appendBlock(t, bc, db, *tx)
+	if err != nil {
+		t.Fatalf("Failed to append block: %v", err)
+	}
+
+	stored, err := bc.GetBlockById(mined.Id)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+
+	// Stored as confirmed, while it was signed as pending
+	storedTx := stored.Transactions[1]
+	if storedTx.Status == tx.Status {
+		t.Fatalf("Expected the stored tx to have another local status than %q", tx.Status)
+	}
+
+	if !storedTx.Verify(bc.ChainId()) {
+		t.Error("Stored tx should still verify")
+	}
+
+	if err := bc.VerifyTransaction(&storedTx); err != nil {
+		t.Errorf("Stored tx should still pass verification: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+)
+
+func setupChannel(t *testing.T, disputePeriod int64) (*blockchain.Blockchain, *database.Database, func(), *CryptoGraphy.KeyPair, *CryptoGraphy.KeyPair, string) {
+	t.Helper()
+
+	db, _, cleanup := setupTestDB(t)
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	bob, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 100_000)
+
+	openTx := signTx(t, blockchain.NewChannelOpenTransaction(alice.Address, bob.Address, 50_000, disputePeriod), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *openTx); err != nil {
+		t.Fatalf("Open transaction should be valid: %v", err)
+	}
+
+	return bc, db, cleanup, alice, bob, openTx.LedgerId()
+}
+
+func signedState(channelId string, nonce, paid uint64, keyPair *CryptoGraphy.KeyPair) *blockchain.ChannelState {
+	state := blockchain.NewChannelState(channelId, nonce, paid)
+	state.Sign(keyPair, blockchain.DefaultChainId)
+	return state
+}
+
+func assertBalances(t *testing.T, db *database.Database, expected map[string]uint64) {
+	t.Helper()
+
+	for address, amount := range expected {
+		balance, err := db.GetConfirmedBalance(address)
+		if err != nil {
+			t.Fatalf("Failed to get balance of %s: %v", address, err)
+		}
+
+		if balance != amount {
+			t.Errorf("Expected balance %d for %s, got %d", amount, address, balance)
+		}
+	}
+}
+
+func TestChannel_StateVerification(t *testing.T) {
+	bc, _, cleanup, alice, bob, channelId := setupChannel(t, 5)
+	defer cleanup()
+
+	channel, err := bc.GetChannel(channelId)
+	if err != nil {
+		t.Fatalf("Failed to get channel: %v", err)
+	}
+
+	if channel.Status != blockchain.ChannelStatusOpen || channel.Deposit != 50_000 || channel.Recipient != bob.Address {
+		t.Fatalf("Unexpected channel: %+v", channel)
+	}
+
+	if err := blockchain.VerifyChannelState(channel, signedState(channelId, 1, 1_000, alice), blockchain.DefaultChainId); err != nil {
+		t.Errorf("State signed by the sender should be valid: %v", err)
+	}
+
+	tampered := signedState(channelId, 1, 1_000, alice)
+	tampered.Paid = 2_000
+
+	tests := []struct {
+		name  string
+		state *blockchain.ChannelState
+	}{
+		{"Signed by the recipient", signedState(channelId, 1, 1_000, bob)},
+		{"Pays more than the deposit", signedState(channelId, 1, 50_001, alice)},
+		{"Other channel", signedState("other", 1, 1_000, alice)},
+		{"Tampered amount", tampered},
+		{"Unsigned", blockchain.NewChannelState(channelId, 1, 1_000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := blockchain.VerifyChannelState(channel, tt.state, blockchain.DefaultChainId); err == nil {
+				t.Error("Expected invalid state to be rejected")
+			}
+		})
+	}
+}
+
+func TestChannel_PayloadValidation(t *testing.T) {
+	bc, _, cleanup, alice, bob, channelId := setupChannel(t, 5)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		tx   *blockchain.Transaction
+	}{
+		{"Zero dispute period", blockchain.NewChannelOpenTransaction(alice.Address, bob.Address, 1000, 0)},
+		{"Dispute period too long", blockchain.NewChannelOpenTransaction(alice.Address, bob.Address, 1000, blockchain.MaxChannelDisputePeriod+1)},
+		{"Channel to self", blockchain.NewChannelOpenTransaction(alice.Address, alice.Address, 1000, 5)},
+		{"Close without channel id", blockchain.NewChannelCloseTransaction(alice.Address, "", nil)},
+		{"Close with state of another channel", blockchain.NewChannelCloseTransaction(alice.Address, channelId, signedState("other", 1, 10, alice))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := bc.VerifyTransaction(signTx(t, tt.tx, alice, testFee)); err == nil {
+				t.Error("Expected invalid channel transaction to be rejected")
+			}
+		})
+	}
+}
+
+func TestChannel_RecipientClose(t *testing.T) {
+	bc, db, cleanup, alice, bob, channelId := setupChannel(t, 5)
+	defer cleanup()
+
+	// The recipient can only close with a signed state
+	noState := signTx(t, blockchain.NewChannelCloseTransaction(bob.Address, channelId, nil), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *noState); err == nil {
+		t.Fatal("Expected close by the recipient without a state to be rejected")
+	}
+
+	closeTx := signTx(t, blockchain.NewChannelCloseTransaction(bob.Address, channelId, signedState(channelId, 2, 20_000, alice)), bob, testFee)
+	block, err := appendBlock(t, bc, db, *closeTx)
+	if err != nil {
+		t.Fatalf("Close by the recipient should be valid: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{
+		alice.Address: 100_000 - 50_000 - testFee + 30_000,
+		bob.Address:   20_000 - testFee,
+	})
+
+	channel, err := bc.GetChannel(channelId)
+	if err != nil {
+		t.Fatalf("Failed to get channel: %v", err)
+	}
+
+	if channel.Status != blockchain.ChannelStatusSettled || channel.Paid != 20_000 || channel.SettleTx != closeTx.LedgerId() {
+		t.Errorf("Unexpected channel after close: %+v", channel)
+	}
+
+	again := signTx(t, blockchain.NewChannelCloseTransaction(bob.Address, channelId, signedState(channelId, 3, 30_000, alice)), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *again); err == nil {
+		t.Error("Expected a settled channel to reject another close")
+	}
+
+	loadedBlock, err := bc.GetBlockById(block.Id)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if loadedTx.Channel == nil || loadedTx.Channel.State == nil || !bytes.Equal(loadedTx.Channel.State.Signature, closeTx.Channel.State.Signature) {
+		t.Fatalf("Channel payload was not restored: %+v", loadedTx.Channel)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, block.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
+
+func TestChannel_SenderCloseIsDisputed(t *testing.T) {
+	bc, db, cleanup, alice, bob, channelId := setupChannel(t, 3)
+	defer cleanup()
+
+	// The sender closes with an old state, paying the recipient less
+	closeTx := signTx(t, blockchain.NewChannelCloseTransaction(alice.Address, channelId, signedState(channelId, 1, 5_000, alice)), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *closeTx); err != nil {
+		t.Fatalf("Close by the sender should be valid: %v", err)
+	}
+
+	channel, err := bc.GetChannel(channelId)
+	if err != nil {
+		t.Fatalf("Failed to get channel: %v", err)
+	}
+
+	if channel.Status != blockchain.ChannelStatusClosing || channel.CloseHeight != 2 || channel.CloseFee != testFee {
+		t.Fatalf("Unexpected channel after close: %+v", channel)
+	}
+
+	settleTx := signTx(t, blockchain.NewChannelSettleTransaction(alice.Address, channelId), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *settleTx); err == nil {
+		t.Fatal("Expected settle within the dispute period to be rejected")
+	}
+
+	older := signTx(t, blockchain.NewChannelCloseTransaction(bob.Address, channelId, signedState(channelId, 0, 1_000, alice)), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *older); err == nil {
+		t.Fatal("Expected a state older than the closing one to be rejected")
+	}
+
+	// The recipient answers with the latest state
+	disputeTx := signTx(t, blockchain.NewChannelCloseTransaction(bob.Address, channelId, signedState(channelId, 4, 20_000, alice)), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *disputeTx); err != nil {
+		t.Fatalf("Dispute by the recipient should be valid: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{
+		alice.Address: 100_000 - 50_000 - testFee + 30_000 - testFee,
+		bob.Address:   20_000 - testFee,
+	})
+}
+
+func TestChannel_SettleAfterDisputePeriod(t *testing.T) {
+	bc, db, cleanup, alice, bob, channelId := setupChannel(t, 3)
+	defer cleanup()
+
+	// Closed at height 2, settles from height 5 on
+	closeTx := signTx(t, blockchain.NewChannelCloseTransaction(alice.Address, channelId, nil), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *closeTx); err != nil {
+		t.Fatalf("Close by the sender should be valid: %v", err)
+	}
+
+	for range 2 {
+		// Empty blocks only differ by the coinbase timestamp
+		time.Sleep(2 * time.Millisecond)
+
+		if _, err := appendBlock(t, bc, db); err != nil {
+			t.Fatalf("Failed to append block: %v", err)
+		}
+	}
+
+	stranger, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	strangerTx := signTx(t, blockchain.NewChannelSettleTransaction(stranger.Address, channelId), stranger, testFee)
+	if _, err := appendBlock(t, bc, db, *strangerTx); err == nil {
+		t.Fatal("Expected settle by a third party to be rejected")
+	}
+
+	settleTx := signTx(t, blockchain.NewChannelSettleTransaction(alice.Address, channelId), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *settleTx); err != nil {
+		t.Fatalf("Settle after the dispute period should be valid: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{
+		alice.Address: 100_000 - 3*testFee,
+		bob.Address:   0,
+	})
+}
+
+func TestChannel_PendingSettlementConflict(t *testing.T) {
+	bc, _, cleanup, alice, bob, channelId := setupChannel(t, 5)
+	defer cleanup()
+
+	closeTx := blockchain.NewChannelCloseTransaction(bob.Address, channelId, signedState(channelId, 1, 10_000, alice))
+	closeTx.PublicKey = bob.GetPublicKeyHex()
+	signTx(t, closeTx, bob, bc.Mempool.CalculateFee(closeTx))
+
+	if err := bc.AddTransactionToMempool(closeTx); err != nil {
+		t.Fatalf("Failed to add close to mempool: %v", err)
+	}
+
+	senderClose := signTx(t, blockchain.NewChannelCloseTransaction(alice.Address, channelId, nil), alice, closeTx.Fee*2)
+	if err := bc.ValidateTransaction(senderClose); err == nil {
+		t.Error("A second pending settlement of the same channel should be rejected")
+	}
+}
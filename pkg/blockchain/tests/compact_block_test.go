@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestCompactBlock_RebuildAndComplete(t *testing.T) {
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	held := signTx(t, blockchain.NewTransaction(alice.Address, "bob", 1_000, utils.GetTimestamp()), alice, testFee)
+	missing := signTx(t, blockchain.NewTransaction(alice.Address, "carol", 2_000, utils.GetTimestamp()), alice, testFee)
+
+	block := &blockchain.Block{
+		Id:           1,
+		PrevHash:     bytes.Repeat([]byte{0x01}, 32),
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward), *held, *missing},
+		BaseFee:      blockchain.InitialBaseFee,
+	}
+
+	if err := block.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	compact, err := blockchain.NewCompactBlock(block)
+	if err != nil {
+		t.Fatalf("Failed to create compact block: %v", err)
+	}
+
+	if len(compact.ShortIds) != 2 {
+		t.Fatalf("Expected 2 short ids, got %d", len(compact.ShortIds))
+	}
+
+	mp := blockchain.NewMempool(1048576)
+	mp.AddTransaction(held)
+
+	rebuilt, missingIdx := compact.Rebuild(mp)
+	if !slices.Equal(missingIdx, []int{1}) {
+		t.Fatalf("Expected only the second tx to be missing, got %v", missingIdx)
+	}
+
+	if err := compact.Complete(rebuilt, missingIdx, []blockchain.Transaction{*held}); err == nil {
+		t.Error("A tx that does not match the short id should be rejected")
+	}
+
+	if err := compact.Complete(rebuilt, missingIdx, []blockchain.Transaction{*missing}); err != nil {
+		t.Fatalf("Failed to complete block: %v", err)
+	}
+
+	if !bytes.Equal(rebuilt.Hash, block.Hash) || len(rebuilt.Transactions) != 3 {
+		t.Error("Rebuilt block should be the original one")
+	}
+
+	// Same short ids, but the coinbase no longer matches the merkle root
+	compact.Coinbase.Amount++
+	rebuilt, missingIdx = compact.Rebuild(mp)
+	if err := compact.Complete(rebuilt, missingIdx, []blockchain.Transaction{*missing}); !errors.Is(err, blockchain.ErrCompactBlockMismatch) {
+		t.Errorf("Expected %v, got %v", blockchain.ErrCompactBlockMismatch, err)
+	}
+
+	block.Transactions = block.Transactions[1:]
+	if _, err := blockchain.NewCompactBlock(block); err == nil {
+		t.Error("A block without a coinbase cannot be made compact")
+	}
+}
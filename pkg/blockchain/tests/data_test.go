@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestTx_DataCoveredByHashAndSize(t *testing.T) {
+	tx := blockchain.NewTransaction("Alice", "Bob", 100, 1234567890)
+	hashWithoutData := tx.Hash().EncodeToString()
+	sizeWithoutData := tx.Size()
+
+	tx.Data = "INV-2024-0042"
+
+	if tx.Hash().EncodeToString() == hashWithoutData {
+		t.Error("Data should be part of the transaction hash")
+	}
+
+	if tx.Size() < sizeWithoutData+len(tx.Data) {
+		t.Errorf("Expected size of at least %d, got %d", sizeWithoutData+len(tx.Data), tx.Size())
+	}
+}
+
+func TestVerifyTransaction_DataTooLarge(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := &blockchain.Transaction{
+		From:      keyPair.Address,
+		To:        "bob",
+		Amount:    100,
+		Fee:       10,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Data:      strings.Repeat("a", blockchain.MaxTxDataSize+1),
+	}
+
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	if err := bc.VerifyTransaction(tx); err == nil {
+		t.Error("Transaction with oversized data should be rejected")
+	}
+
+	tx.Data = strings.Repeat("a", blockchain.MaxTxDataSize)
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Errorf("Transaction with data at the size limit should be valid: %v", err)
+	}
+}
+
+func TestFindTransactionsByDataPrefix(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	invoiceTx := blockchain.NewTransaction("alice", "bob", 100, 1234567890)
+	invoiceTx.Data = "INV-0042"
+
+	globTx := blockchain.NewTransaction("alice", "carol", 200, 1234567891)
+	globTx.Data = "I*V-0043"
+
+	plainTx := blockchain.NewTransaction("alice", "dave", 300, 1234567892)
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	blockId, err := db.AddBlock(sqlTx, "prev", "hash", "merkle", 0, 1234567890, 1, blockchain.InitialBaseFee)
+	if err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	txs := []blockchain.Transaction{*invoiceTx, *globTx, *plainTx}
+	if err := bc.AddTransactionToDB(sqlTx, int(blockId), txs); err != nil {
+		t.Fatalf("Failed to add transactions: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	matches, err := bc.FindTransactionsByDataPrefix("INV-", 10)
+	if err != nil {
+		t.Fatalf("Failed to search transactions: %v", err)
+	}
+
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+
+	if matches[0].Transaction.Data != "INV-0042" || matches[0].BlockHeight != 1 {
+		t.Errorf("Unexpected match: data %q at height %d", matches[0].Transaction.Data, matches[0].BlockHeight)
+	}
+
+	// Wildcards in the prefix are matched literally
+	matches, err = bc.FindTransactionsByDataPrefix("I*", 10)
+	if err != nil {
+		t.Fatalf("Failed to search transactions: %v", err)
+	}
+
+	if len(matches) != 1 || matches[0].Transaction.Data != "I*V-0043" {
+		t.Errorf("Expected only the literal I* match, got %d matches", len(matches))
+	}
+
+	// Case-sensitive
+	matches, err = bc.FindTransactionsByDataPrefix("inv-", 10)
+	if err != nil {
+		t.Fatalf("Failed to search transactions: %v", err)
+	}
+
+	if len(matches) != 0 {
+		t.Errorf("Expected no case-insensitive matches, got %d", len(matches))
+	}
+
+	pendingTx := blockchain.NewTransaction("alice", "erin", 400, 1234567893)
+	pendingTx.Data = "INV-0044"
+	mp.AddTransaction(pendingTx)
+
+	if pending := mp.FindByDataPrefix("INV-"); len(pending) != 1 {
+		t.Errorf("Expected 1 pending match, got %d", len(pending))
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+)
+
+func createFeeRateTransaction(amount uint64, feeRate uint64) *blockchain.Transaction {
+	tx := blockchain.NewTransaction("Alice", "Bob", amount, 1234567890)
+	tx.Fee = feeRate * uint64(tx.Size())
+	return tx
+}
+
+func TestFeeEstimator_NoData(t *testing.T) {
+	fe := blockchain.NewFeeEstimator()
+
+	if _, err := fe.EstimateFee(1); !errors.Is(err, blockchain.ErrInsufficientFeeData) {
+		t.Errorf("Expected ErrInsufficientFeeData, got %v", err)
+	}
+}
+
+func TestFeeEstimator_InvalidTarget(t *testing.T) {
+	fe := blockchain.NewFeeEstimator()
+
+	for _, target := range []int{0, -1, blockchain.MaxConfirmTarget + 1} {
+		if _, err := fe.EstimateFee(target); err == nil {
+			t.Errorf("Expected error for target %d", target)
+		}
+	}
+}
+
+func TestFeeEstimator_ByTarget(t *testing.T) {
+	fe := blockchain.NewFeeEstimator()
+
+	height := int64(0)
+
+	// Expensive txs confirm in the next block, cheap ones wait 10 blocks
+	for round := 0; round < 5; round++ {
+		expensive := createFeeRateTransaction(uint64(1000+round), 40)
+		cheap := createFeeRateTransaction(uint64(2000+round), 10)
+
+		fe.TrackTransaction(expensive, height)
+		fe.TrackTransaction(cheap, height)
+
+		height++
+		fe.ProcessBlock(height, []blockchain.Transaction{*expensive})
+
+		for i := 0; i < 9; i++ {
+			height++
+			fe.ProcessBlock(height, nil)
+		}
+
+		height++
+		fe.ProcessBlock(height, []blockchain.Transaction{*cheap})
+	}
+
+	fast, err := fe.EstimateFee(1)
+	if err != nil {
+		t.Fatalf("EstimateFee(1) failed: %v", err)
+	}
+
+	if fast.FeeRate != 40 {
+		t.Errorf("Expected fee rate 40 for target 1, got %d", fast.FeeRate)
+	}
+
+	if fast.Confidence < 0.85 {
+		t.Errorf("Expected confidence >= 0.85, got %f", fast.Confidence)
+	}
+
+	slow, err := fe.EstimateFee(12)
+	if err != nil {
+		t.Fatalf("EstimateFee(12) failed: %v", err)
+	}
+
+	if slow.FeeRate != 10 {
+		t.Errorf("Expected fee rate 10 for target 12, got %d", slow.FeeRate)
+	}
+
+	if slow.TargetBlocks != 12 {
+		t.Errorf("Expected target 12, got %d", slow.TargetBlocks)
+	}
+}
+
+func TestFeeEstimator_StaleTransactionsCountAsFailures(t *testing.T) {
+	fe := blockchain.NewFeeEstimator()
+
+	confirmed := createFeeRateTransaction(100, 10)
+	stale := createFeeRateTransaction(200, 10)
+
+	fe.TrackTransaction(confirmed, 0)
+	fe.TrackTransaction(stale, 0)
+
+	fe.ProcessBlock(1, []blockchain.Transaction{*confirmed})
+
+	// stale never confirms
+	for height := int64(2); height <= blockchain.MaxConfirmTarget+1; height++ {
+		fe.ProcessBlock(height, nil)
+	}
+
+	// Only half of the bucket confirmed, below the success threshold
+	if _, err := fe.EstimateFee(blockchain.MaxConfirmTarget); !errors.Is(err, blockchain.ErrInsufficientFeeData) {
+		t.Errorf("Expected ErrInsufficientFeeData, got %v", err)
+	}
+}
+
+func TestFeeEstimator_IgnoresUntrackedAndCoinbase(t *testing.T) {
+	fe := blockchain.NewFeeEstimator()
+
+	coinbaseTx := blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)
+	fe.TrackTransaction(coinbaseTx, 0)
+
+	untracked := createFeeRateTransaction(100, 10)
+	fe.ProcessBlock(1, []blockchain.Transaction{*coinbaseTx, *untracked})
+
+	if _, err := fe.EstimateFee(1); !errors.Is(err, blockchain.ErrInsufficientFeeData) {
+		t.Errorf("Expected ErrInsufficientFeeData, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func createSponsoredTransaction(t *testing.T) (*blockchain.Transaction, *CryptoGraphy.KeyPair, *CryptoGraphy.KeyPair) {
+	t.Helper()
+
+	sender, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	sponsor, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := &blockchain.Transaction{
+		From:              sender.Address,
+		To:                "bob",
+		Amount:            100,
+		Fee:               testFee,
+		Timestamp:         utils.GetTimestamp(),
+		Status:            "pending",
+		FeePayer:          sponsor.Address,
+		FeePayerPublicKey: sponsor.GetPublicKeyHex(),
+	}
+
+	if err := tx.Sign(sender, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	if err := tx.SignAsFeePayer(sponsor, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction as fee payer: %v", err)
+	}
+
+	return tx, sender, sponsor
+}
+
+func TestFeePayer_Signatures(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	tx, sender, _ := createSponsoredTransaction(t)
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Fatalf("Sponsored transaction should be valid: %v", err)
+	}
+
+	// Missing sponsor signature
+	unsigned := *tx
+	unsigned.FeePayerSignature = nil
+	if unsigned.Verify(blockchain.DefaultChainId) {
+		t.Error("Sponsored transaction without fee payer signature should not verify")
+	}
+
+	// Only the declared sponsor may co-sign
+	if err := unsigned.SignAsFeePayer(sender, blockchain.DefaultChainId); err == nil {
+		t.Error("Expected error when a different key signs as fee payer")
+	}
+
+	// The sender's signature covers the fee payer
+	swapped := *tx
+	swapped.FeePayer = sender.Address
+	swapped.FeePayerPublicKey = sender.GetPublicKeyHex()
+	if err := swapped.SignAsFeePayer(sender, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction as fee payer: %v", err)
+	}
+
+	if swapped.Verify(blockchain.DefaultChainId) {
+		t.Error("Replacing the fee payer should invalidate the sender's signature")
+	}
+
+	// Fee payer address must match its key
+	mismatched := *tx
+	mismatched.FeePayer = "someone-else"
+	if err := mismatched.VerifyFeePayer(); err == nil {
+		t.Error("Expected fee payer address mismatch")
+	}
+
+	if tx.SignedSize() != tx.Size() {
+		t.Errorf("Signed size %d should match the size of the signed tx %d", tx.SignedSize(), tx.Size())
+	}
+}
+
+func TestFeePayer_PendingFeesCountAgainstSponsor(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	tx, sender, sponsor := createSponsoredTransaction(t)
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, sender.Address, 100); err != nil {
+		t.Fatalf("Failed to fund sender: %v", err)
+	}
+
+	if err := db.IncreaseUserBalance(sqlTx, sponsor.Address, testFee+40); err != nil {
+		t.Fatalf("Failed to fund sponsor: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	// The sender only needs the amount, the sponsor covers the fee
+	if err := bc.ValidateTransaction(tx); err != nil {
+		t.Fatalf("Sponsored transaction should pass balance validation: %v", err)
+	}
+
+	mp.AddTransaction(tx)
+
+	senderBalance, err := bc.GetBalance(sender.Address)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+
+	if senderBalance != 0 {
+		t.Errorf("Expected sender balance 0, got %d", senderBalance)
+	}
+
+	sponsorBalance, err := bc.GetBalance(sponsor.Address)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+
+	if sponsorBalance != 40 {
+		t.Errorf("Expected sponsor balance 40, got %d", sponsorBalance)
+	}
+}
+
+func TestFeePayer_BlockRoundTrip(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	tx, sender, sponsor := createSponsoredTransaction(t)
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, sender.Address, 100); err != nil {
+		t.Fatalf("Failed to fund sender: %v", err)
+	}
+
+	if err := db.IncreaseUserBalance(sqlTx, sponsor.Address, testFee+40); err != nil {
+		t.Fatalf("Failed to fund sponsor: %v", err)
+	}
+
+	genesisBlock := bc.GetLatestBlock()
+	newBlock := &blockchain.Block{
+		Id:           1,
+		PrevHash:     genesisBlock.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward), *tx},
+		BaseFee:      bc.NextBaseFee(),
+	}
+
+	if err := bc.VerifyBlockTransactions(newBlock); err != nil {
+		t.Fatalf("Block transactions should be valid: %v", err)
+	}
+
+	if err := newBlock.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	if err := bc.AddBlock(sqlTx, newBlock); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	expectedBalances := map[string]uint64{
+		sender.Address:  0,
+		sponsor.Address: 40,
+		"bob":           100,
+		"miner":         blockchain.MiningReward + tx.Fee - tx.MinFee(newBlock.BaseFee),
+	}
+
+	for address, expected := range expectedBalances {
+		balance, err := db.GetConfirmedBalance(address)
+		if err != nil {
+			t.Fatalf("Failed to get balance of %s: %v", address, err)
+		}
+
+		if balance != expected {
+			t.Errorf("Expected balance %d for %s, got %d", expected, address, balance)
+		}
+	}
+
+	loadedBlock, err := bc.GetBlockById(1)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if loadedTx.FeePayer != sponsor.Address || !bytes.Equal(loadedTx.FeePayerSignature, tx.FeePayerSignature) {
+		t.Fatalf("Fee payer fields were not restored: %+v", loadedTx)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, newBlock.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
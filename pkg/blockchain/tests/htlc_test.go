@@ -0,0 +1,325 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+const htlcPreimage = "73776170207365637265742031" // "swap secret 1"
+
+// appendBlock -> Verifies the txs, then stores them in the next block on top of the chain
+func appendBlock(t *testing.T, bc *blockchain.Blockchain, db *database.Database, txs ...blockchain.Transaction) (*blockchain.Block, error) {
+	t.Helper()
+
+	latestBlock := bc.GetLatestBlock()
+	newBlock := &blockchain.Block{
+		Id:           latestBlock.Id + 1,
+		PrevHash:     latestBlock.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: append([]blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)}, txs...),
+		BaseFee:      bc.NextBaseFee(),
+	}
+
+	if err := bc.VerifyBlockTransactions(newBlock); err != nil {
+		return nil, err
+	}
+
+	if err := newBlock.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := bc.AddBlock(sqlTx, newBlock); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	bc.AddBlockToMemory(newBlock)
+
+	return newBlock, nil
+}
+
+func fundAddress(t *testing.T, db *database.Database, address string, amount uint64) {
+	t.Helper()
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, address, amount); err != nil {
+		t.Fatalf("Failed to fund %s: %v", address, err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+}
+
+// testFee -> Flat fee of the test txs, above the base fee any of them pays
+const testFee = 1_000
+
+func signTx(t *testing.T, tx *blockchain.Transaction, keyPair *CryptoGraphy.KeyPair, fee uint64) *blockchain.Transaction {
+	t.Helper()
+
+	tx.Fee = fee
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	return tx
+}
+
+func setupHTLC(t *testing.T, expiry int64) (*blockchain.Blockchain, *database.Database, func(), *CryptoGraphy.KeyPair, *CryptoGraphy.KeyPair, string) {
+	t.Helper()
+
+	db, _, cleanup := setupTestDB(t)
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	bob, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 100_000)
+
+	hashLock, err := blockchain.HashLockOf(htlcPreimage)
+	if err != nil {
+		t.Fatalf("Failed to hash preimage: %v", err)
+	}
+
+	lockTx := signTx(t, blockchain.NewHTLCLockTransaction(alice.Address, bob.Address, 50_000, hashLock, expiry), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *lockTx); err != nil {
+		t.Fatalf("Lock transaction should be valid: %v", err)
+	}
+
+	return bc, db, cleanup, alice, bob, lockTx.LedgerId()
+}
+
+func TestHTLC_PayloadValidation(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	hashLock, _ := blockchain.HashLockOf(htlcPreimage)
+
+	claimWithAmount := blockchain.NewHTLCClaimTransaction(keyPair.Address, "id", htlcPreimage)
+	claimWithAmount.Amount = 100
+
+	plainWithPayload := blockchain.NewTransaction(keyPair.Address, "bob", 100, utils.GetTimestamp())
+	plainWithPayload.HTLC = &blockchain.HTLCPayload{HashLock: hashLock}
+
+	unknownType := blockchain.NewTransaction(keyPair.Address, "bob", 100, utils.GetTimestamp())
+	unknownType.Type = "teleport"
+
+	tests := []struct {
+		name string
+		tx   *blockchain.Transaction
+	}{
+		{"Invalid hash lock", blockchain.NewHTLCLockTransaction(keyPair.Address, "bob", 100, "abcd", 10)},
+		{"No expiry", blockchain.NewHTLCLockTransaction(keyPair.Address, "bob", 100, hashLock, 0)},
+		{"No recipient", blockchain.NewHTLCLockTransaction(keyPair.Address, "", 100, hashLock, 10)},
+		{"Claim with amount", claimWithAmount},
+		{"Claim without preimage", blockchain.NewHTLCClaimTransaction(keyPair.Address, "id", "")},
+		{"Refund without id", blockchain.NewHTLCRefundTransaction(keyPair.Address, "")},
+		{"Plain transfer with payload", plainWithPayload},
+		{"Unknown type", unknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signTx(t, tt.tx, keyPair, testFee)
+
+			if err := bc.VerifyTransaction(tt.tx); err == nil {
+				t.Error("Expected invalid HTLC transaction to be rejected")
+			}
+		})
+	}
+
+	validLock := signTx(t, blockchain.NewHTLCLockTransaction(keyPair.Address, "bob", 100, hashLock, 10), keyPair, testFee)
+	if err := bc.VerifyTransaction(validLock); err != nil {
+		t.Errorf("Valid lock should pass: %v", err)
+	}
+}
+
+func TestHTLC_Claim(t *testing.T) {
+	bc, db, cleanup, alice, bob, htlcId := setupHTLC(t, 5)
+	defer cleanup()
+
+	htlc, err := bc.GetHTLC(htlcId)
+	if err != nil {
+		t.Fatalf("Failed to get htlc: %v", err)
+	}
+
+	if htlc.Status != blockchain.HTLCStatusLocked || htlc.Amount != 50_000 || htlc.Recipient != bob.Address {
+		t.Fatalf("Unexpected htlc state: %+v", htlc)
+	}
+
+	aliceBalance, _ := db.GetConfirmedBalance(alice.Address)
+	if aliceBalance != 100_000-50_000-testFee {
+		t.Errorf("Expected alice balance %d, got %d", 100_000-50_000-testFee, aliceBalance)
+	}
+
+	// Locked funds are not credited to the recipient yet
+	if bobBalance, _ := db.GetConfirmedBalance(bob.Address); bobBalance != 0 {
+		t.Errorf("Expected bob balance 0 before the claim, got %d", bobBalance)
+	}
+
+	wrongPreimage := signTx(t, blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, "00"), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *wrongPreimage); err == nil {
+		t.Error("Claim with a wrong preimage should be rejected")
+	}
+
+	claimBySender := signTx(t, blockchain.NewHTLCClaimTransaction(alice.Address, htlcId, htlcPreimage), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *claimBySender); err == nil {
+		t.Error("Only the recipient should be able to claim")
+	}
+
+	refundTooEarly := signTx(t, blockchain.NewHTLCRefundTransaction(alice.Address, htlcId), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *refundTooEarly); err == nil {
+		t.Error("Refund before expiry should be rejected")
+	}
+
+	// bob holds no coins, the fee comes out of the claimed amount
+	claimTx := signTx(t, blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, htlcPreimage), bob, testFee)
+	if err := bc.ValidateTransaction(claimTx); err != nil {
+		t.Fatalf("Claim should pass mempool validation: %v", err)
+	}
+
+	if _, err := appendBlock(t, bc, db, *claimTx); err != nil {
+		t.Fatalf("Claim should be valid: %v", err)
+	}
+
+	if bobBalance, _ := db.GetConfirmedBalance(bob.Address); bobBalance != 50_000-testFee {
+		t.Errorf("Expected bob balance %d, got %d", 50_000-testFee, bobBalance)
+	}
+
+	htlc, err = bc.GetHTLC(htlcId)
+	if err != nil {
+		t.Fatalf("Failed to get htlc: %v", err)
+	}
+
+	if htlc.Status != blockchain.HTLCStatusClaimed || htlc.Preimage != htlcPreimage {
+		t.Errorf("Expected claimed htlc with revealed preimage, got %+v", htlc)
+	}
+
+	secondClaim := signTx(t, blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, htlcPreimage), bob, 2*testFee)
+	if _, err := appendBlock(t, bc, db, *secondClaim); err == nil {
+		t.Error("An HTLC should only be claimed once")
+	}
+}
+
+func TestHTLC_Refund(t *testing.T) {
+	bc, db, cleanup, alice, bob, htlcId := setupHTLC(t, 3)
+	defer cleanup()
+
+	// Lock is at height 1, the next block is 2, below the expiry
+	refundTx := signTx(t, blockchain.NewHTLCRefundTransaction(alice.Address, htlcId), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *refundTx); err == nil {
+		t.Fatal("Refund before expiry should be rejected")
+	}
+
+	if _, err := appendBlock(t, bc, db); err != nil {
+		t.Fatalf("Failed to append empty block: %v", err)
+	}
+
+	// Height 3 reached the expiry, claims are too late and refunds are allowed
+	lateClaim := signTx(t, blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, htlcPreimage), bob, testFee)
+	if _, err := appendBlock(t, bc, db, *lateClaim); err == nil {
+		t.Error("Claim at the expiry height should be rejected")
+	}
+
+	if _, err := appendBlock(t, bc, db, *refundTx); err != nil {
+		t.Fatalf("Refund at the expiry height should be valid: %v", err)
+	}
+
+	if aliceBalance, _ := db.GetConfirmedBalance(alice.Address); aliceBalance != 100_000-2*testFee {
+		t.Errorf("Expected alice balance %d, got %d", 100_000-2*testFee, aliceBalance)
+	}
+
+	htlc, err := bc.GetHTLC(htlcId)
+	if err != nil {
+		t.Fatalf("Failed to get htlc: %v", err)
+	}
+
+	if htlc.Status != blockchain.HTLCStatusRefunded || htlc.SettleTx != refundTx.LedgerId() {
+		t.Errorf("Expected refunded htlc, got %+v", htlc)
+	}
+}
+
+func TestHTLC_PendingSettlementConflict(t *testing.T) {
+	bc, _, cleanup, _, bob, htlcId := setupHTLC(t, 5)
+	defer cleanup()
+
+	claimTx := blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, htlcPreimage)
+	claimTx.PublicKey = bob.GetPublicKeyHex()
+	signTx(t, claimTx, bob, bc.Mempool.CalculateFee(claimTx))
+
+	if err := bc.AddTransactionToMempool(claimTx); err != nil {
+		t.Fatalf("Failed to add claim to mempool: %v", err)
+	}
+
+	otherClaim := signTx(t, blockchain.NewHTLCClaimTransaction(bob.Address, htlcId, htlcPreimage), bob, claimTx.Fee*2)
+	if err := bc.ValidateTransaction(otherClaim); err == nil {
+		t.Error("A second pending settlement of the same HTLC should be rejected")
+	}
+}
+
+func TestHTLC_BlockRoundTrip(t *testing.T) {
+	bc, _, cleanup, _, _, htlcId := setupHTLC(t, 5)
+	defer cleanup()
+
+	originalBlock := bc.GetLatestBlock()
+
+	loadedBlock, err := bc.GetBlockById(originalBlock.Id)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if loadedTx.Type != blockchain.TxTypeHTLCLock || loadedTx.HTLC == nil || loadedTx.HTLC.Expiry != 5 {
+		t.Fatalf("HTLC payload was not restored: %+v", loadedTx)
+	}
+
+	if loadedTx.LedgerId() != htlcId {
+		t.Errorf("Ledger id of the loaded lock %s should match %s", loadedTx.LedgerId(), htlcId)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, originalBlock.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// appendEmptyBlocks -> Appends count coinbase only blocks, each paying another miner so
+// their merkle roots differ
+func appendEmptyBlocks(t *testing.T, bc *blockchain.Blockchain, db *database.Database, count int) {
+	t.Helper()
+
+	for range count {
+		latest := bc.GetLatestBlock()
+		block := &blockchain.Block{
+			Id:           latest.Id + 1,
+			PrevHash:     latest.Hash,
+			Timestamp:    utils.GetTimestamp(),
+			Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx(fmt.Sprintf("miner-%d", latest.Id+1), blockchain.MiningReward)},
+			BaseFee:      bc.NextBaseFee(),
+		}
+
+		if err := block.HashBlock(); err != nil {
+			t.Fatalf("Failed to hash block: %v", err)
+		}
+
+		sqlTx, err := db.BeginTx()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+
+		if err := bc.AddBlock(sqlTx, block); err != nil {
+			sqlTx.Rollback()
+			t.Fatalf("Failed to add block: %v", err)
+		}
+
+		if err := sqlTx.Commit(); err != nil {
+			t.Fatalf("Failed to commit transaction: %v", err)
+		}
+
+		bc.AddBlockToMemory(block)
+	}
+}
+
+func TestLocator_Spacing(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	appendEmptyBlocks(t, bc, db, 40)
+
+	// Ten dense hashes from the tip, then the step doubles down to genesis
+	expected := []int64{40, 39, 38, 37, 36, 35, 34, 33, 32, 31, 29, 25, 17, 1, 0}
+
+	locator := bc.BlockLocator()
+	if len(locator) != len(expected) {
+		t.Fatalf("Expected %d locator hashes, got %d", len(expected), len(locator))
+	}
+
+	for idx, height := range expected {
+		if block := bc.GetBlockByHash(locator[idx]); block == nil || block.Id != height {
+			t.Errorf("Expected locator hash %d to be block %d, got %+v", idx, height, block)
+		}
+	}
+}
+
+func TestLocator_HeadersAfter(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	appendEmptyBlocks(t, bc, db, 10)
+
+	block4, err := bc.GetBlockById(4)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+
+	unknown := bytes.Repeat([]byte{0xab}, 32)
+
+	testCases := []struct {
+		name    string
+		locator [][]byte
+		limit   int
+		first   int64
+		count   int
+	}{
+		{"up to date", bc.BlockLocator(), blockchain.MaxHeadersPerMsg, 0, 0},
+		{"empty locator starts at genesis", nil, blockchain.MaxHeadersPerMsg, 0, 11},
+		{"fork point skips unknown hashes", [][]byte{unknown, block4.Hash}, blockchain.MaxHeadersPerMsg, 5, 6},
+		{"limited", [][]byte{block4.Hash}, 3, 5, 3},
+		{"nothing shared", [][]byte{unknown}, 2, 0, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := bc.HeadersAfter(tc.locator, tc.limit)
+			if len(headers) != tc.count {
+				t.Fatalf("Expected %d headers, got %d", tc.count, len(headers))
+			}
+
+			for idx, header := range headers {
+				if header.Id != tc.first+int64(idx) {
+					t.Errorf("Expected header %d, got %d", tc.first+int64(idx), header.Id)
+				}
+			}
+
+			if tc.first > 0 && bc.GetBlockByHash(headers[0].PrevHash) == nil {
+				t.Error("First header should connect to a known block")
+			}
+		})
+	}
+}
+
+func TestLocator_LoadedChainFindsBlocksByHash(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	// Only mined blocks survive loading
+	genesis := bc.GetLatestBlock()
+	block := &blockchain.Block{
+		Id:           1,
+		PrevHash:     genesis.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)},
+		BaseFee:      bc.NextBaseFee(),
+	}
+
+	if err := mineBlock(block); err != nil {
+		t.Fatalf("Failed to mine block: %v", err)
+	}
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := bc.AddBlock(sqlTx, block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	bc.AddBlockToMemory(block)
+
+	loaded, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to load blockchain: %v", err)
+	}
+
+	for _, block := range bc.Blocks {
+		if found := loaded.GetBlockByHash(block.Hash); found == nil || found.Id != block.Id {
+			t.Errorf("Expected block %d to be found by its hash, got %+v", block.Id, found)
+		}
+	}
+
+	if headers := loaded.HeadersAfter(bc.BlockLocator(), blockchain.MaxHeadersPerMsg); len(headers) != 0 {
+		t.Errorf("Expected no headers after our own tip, got %d", len(headers))
+	}
+}
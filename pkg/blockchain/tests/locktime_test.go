@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestTx_IsFinal(t *testing.T) {
+	timeLock := int64(1_700_000_000_000) // unix ms
+
+	tests := []struct {
+		name       string
+		lockTime   int64
+		height     int64
+		medianTime int64
+		expected   bool
+	}{
+		{"No lock time", 0, 1, 0, true},
+		{"Height lock reached", 10, 10, 0, true},
+		{"Height lock not reached", 10, 9, 0, false},
+		{"Height lock ignores time", 10, 9, timeLock * 2, false},
+		{"Time lock reached", timeLock, 1, timeLock, true},
+		{"Time lock not reached", timeLock, 1, timeLock - 1, false},
+		{"Time lock ignores height", timeLock, timeLock, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := blockchain.NewTransaction("Alice", "Bob", 100, 1234567890)
+			tx.LockTime = tt.lockTime
+
+			if final := tx.IsFinal(tt.height, tt.medianTime); final != tt.expected {
+				t.Errorf("Expected IsFinal=%t, got %t", tt.expected, final)
+			}
+		})
+	}
+}
+
+func TestTx_LockTimeCoveredByHash(t *testing.T) {
+	tx := blockchain.NewTransaction("Alice", "Bob", 100, 1234567890)
+	hashWithoutLock := tx.Hash().EncodeToString()
+
+	tx.LockTime = 5
+	if tx.Hash().EncodeToString() == hashWithoutLock {
+		t.Error("Lock time should be part of the transaction hash")
+	}
+}
+
+func TestMedianTimePast(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	// Out of order timestamps, the median must not depend on the order
+	timestamps := []int64{500, 100, 300, 200, 400}
+	for idx, timestamp := range timestamps {
+		bc.AddBlockToMemory(&blockchain.Block{Id: int64(idx + 1), Timestamp: timestamp})
+	}
+
+	// Blocks 0..5, the genesis block has the largest timestamp
+	if median := bc.MedianTimePast(6); median != 400 {
+		t.Errorf("Expected median time 400, got %d", median)
+	}
+
+	// Only blocks below the height count
+	if median := bc.MedianTimePast(3); median != 500 {
+		t.Errorf("Expected median time 500, got %d", median)
+	}
+}
+
+func TestVerifyBlockTransactions_RejectsNonFinal(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := &blockchain.Transaction{
+		From:      keyPair.Address,
+		To:        "bob",
+		Amount:    100,
+		Fee:       testFee,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		LockTime:  2,
+	}
+
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	block := &blockchain.Block{Id: 1, Transactions: []blockchain.Transaction{*tx}, BaseFee: bc.NextBaseFee()}
+	if err := bc.VerifyBlockTransactions(block); err == nil {
+		t.Error("Block at height 1 should not contain a tx locked until height 2")
+	}
+
+	if _, err := appendBlock(t, bc, db); err != nil {
+		t.Fatalf("Failed to append block: %v", err)
+	}
+
+	block.Id = 2
+	block.BaseFee = bc.NextBaseFee()
+	if err := bc.VerifyBlockTransactions(block); err != nil {
+		t.Errorf("Block at height 2 may contain a tx locked until height 2: %v", err)
+	}
+}
+
+func TestMineBlock_SkipsNonFinalTransactions(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, "alice", 5000); err != nil {
+		t.Fatalf("Failed to increase balance: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	finalTx := blockchain.NewTransaction("alice", "bob", 100, utils.GetTimestamp())
+	finalTx.Fee = testFee
+
+	lockedTx := blockchain.NewTransaction("alice", "carol", 200, utils.GetTimestamp())
+	lockedTx.Fee = testFee
+	lockedTx.LockTime = 100
+
+	mp.AddTransaction(finalTx)
+	mp.AddTransaction(lockedTx)
+
+	block, err := bc.MineBlock(context.Background(), mp, "miner")
+	if err != nil {
+		t.Fatalf("Failed to mine block: %v", err)
+	}
+
+	if len(block.Transactions) != 2 {
+		t.Fatalf("Expected coinbase and final tx, got %d transactions", len(block.Transactions))
+	}
+
+	if block.Transactions[1].To != "bob" {
+		t.Errorf("Expected final tx to be mined, got tx to %s", block.Transactions[1].To)
+	}
+
+	if !mp.HasTransaction(lockedTx.Hash().EncodeToString()) {
+		t.Error("Locked transaction should stay in the mempool")
+	}
+
+	if mp.HasTransaction(finalTx.Hash().EncodeToString()) {
+		t.Error("Mined transaction should leave the mempool")
+	}
+}
@@ -336,7 +336,7 @@ func TestCalculateTxFee(t *testing.T) {
 			name: "Medium congestion",
 			setupMempool: func(mp *blockchain.Mempool) {
 				// Fill mempool to ~50% capacity
-				for i := 0; i < 500; i++ {
+				for i := 0; i < 1000; i++ {
 					tx := createMockMempoolTransaction("Alice", "Bob", uint64(i))
 					mp.AddTransaction(tx)
 				}
@@ -465,65 +465,6 @@ func TestDeleteMinedTransactions(t *testing.T) {
 	}
 }
 
-func TestSyncMempool(t *testing.T) {
-	mp1 := blockchain.NewMempool(1000000)
-	mp2 := blockchain.NewMempool(1000000)
-
-	// Add transactions to mp1
-	tx1 := createMockMempoolTransaction("Alice", "Bob", 100)
-	tx2 := createMockMempoolTransaction("Bob", "Charlie", 200)
-	mp1.AddTransaction(tx1)
-	mp1.AddTransaction(tx2)
-
-	// Add different transaction to mp2
-	tx3 := createMockMempoolTransaction("Charlie", "Dave", 300)
-	mp2.AddTransaction(tx3)
-
-	// Sync mp2 with mp1
-	mp2.SyncMempool(mp1)
-
-	// mp2 should now have all three transactions
-	if len(mp2.Transactions) != 3 {
-		t.Errorf("Expected 3 transactions after sync, got %d", len(mp2.Transactions))
-	}
-
-	// Verify all transactions are present
-	hash1 := tx1.Hash().EncodeToString()
-	hash2 := tx2.Hash().EncodeToString()
-	hash3 := tx3.Hash().EncodeToString()
-
-	if _, exists := mp2.Transactions[hash1]; !exists {
-		t.Error("Transaction 1 should be in synced mempool")
-	}
-
-	if _, exists := mp2.Transactions[hash2]; !exists {
-		t.Error("Transaction 2 should be in synced mempool")
-	}
-
-	if _, exists := mp2.Transactions[hash3]; !exists {
-		t.Error("Transaction 3 should be in synced mempool")
-	}
-}
-
-func TestSyncMempoolWithEmpty(t *testing.T) {
-	mp1 := blockchain.NewMempool(1000000)
-	mp2 := blockchain.NewMempool(1000000)
-
-	// Add transactions to mp1
-	tx1 := createMockMempoolTransaction("Alice", "Bob", 100)
-	tx2 := createMockMempoolTransaction("Bob", "Charlie", 200)
-	mp1.AddTransaction(tx1)
-	mp1.AddTransaction(tx2)
-
-	// Sync mp1 with empty mp2
-	mp1.SyncMempool(mp2)
-
-	// mp1 should be cleared
-	if len(mp1.Transactions) != 0 {
-		t.Errorf("Expected empty mempool after syncing with empty mempool, got %d transactions", len(mp1.Transactions))
-	}
-}
-
 func TestGetCongestion(t *testing.T) {
 	tests := []struct {
 		name               string
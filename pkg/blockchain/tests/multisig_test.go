@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func generateKeyPairs(t *testing.T, count int) ([]*CryptoGraphy.KeyPair, []string) {
+	t.Helper()
+
+	keyPairs := make([]*CryptoGraphy.KeyPair, count)
+	pubKeys := make([]string, count)
+
+	for i := range keyPairs {
+		kp, err := CryptoGraphy.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("Failed to generate key pair: %v", err)
+		}
+
+		keyPairs[i] = kp
+		pubKeys[i] = kp.GetPublicKeyHex()
+	}
+
+	return keyPairs, pubKeys
+}
+
+func TestMultisig_ThresholdSignatures(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 3)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 2, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	if err := tx.VerifySender(); err != nil {
+		t.Errorf("From should be the multisig address: %v", err)
+	}
+
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("Unsigned multisig transaction should not verify")
+	}
+
+	if err := tx.AddMultisigSignature(keyPairs[0], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("1-of-2 signatures should not verify")
+	}
+
+	if err := tx.AddMultisigSignature(keyPairs[2], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	if !tx.Verify(blockchain.DefaultChainId) {
+		t.Error("2-of-3 signed transaction should verify")
+	}
+}
+
+func TestMultisig_RejectsForeignAndDuplicateSigners(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 2)
+	outsider, _ := generateKeyPairs(t, 1)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 1, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	if err := tx.AddMultisigSignature(outsider[0], blockchain.DefaultChainId); err == nil {
+		t.Error("Key outside the set should not be able to sign")
+	}
+
+	if err := tx.AddMultisigSignature(keyPairs[0], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	if err := tx.AddMultisigSignature(keyPairs[0], blockchain.DefaultChainId); err == nil {
+		t.Error("Same key should not be able to sign twice")
+	}
+
+	// A duplicated signature smuggled in directly must not count twice
+	tx.Threshold = 2
+	tx.Signatures = append(tx.Signatures, tx.Signatures[0])
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("Duplicate signer should not satisfy the threshold")
+	}
+}
+
+func TestMultisig_TamperingInvalidates(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 2)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 2, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	for _, kp := range keyPairs {
+		if err := tx.AddMultisigSignature(kp, blockchain.DefaultChainId); err != nil {
+			t.Fatalf("Failed to add signature: %v", err)
+		}
+	}
+
+	tx.Amount = 5000
+	if tx.Verify(blockchain.DefaultChainId) {
+		t.Error("Tampered multisig transaction should not verify")
+	}
+
+	tx.Amount = 500
+	tx.Threshold = 1
+	if err := tx.VerifySender(); err == nil {
+		t.Error("Changing the threshold should change the sender address")
+	}
+}
+
+func TestMultisig_HashExcludesSignatures(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 2)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 1, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	hashBefore := tx.Hash()
+	if err := tx.AddMultisigSignature(keyPairs[1], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	if !bytes.Equal(hashBefore, tx.Hash()) {
+		t.Error("Adding signatures should not change the transaction hash")
+	}
+}
+
+func TestMultisig_SignedSizeCoversMissingSignatures(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 3)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 2, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	unsignedEstimate := tx.SignedSize()
+
+	for _, kp := range keyPairs[:2] {
+		if err := tx.AddMultisigSignature(kp, blockchain.DefaultChainId); err != nil {
+			t.Fatalf("Failed to add signature: %v", err)
+		}
+	}
+
+	if unsignedEstimate != tx.Size() {
+		t.Errorf("Signed size estimate %d should match the signed size %d", unsignedEstimate, tx.Size())
+	}
+}
+
+func TestMultisig_BlockRoundTrip(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPairs, pubKeys := generateKeyPairs(t, 3)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 2, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+	tx.Fee = testFee
+
+	for _, kp := range keyPairs[1:] {
+		if err := tx.AddMultisigSignature(kp, blockchain.DefaultChainId); err != nil {
+			t.Fatalf("Failed to add signature: %v", err)
+		}
+	}
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := db.IncreaseUserBalance(sqlTx, tx.From, 2000); err != nil {
+		t.Fatalf("Failed to fund multisig address: %v", err)
+	}
+
+	genesisBlock := bc.GetLatestBlock()
+	newBlock := &blockchain.Block{
+		Id:           1,
+		PrevHash:     genesisBlock.Hash,
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward), *tx},
+		BaseFee:      bc.NextBaseFee(),
+	}
+
+	if err := bc.VerifyBlockTransactions(newBlock); err != nil {
+		t.Fatalf("Block transactions should be valid: %v", err)
+	}
+
+	if err := newBlock.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	if err := bc.AddBlock(sqlTx, newBlock); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	loadedBlock, err := bc.GetBlockById(1)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if !loadedTx.IsMultisig() || loadedTx.Threshold != 2 || len(loadedTx.Signatures) != 2 {
+		t.Fatalf("Multisig fields were not restored: %+v", loadedTx)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, newBlock.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+
+	balance, err := db.GetConfirmedBalance(tx.From)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+
+	if balance != 2000-500-testFee {
+		t.Errorf("Expected multisig balance %d, got %d", 2000-500-testFee, balance)
+	}
+}
+
+func TestMultisig_MerkleRootCommitsToSignatures(t *testing.T) {
+	keyPairs, pubKeys := generateKeyPairs(t, 2)
+
+	tx, err := blockchain.NewMultisigTransaction(pubKeys, 1, "bob", 500)
+	if err != nil {
+		t.Fatalf("Failed to create multisig transaction: %v", err)
+	}
+
+	if err := tx.AddMultisigSignature(keyPairs[0], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	block1 := &blockchain.Block{Transactions: []blockchain.Transaction{*tx}}
+	block1.ComputeMerkleRoot()
+
+	otherTx := *tx
+	otherTx.Signatures = nil
+	if err := otherTx.AddMultisigSignature(keyPairs[1], blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to add signature: %v", err)
+	}
+
+	block2 := &blockchain.Block{Transactions: []blockchain.Transaction{otherTx}}
+	block2.ComputeMerkleRoot()
+
+	if bytes.Equal(block1.MerkleRoot, block2.MerkleRoot) {
+		t.Error("Merkle root should change when the signatures change")
+	}
+}
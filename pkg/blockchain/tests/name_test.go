@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+)
+
+func setupName(t *testing.T, name string) (*blockchain.Blockchain, *database.Database, func(), *CryptoGraphy.KeyPair, *CryptoGraphy.KeyPair) {
+	t.Helper()
+
+	db, _, cleanup := setupTestDB(t)
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	bob, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 100_000)
+	fundAddress(t, db, bob.Address, 100_000)
+
+	registerTx := signTx(t, blockchain.NewNameRegisterTransaction(alice.Address, name, blockchain.MinNameDuration), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *registerTx); err != nil {
+		t.Fatalf("Register transaction should be valid: %v", err)
+	}
+
+	return bc, db, cleanup, alice, bob
+}
+
+func TestName_PayloadValidation(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	wrongFee := blockchain.NewNameRegisterTransaction(alice.Address, "alice", blockchain.MinNameDuration)
+	wrongFee.Amount--
+
+	testCases := []struct {
+		name string
+		tx   *blockchain.Transaction
+	}{
+		{"too short", blockchain.NewNameRegisterTransaction(alice.Address, "al", blockchain.MinNameDuration)},
+		{"upper case", blockchain.NewNameRegisterTransaction(alice.Address, "Alice", blockchain.MinNameDuration)},
+		{"starts with a digit", blockchain.NewNameRegisterTransaction(alice.Address, "1alice", blockchain.MinNameDuration)},
+		{"address sized", blockchain.NewNameRegisterTransaction(alice.Address, alice.Address, blockchain.MinNameDuration)},
+		{"duration too short", blockchain.NewNameRegisterTransaction(alice.Address, "alice", blockchain.MinNameDuration-1)},
+		{"duration too long", blockchain.NewNameRegisterTransaction(alice.Address, "alice", blockchain.MaxNameDuration+1)},
+		{"wrong registration fee", wrongFee},
+		{"transfer to self", blockchain.NewNameTransferTransaction(alice.Address, alice.Address, "alice")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signTx(t, tc.tx, alice, testFee)
+
+			if err := bc.VerifyTransaction(tc.tx); err == nil {
+				t.Error("Expected the name transaction to be rejected")
+			}
+		})
+	}
+}
+
+func TestName_RegisterAndResolve(t *testing.T) {
+	bc, db, cleanup, alice, bob := setupName(t, "alice")
+	defer cleanup()
+
+	address, err := bc.ResolveName("alice")
+	if err != nil {
+		t.Fatalf("Failed to resolve name: %v", err)
+	}
+
+	if address != alice.Address {
+		t.Errorf("Expected alice to resolve to %s, got %s", alice.Address, address)
+	}
+
+	record, err := bc.GetName("alice")
+	if err != nil {
+		t.Fatalf("Failed to get name: %v", err)
+	}
+
+	if record.Expiry != record.RegisteredHeight+blockchain.MinNameDuration {
+		t.Errorf("Unexpected expiry: %+v", record)
+	}
+
+	// The registration fee is burned, the tx fee goes to the miner
+	assertBalances(t, db, map[string]uint64{alice.Address: 100_000 - blockchain.NameFee(blockchain.MinNameDuration) - testFee})
+
+	taken := blockchain.NewNameRegisterTransaction(bob.Address, "alice", blockchain.MinNameDuration)
+	signTx(t, taken, bob, testFee)
+	if err := bc.ValidateTransaction(taken); err == nil {
+		t.Error("Registering an active name again should be rejected")
+	}
+
+	if _, err := appendBlock(t, bc, db, *taken); err == nil {
+		t.Error("Block registering an active name again should be rejected")
+	}
+
+	if _, err := bc.ResolveName("nobody"); !errors.Is(err, blockchain.ErrNameNotFound) {
+		t.Errorf("Expected ErrNameNotFound, got %v", err)
+	}
+}
+
+func TestName_RenewAndTransfer(t *testing.T) {
+	bc, db, cleanup, alice, bob := setupName(t, "alice")
+	defer cleanup()
+
+	before, err := bc.GetName("alice")
+	if err != nil {
+		t.Fatalf("Failed to get name: %v", err)
+	}
+
+	notOwner := signTx(t, blockchain.NewNameRenewTransaction(bob.Address, "alice", blockchain.MinNameDuration), bob, testFee)
+	if err := bc.ValidateTransaction(notOwner); err == nil {
+		t.Error("Only the owner should be able to renew")
+	}
+
+	renewTx := signTx(t, blockchain.NewNameRenewTransaction(alice.Address, "alice", blockchain.MinNameDuration), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *renewTx); err != nil {
+		t.Fatalf("Renew should be valid: %v", err)
+	}
+
+	transferTx := signTx(t, blockchain.NewNameTransferTransaction(alice.Address, bob.Address, "alice"), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *transferTx); err != nil {
+		t.Fatalf("Transfer should be valid: %v", err)
+	}
+
+	after, err := bc.GetName("alice")
+	if err != nil {
+		t.Fatalf("Failed to get name: %v", err)
+	}
+
+	if after.Owner != bob.Address || after.Expiry != before.Expiry+blockchain.MinNameDuration {
+		t.Errorf("Unexpected name after renew and transfer: %+v", after)
+	}
+
+	if after.LastTx != transferTx.LedgerId() {
+		t.Errorf("Expected last tx %s, got %s", transferTx.LedgerId(), after.LastTx)
+	}
+
+	// The transfer moves the name, not coins
+	assertBalances(t, db, map[string]uint64{bob.Address: 100_000})
+}
+
+func TestName_PendingConflict(t *testing.T) {
+	bc, _, cleanup, alice, bob := setupName(t, "alice")
+	defer cleanup()
+
+	transferTx := blockchain.NewNameTransferTransaction(alice.Address, bob.Address, "alice")
+	transferTx.PublicKey = alice.GetPublicKeyHex()
+	signTx(t, transferTx, alice, bc.Mempool.CalculateFee(transferTx))
+
+	if err := bc.AddTransactionToMempool(transferTx); err != nil {
+		t.Fatalf("Failed to add transfer to mempool: %v", err)
+	}
+
+	renewTx := signTx(t, blockchain.NewNameRenewTransaction(alice.Address, "alice", blockchain.MinNameDuration), alice, transferTx.Fee)
+	if err := bc.ValidateTransaction(renewTx); err == nil {
+		t.Error("A second pending operation on the same name should be rejected")
+	}
+
+	other := signTx(t, blockchain.NewNameRegisterTransaction(bob.Address, "bob", blockchain.MinNameDuration), bob, transferTx.Fee)
+	if err := bc.ValidateTransaction(other); err != nil {
+		t.Errorf("Operation on another name should be accepted: %v", err)
+	}
+}
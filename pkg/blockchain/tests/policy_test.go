@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+)
+
+func createPolicyTransaction(from string, amount uint64) *blockchain.Transaction {
+	tx := blockchain.NewTransaction(from, "Bob", amount, 1234567890)
+	tx.Fee = blockchain.DefaultMinRelayFeeRate * uint64(tx.Size())
+	return tx
+}
+
+func assertPolicyReason(t *testing.T, err error, reason string) {
+	t.Helper()
+
+	var policyErr *blockchain.PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected *PolicyError with reason %s, got %v", reason, err)
+	}
+
+	if policyErr.Reason != reason {
+		t.Errorf("Expected reason %s, got %s", reason, policyErr.Reason)
+	}
+}
+
+func TestPolicy_AcceptsStandardTransaction(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	if err := policy.Check(createPolicyTransaction("Alice", 1000), mp); err != nil {
+		t.Errorf("Standard transaction should pass policy: %v", err)
+	}
+}
+
+func TestPolicy_Dust(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	tx := createPolicyTransaction("Alice", blockchain.DefaultDustThreshold-1)
+	assertPolicyReason(t, policy.Check(tx, mp), blockchain.RejectDust)
+}
+
+func TestPolicy_FeeTooLow(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	tx := createPolicyTransaction("Alice", 1000)
+	tx.Fee = 1
+	assertPolicyReason(t, policy.Check(tx, mp), blockchain.RejectFeeTooLow)
+}
+
+func TestPolicy_TxTooLarge(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	policy.MaxTxSize = 10
+	mp := blockchain.NewMempool(1048576)
+
+	tx := createPolicyTransaction("Alice", 1000)
+	assertPolicyReason(t, policy.Check(tx, mp), blockchain.RejectTxTooLarge)
+}
+
+func TestPolicy_MempoolFull(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+
+	tx := createPolicyTransaction("Alice", 1000)
+	mp := blockchain.NewMempool(int64(tx.Size() - 1))
+
+	assertPolicyReason(t, policy.Check(tx, mp), blockchain.RejectMempoolFull)
+}
+
+func TestPolicy_SenderTxLimit(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	policy.MaxTxsPerSender = 3
+	mp := blockchain.NewMempool(1048576)
+
+	for i := 0; i < 3; i++ {
+		tx := createPolicyTransaction("Alice", uint64(1000+i))
+		if err := policy.Check(tx, mp); err != nil {
+			t.Fatalf("Transaction %d should pass policy: %v", i, err)
+		}
+		mp.AddTransaction(tx)
+	}
+
+	assertPolicyReason(t, policy.Check(createPolicyTransaction("Alice", 2000), mp),
+		blockchain.RejectSenderTxLimit)
+
+	// Other senders are not affected
+	if err := policy.Check(createPolicyTransaction("Carol", 2000), mp); err != nil {
+		t.Errorf("Other sender should pass policy: %v", err)
+	}
+}
+
+func TestPolicy_SenderByteLimit(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	tx := createPolicyTransaction("Alice", 1000)
+	mp.AddTransaction(tx)
+
+	policy.MaxBytesPerSender = tx.Size() + 1
+
+	assertPolicyReason(t, policy.Check(createPolicyTransaction("Alice", 2000), mp),
+		blockchain.RejectSenderByteLimit)
+}
+
+func TestPolicy_CoinbaseExempt(t *testing.T) {
+	policy := blockchain.DefaultPolicy()
+	mp := blockchain.NewMempool(1048576)
+
+	coinbaseTx := blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)
+	if err := policy.Check(coinbaseTx, mp); err != nil {
+		t.Errorf("Coinbase transaction should be exempt from policy: %v", err)
+	}
+}
+
+func TestAddTransactionToMempool_Policy(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mp := blockchain.NewMempool(1048576)
+	bc, err := blockchain.NewBlockchain(db, mp, blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	tx := createPolicyTransaction("Alice", 1000)
+	if err := bc.AddTransactionToMempool(tx); err != nil {
+		t.Fatalf("Transaction should be admitted: %v", err)
+	}
+
+	// Re-adding the same tx is a no-op
+	if err := bc.AddTransactionToMempool(tx); err != nil {
+		t.Errorf("Re-adding a pending transaction should not error: %v", err)
+	}
+
+	if len(mp.Transactions) != 1 {
+		t.Errorf("Expected 1 transaction in mempool, got %d", len(mp.Transactions))
+	}
+
+	dust := createPolicyTransaction("Alice", 1)
+	assertPolicyReason(t, bc.AddTransactionToMempool(dust), blockchain.RejectDust)
+
+	if len(mp.Transactions) != 1 {
+		t.Errorf("Rejected transaction should not enter the mempool, got %d transactions", len(mp.Transactions))
+	}
+}
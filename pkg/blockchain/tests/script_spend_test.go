@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/script"
+)
+
+// createScriptSpend -> Signed spend of a height locked script, witness [<sig>]
+func createScriptSpend(t *testing.T, keyPair *CryptoGraphy.KeyPair, lockHeight int64) *blockchain.Transaction {
+	t.Helper()
+
+	heightLock, err := script.HeightLockScript(lockHeight, keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	tx := blockchain.NewScriptTransaction(heightLock, "bob", 1000)
+	tx.Fee = testFee
+	tx.SetWitness(keyPair.Sign(tx.SigHash(blockchain.DefaultChainId)))
+
+	return tx
+}
+
+func TestScriptSpend_Verify(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := createScriptSpend(t, keyPair, 1)
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Fatalf("Script spend should be valid: %v", err)
+	}
+
+	// The witness is not covered by the hash
+	hash := tx.Hash()
+	tx.SetWitness([]byte{})
+	if !bytes.Equal(hash, tx.Hash()) {
+		t.Error("Changing the witness should not change the tx hash")
+	}
+
+	if err := bc.VerifyTransaction(tx); err == nil {
+		t.Error("Expected an empty signature to fail the script")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(tx *blockchain.Transaction)
+	}{
+		{"Locked until a later height", func(tx *blockchain.Transaction) {
+			*tx = *createScriptSpend(t, keyPair, 5)
+		}},
+		{"Amount changed after signing", func(tx *blockchain.Transaction) { tx.Amount = 2000 }},
+		{"From is not the script address", func(tx *blockchain.Transaction) { tx.From = keyPair.Address }},
+		{"Key signature next to the script", func(tx *blockchain.Transaction) { _ = tx.Sign(keyPair, blockchain.DefaultChainId) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := createScriptSpend(t, keyPair, 1)
+			tt.modify(tx)
+
+			if err := bc.VerifyTransaction(tx); err == nil {
+				t.Error("Expected invalid script spend to be rejected")
+			}
+		})
+	}
+}
+
+func TestScriptSpend_BlockRoundTrip(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	tx := createScriptSpend(t, keyPair, 1)
+	fundAddress(t, db, tx.From, 5000)
+
+	block, err := appendBlock(t, bc, db, *tx)
+	if err != nil {
+		t.Fatalf("Script spend should be valid in block 1: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{
+		tx.From: 5000 - 1000 - testFee,
+		"bob":   1000,
+	})
+
+	loadedBlock, err := bc.GetBlockById(block.Id)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if loadedTx.Script != tx.Script || len(loadedTx.Witness) != 1 || loadedTx.Witness[0] != tx.Witness[0] {
+		t.Fatalf("Script and witness were not restored: %+v", loadedTx)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, block.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
@@ -0,0 +1,307 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+)
+
+func setupToken(t *testing.T, maxSupply, initialSupply uint64) (*blockchain.Blockchain, *database.Database, func(), *CryptoGraphy.KeyPair, *CryptoGraphy.KeyPair, string) {
+	t.Helper()
+
+	db, _, cleanup := setupTestDB(t)
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	bob, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 10_000)
+	fundAddress(t, db, bob.Address, 10_000)
+
+	issueTx := signTx(t, blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, maxSupply, initialSupply), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *issueTx); err != nil {
+		t.Fatalf("Issue transaction should be valid: %v", err)
+	}
+
+	return bc, db, cleanup, alice, bob, issueTx.LedgerId()
+}
+
+func assertTokenBalance(t *testing.T, db *database.Database, address, assetId string, expected uint64) {
+	t.Helper()
+
+	balance, err := db.GetTokenBalance(address, assetId)
+	if err != nil {
+		t.Fatalf("Failed to get token balance of %s: %v", address, err)
+	}
+
+	if balance != expected {
+		t.Errorf("Expected token balance %d for %s, got %d", expected, address, balance)
+	}
+}
+
+func TestToken_PayloadValidation(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	nativeAmount := blockchain.NewTokenTransferTransaction(alice.Address, "bob", "asset", 5)
+	nativeAmount.Amount = 5
+
+	testCases := []struct {
+		name string
+		tx   *blockchain.Transaction
+	}{
+		{"lower case symbol", blockchain.NewTokenIssueTransaction(alice.Address, "gold", 2, 1_000, 0)},
+		{"symbol too long", blockchain.NewTokenIssueTransaction(alice.Address, "ABCDEFGHIJKLM", 2, 1_000, 0)},
+		{"too many decimals", blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 19, 1_000, 0)},
+		{"zero max supply", blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, 0, 0)},
+		{"initial supply above max", blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, 1_000, 1_001)},
+		{"transfer without asset", blockchain.NewTokenTransferTransaction(alice.Address, "bob", "", 5)},
+		{"transfer of zero units", blockchain.NewTokenTransferTransaction(alice.Address, "bob", "asset", 0)},
+		{"transfer without recipient", blockchain.NewTokenTransferTransaction(alice.Address, "", "asset", 5)},
+		{"transfer with native amount", nativeAmount},
+		{"max supply above int64", blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, math.MaxInt64+1, 0)},
+		{"initial supply above int64", blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, math.MaxUint64, math.MaxUint64)},
+		{"mint above int64", blockchain.NewTokenMintTransaction(alice.Address, "bob", "asset", math.MaxInt64+1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signTx(t, tc.tx, alice, testFee)
+
+			if err := bc.VerifyTransaction(tc.tx); err == nil {
+				t.Error("Expected the token transaction to be rejected")
+			}
+		})
+	}
+
+	validIssue := signTx(t, blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 18, 1_000, 1_000), alice, testFee)
+	if err := bc.VerifyTransaction(validIssue); err != nil {
+		t.Errorf("Valid issue transaction rejected: %v", err)
+	}
+
+	largestIssue := signTx(t, blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, math.MaxInt64, math.MaxInt64), alice, testFee)
+	if err := bc.VerifyTransaction(largestIssue); err != nil {
+		t.Errorf("Issue of the largest supply rejected: %v", err)
+	}
+}
+
+func TestToken_IssueMintAndTransfer(t *testing.T) {
+	bc, db, cleanup, alice, bob, assetId := setupToken(t, 10_000, 1_000)
+	defer cleanup()
+
+	asset, err := bc.GetAsset(assetId)
+	if err != nil {
+		t.Fatalf("Failed to get asset: %v", err)
+	}
+
+	if asset.Symbol != "GOLD" || asset.Supply != 1_000 || asset.Issuer != alice.Address || asset.IssuerPublicKey != alice.GetPublicKeyHex() {
+		t.Fatalf("Unexpected asset: %+v", asset)
+	}
+
+	assertTokenBalance(t, db, alice.Address, assetId, 1_000)
+
+	mintTx := signTx(t, blockchain.NewTokenMintTransaction(alice.Address, bob.Address, assetId, 500), alice, testFee)
+	transferTx := signTx(t, blockchain.NewTokenTransferTransaction(alice.Address, bob.Address, assetId, 300), alice, testFee)
+	if _, err := appendBlock(t, bc, db, *mintTx, *transferTx); err != nil {
+		t.Fatalf("Mint and transfer should be valid: %v", err)
+	}
+
+	assertTokenBalance(t, db, alice.Address, assetId, 700)
+	assertTokenBalance(t, db, bob.Address, assetId, 800)
+
+	// Fees stay in native coins
+	assertBalances(t, db, map[string]uint64{alice.Address: 10_000 - 3*testFee, bob.Address: 10_000})
+
+	asset, err = bc.GetAsset(assetId)
+	if err != nil {
+		t.Fatalf("Failed to get asset: %v", err)
+	}
+
+	if asset.Supply != 1_500 {
+		t.Errorf("Expected supply 1500 after the mint, got %d", asset.Supply)
+	}
+
+	balances, err := bc.GetTokenBalances(bob.Address)
+	if err != nil {
+		t.Fatalf("Failed to get token balances: %v", err)
+	}
+
+	if len(balances) != 1 || balances[0].AssetId != assetId || balances[0].Symbol != "GOLD" || balances[0].Balance != 800 {
+		t.Errorf("Unexpected token balances: %+v", balances)
+	}
+}
+
+func TestToken_MintRules(t *testing.T) {
+	bc, db, cleanup, alice, bob, assetId := setupToken(t, 1_000, 900)
+	defer cleanup()
+
+	notIssuer := blockchain.NewTokenMintTransaction(bob.Address, bob.Address, assetId, 10)
+	notIssuer.PublicKey = bob.GetPublicKeyHex()
+	signTx(t, notIssuer, bob, bc.Mempool.CalculateFee(notIssuer))
+	if err := bc.ValidateTransaction(notIssuer); err == nil {
+		t.Error("Only the issuer should be able to mint")
+	}
+
+	overMax := signTx(t, blockchain.NewTokenMintTransaction(alice.Address, bob.Address, assetId, 101), alice, testFee)
+	if err := bc.ValidateTransaction(overMax); err == nil {
+		t.Error("Minting above the max supply should be rejected")
+	}
+
+	// A block carrying it anyway is refused when the ledger is updated
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	if err := bc.UpdateUserBalances(sqlTx, []blockchain.Transaction{*overMax}); err == nil {
+		t.Error("Ledger should refuse a mint above the max supply")
+	}
+	sqlTx.Rollback()
+
+	firstMint := blockchain.NewTokenMintTransaction(alice.Address, bob.Address, assetId, 60)
+	firstMint.PublicKey = alice.GetPublicKeyHex()
+	signTx(t, firstMint, alice, bc.Mempool.CalculateFee(firstMint))
+	if err := bc.AddTransactionToMempool(firstMint); err != nil {
+		t.Fatalf("Failed to add mint to mempool: %v", err)
+	}
+
+	secondMint := signTx(t, blockchain.NewTokenMintTransaction(alice.Address, alice.Address, assetId, 60), alice, firstMint.Fee)
+	if err := bc.ValidateTransaction(secondMint); err == nil {
+		t.Error("Pending mints should count against the max supply")
+	}
+
+	unknown := signTx(t, blockchain.NewTokenMintTransaction(alice.Address, bob.Address, "missing", 1), alice, testFee)
+	if err := bc.ValidateTransaction(unknown); !errors.Is(err, blockchain.ErrAssetNotFound) {
+		t.Errorf("Expected ErrAssetNotFound, got %v", err)
+	}
+}
+
+func TestToken_TransferBalance(t *testing.T) {
+	bc, _, cleanup, alice, bob, assetId := setupToken(t, 1_000, 100)
+	defer cleanup()
+
+	tooMuch := signTx(t, blockchain.NewTokenTransferTransaction(bob.Address, alice.Address, assetId, 1), bob, testFee)
+	if err := bc.ValidateTransaction(tooMuch); err == nil {
+		t.Error("Transfer without a token balance should be rejected")
+	}
+
+	firstTransfer := blockchain.NewTokenTransferTransaction(alice.Address, bob.Address, assetId, 70)
+	firstTransfer.PublicKey = alice.GetPublicKeyHex()
+	signTx(t, firstTransfer, alice, bc.Mempool.CalculateFee(firstTransfer))
+	if err := bc.AddTransactionToMempool(firstTransfer); err != nil {
+		t.Fatalf("Failed to add transfer to mempool: %v", err)
+	}
+
+	secondTransfer := signTx(t, blockchain.NewTokenTransferTransaction(alice.Address, bob.Address, assetId, 40), alice, firstTransfer.Fee)
+	if err := bc.ValidateTransaction(secondTransfer); err == nil {
+		t.Error("Pending transfers should count against the token balance")
+	}
+
+	balance, err := bc.GetTokenBalance(alice.Address, assetId)
+	if err != nil {
+		t.Fatalf("Failed to get token balance: %v", err)
+	}
+
+	if balance != 30 {
+		t.Errorf("Expected effective token balance 30, got %d", balance)
+	}
+}
+
+func TestToken_BlockRoundTrip(t *testing.T) {
+	bc, _, cleanup, _, _, assetId := setupToken(t, 1_000, 100)
+	defer cleanup()
+
+	originalBlock := bc.GetLatestBlock()
+
+	loadedBlock, err := bc.GetBlockById(originalBlock.Id)
+	if err != nil {
+		t.Fatalf("Failed to load block: %v", err)
+	}
+
+	loadedTx := loadedBlock.Transactions[1]
+	if loadedTx.Type != blockchain.TxTypeTokenIssue || loadedTx.Token == nil || loadedTx.Token.Symbol != "GOLD" {
+		t.Fatalf("Token payload was not restored: %+v", loadedTx)
+	}
+
+	if loadedTx.LedgerId() != assetId {
+		t.Errorf("Ledger id of the loaded issue %s should match %s", loadedTx.LedgerId(), assetId)
+	}
+
+	loadedBlock.MerkleRoot = nil
+	loadedBlock.ComputeMerkleRoot()
+	if !bytes.Equal(loadedBlock.MerkleRoot, originalBlock.MerkleRoot) {
+		t.Error("Merkle root of the loaded block should match the original")
+	}
+}
+
+func TestToken_UnapplicableTxDoesNotStallMining(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	alice, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	fundAddress(t, db, alice.Address, 100_000)
+
+	// Well above the minimum relay fee rate
+	const fee = 5_000
+
+	// Slipped past validation, the ledger cannot store its supply
+	oversized := signTx(t, blockchain.NewTokenIssueTransaction(alice.Address, "HUGE", 2, math.MaxUint64, 0), alice, fee)
+	valid := signTx(t, blockchain.NewTokenIssueTransaction(alice.Address, "GOLD", 2, 1_000, 1_000), alice, fee)
+
+	for _, tx := range []*blockchain.Transaction{oversized, valid} {
+		if err := bc.AddTransactionToMempool(tx); err != nil {
+			t.Fatalf("Failed to add transaction to mempool: %v", err)
+		}
+	}
+
+	block, err := bc.MineBlock(context.Background(), bc.Mempool, "miner")
+	if err != nil || block == nil {
+		t.Fatalf("Mining should not fail because of one tx: %v", err)
+	}
+
+	if len(block.Transactions) != 2 || block.Transactions[1].LedgerId() != valid.LedgerId() {
+		t.Fatalf("Expected the block to hold the coinbase and the valid tx, got %d txs", len(block.Transactions))
+	}
+
+	if bc.Mempool.HasTransaction(oversized.Hash().EncodeToString()) {
+		t.Error("A tx that cannot be applied should be dropped from the mempool")
+	}
+
+	assertTokenBalance(t, db, alice.Address, valid.LedgerId(), 1_000)
+}
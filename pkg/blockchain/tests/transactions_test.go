@@ -35,11 +35,11 @@ func TestTx_SignAndVerify(t *testing.T) {
 		Status:    "pending",
 	}
 
-	if err = tx.Sign(keyPair); err != nil {
+	if err = tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
 		t.Fatalf("Sign failed: %v", err)
 	}
 
-	if !tx.Verify() {
+	if !tx.Verify(blockchain.DefaultChainId) {
 		t.Error("Signature verification failed")
 	}
 }
@@ -55,7 +55,7 @@ func TestTx_Verify_InvalidSignature(t *testing.T) {
 		Signature: []byte("bad"),
 	}
 
-	if tx.Verify() {
+	if tx.Verify(blockchain.DefaultChainId) {
 		t.Error("Verify should fail for invalid signature")
 	}
 }
@@ -89,8 +89,9 @@ func TestTxSize(t *testing.T) {
 		Signature: []byte("bad"),
 	}
 
-	if size := tx.Size(); size != 70 {
-		t.Errorf("the size function is not working properly, got %d, want 70", size)
+	// The local status is not counted
+	if size := tx.Size(); size != 59 {
+		t.Errorf("the size function is not working properly, got %d, want 59", size)
 	}
 }
 
@@ -131,7 +132,7 @@ func TestTx_HashExcludesSignature(t *testing.T) {
 	hashBefore := tx.Hash()
 
 	// Sign transaction (this sets signature but public key is already set)
-	err = tx.Sign(keyPair)
+	err = tx.Sign(keyPair, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -161,7 +162,7 @@ func TestTx_SignWithHexKeys(t *testing.T) {
 		Status:    "pending",
 	}
 
-	err = tx.SignWithHexKeys(privateKeyHex, publicKeyHex)
+	err = tx.SignWithHexKeys(privateKeyHex, publicKeyHex, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to sign with hex keys: %v", err)
 	}
@@ -175,7 +176,7 @@ func TestTx_SignWithHexKeys(t *testing.T) {
 	}
 
 	// Verify the signature
-	if !tx.Verify() {
+	if !tx.Verify(blockchain.DefaultChainId) {
 		t.Error("Signature verification should pass")
 	}
 }
@@ -189,7 +190,7 @@ func TestTx_SignWithHexKeys_InvalidKeys(t *testing.T) {
 		Status:    "pending",
 	}
 
-	err := tx.SignWithHexKeys("invalid_private_key", "invalid_public_key")
+	err := tx.SignWithHexKeys("invalid_private_key", "invalid_public_key", blockchain.DefaultChainId)
 	if err == nil {
 		t.Error("Should return error for invalid hex keys")
 	}
@@ -205,7 +206,7 @@ func TestTx_VerifyWithoutSignature(t *testing.T) {
 	}
 
 	// Verify without signature should fail
-	if tx.Verify() {
+	if tx.Verify(blockchain.DefaultChainId) {
 		t.Error("Verification should fail without signature")
 	}
 }
@@ -221,7 +222,7 @@ func TestTx_VerifyWithoutPublicKey(t *testing.T) {
 	}
 
 	// Verify without public key should fail
-	if tx.Verify() {
+	if tx.Verify(blockchain.DefaultChainId) {
 		t.Error("Verification should fail without public key")
 	}
 }
@@ -358,7 +359,7 @@ func TestTx_SignSetsPublicKey(t *testing.T) {
 		Timestamp: 1234567890,
 	}
 
-	err = tx.Sign(keyPair)
+	err = tx.Sign(keyPair, blockchain.DefaultChainId)
 	if err != nil {
 		t.Fatalf("Failed to sign transaction: %v", err)
 	}
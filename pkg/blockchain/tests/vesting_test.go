@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+func TestVesting_VestedAt(t *testing.T) {
+	schedule := blockchain.VestingSchedule{Amount: 1_000, StartHeight: 10, EndHeight: 20}
+
+	testCases := []struct {
+		height int64
+		vested uint64
+	}{
+		{0, 0},
+		{10, 0},
+		{11, 100},
+		{15, 500},
+		{19, 900},
+		{20, 1_000},
+		{50, 1_000},
+	}
+
+	for _, tc := range testCases {
+		if vested := schedule.VestedAt(tc.height); vested != tc.vested {
+			t.Errorf("Expected %d vested at height %d, got %d", tc.vested, tc.height, vested)
+		}
+	}
+
+	large := blockchain.VestingSchedule{Amount: math.MaxUint64, StartHeight: 0, EndHeight: 4}
+	if vested := large.VestedAt(2); vested != math.MaxUint64/2 {
+		t.Errorf("Expected half of the max amount without overflow, got %d", vested)
+	}
+}
+
+func TestVesting_GenesisAccount(t *testing.T) {
+	db, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	founder, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId, blockchain.GenesisVesting{
+		Address:     founder.Address,
+		Amount:      100_000,
+		StartHeight: 0,
+		EndHeight:   10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{founder.Address: 100_000})
+
+	// The next block is height 1, a tenth has vested
+	balance, err := bc.GetBalance(founder.Address)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+
+	if balance != 10_000 {
+		t.Errorf("Expected 10000 spendable, got %d", balance)
+	}
+
+	vesting, err := bc.GetVestingBalance(founder.Address, 1)
+	if err != nil {
+		t.Fatalf("Failed to get vesting balance: %v", err)
+	}
+
+	if vesting.Vested != 10_000 || vesting.Unvested != 90_000 {
+		t.Errorf("Unexpected vesting balance: %+v", vesting)
+	}
+
+	tooMuch := signTx(t, blockchain.NewTransaction(founder.Address, "bob", 10_000, utils.GetTimestamp()), founder, testFee)
+	if err := bc.ValidateTransaction(tooMuch); err == nil {
+		t.Error("Spending unvested coins should be rejected by the mempool")
+	}
+
+	// A block carrying it anyway is refused when the balances are updated
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	if err := bc.UpdateUserBalances(sqlTx, []blockchain.Transaction{*tooMuch}); err == nil {
+		t.Error("Block spending unvested coins should be rejected")
+	}
+	sqlTx.Rollback()
+
+	spend := signTx(t, blockchain.NewTransaction(founder.Address, "bob", 10_000-testFee, utils.GetTimestamp()), founder, testFee)
+	if err := bc.ValidateTransaction(spend); err != nil {
+		t.Errorf("Spending vested coins should be accepted: %v", err)
+	}
+
+	if _, err := appendBlock(t, bc, db, *spend); err != nil {
+		t.Fatalf("Block spending vested coins should be valid: %v", err)
+	}
+
+	assertBalances(t, db, map[string]uint64{founder.Address: 90_000, "bob": 10_000 - testFee})
+}
+
+func TestVesting_CreateTransaction(t *testing.T) {
+	bc, db, cleanup, alice, bob := setupName(t, "alice")
+	defer cleanup()
+
+	invalid := signTx(t, blockchain.NewVestingTransaction(alice.Address, bob.Address, 5_000, 10, 10), alice, testFee)
+	if err := bc.VerifyTransaction(invalid); err == nil {
+		t.Error("Schedule ending at its start should be rejected")
+	}
+
+	startHeight := bc.GetHeight() + 5
+	vestTx := signTx(t, blockchain.NewVestingTransaction(alice.Address, bob.Address, 5_000, startHeight, startHeight+5), alice, testFee)
+	if err := bc.ValidateTransaction(vestTx); err != nil {
+		t.Fatalf("Vesting transaction should be valid: %v", err)
+	}
+
+	if _, err := appendBlock(t, bc, db, *vestTx); err != nil {
+		t.Fatalf("Vesting transaction should be mined: %v", err)
+	}
+
+	schedules, err := bc.GetVestingSchedules(bob.Address)
+	if err != nil {
+		t.Fatalf("Failed to get vesting schedules: %v", err)
+	}
+
+	if len(schedules) != 1 || schedules[0].Amount != 5_000 || schedules[0].CreatedTx != vestTx.LedgerId() {
+		t.Fatalf("Unexpected vesting schedules: %+v", schedules)
+	}
+
+	// bob's own 100_000 stay spendable, the vested coins do not until startHeight
+	balance, err := bc.GetBalance(bob.Address)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+
+	if balance != 100_000 {
+		t.Errorf("Expected 100000 spendable, got %d", balance)
+	}
+}
+
+func TestVesting_CoinbaseOnlyAtGenesis(t *testing.T) {
+	bc, _, cleanup, alice, _ := setupName(t, "alice")
+	defer cleanup()
+
+	latest := bc.GetLatestBlock()
+	block := &blockchain.Block{
+		Id:        latest.Id + 1,
+		PrevHash:  latest.Hash,
+		Timestamp: utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{
+			*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward),
+			*blockchain.NewVestingTransaction("", alice.Address, 1_000, 0, 10),
+		},
+	}
+	block.Transactions[1].IsCoinbase = true
+
+	if err := bc.VerifyBlockTransactions(block); err == nil {
+		t.Error("A vesting coinbase outside the genesis block should be rejected")
+	}
+}
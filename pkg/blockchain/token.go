@@ -0,0 +1,333 @@
+package blockchain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// MaxTokenDecimals -> Upper bound of the decimals an asset can declare
+const MaxTokenDecimals = 18
+
+var ErrAssetNotFound = errors.New("asset not found")
+
+var tokenSymbolPattern = regexp.MustCompile(`^[A-Z0-9]{1,12}$`)
+
+// TokenPayload -> Fields of the token_issue, token_mint and token_transfer tx types.
+// The native Amount of a token tx is zero, it still pays its Fee in native coins.
+type TokenPayload struct {
+	AssetId   string `json:"asset_id,omitempty"`   // mint, transfer: LedgerId of the issue tx
+	Symbol    string `json:"symbol,omitempty"`     // issue
+	Decimals  uint8  `json:"decimals,omitempty"`   // issue
+	MaxSupply uint64 `json:"max_supply,omitempty"` // issue
+	Amount    uint64 `json:"amount,omitempty"`     // issue: initial supply, mint: new units, transfer: units sent
+}
+
+// Asset -> A token issued on-chain, as kept in the ledger
+type Asset struct {
+	Id              string `json:"id"`
+	Symbol          string `json:"symbol"`
+	Decimals        uint8  `json:"decimals"`
+	MaxSupply       uint64 `json:"max_supply"`
+	Supply          uint64 `json:"supply"`
+	Issuer          string `json:"issuer"`
+	IssuerPublicKey string `json:"issuer_public_key,omitempty"`
+}
+
+// TokenBalance -> Units of one asset held by an address
+type TokenBalance struct {
+	AssetId string `json:"asset_id"`
+	Symbol  string `json:"symbol"`
+	Balance uint64 `json:"balance"`
+}
+
+// NewTokenIssueTransaction -> Unsigned tx defining a new asset issued by `from`,
+// which receives the initial supply
+func NewTokenIssueTransaction(from, symbol string, decimals uint8, maxSupply, initialSupply uint64) *Transaction {
+	return &Transaction{
+		From:      from,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeTokenIssue,
+		Token: &TokenPayload{
+			Symbol:    symbol,
+			Decimals:  decimals,
+			MaxSupply: maxSupply,
+			Amount:    initialSupply,
+		},
+	}
+}
+
+// NewTokenMintTransaction -> Unsigned tx minting amount new units of an asset to `to`
+func NewTokenMintTransaction(from, to, assetId string, amount uint64) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeTokenMint,
+		Token: &TokenPayload{
+			AssetId: assetId,
+			Amount:  amount,
+		},
+	}
+}
+
+// NewTokenTransferTransaction -> Unsigned tx sending amount units of an asset from `from` to `to`
+func NewTokenTransferTransaction(from, to, assetId string, amount uint64) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeTokenTransfer,
+		Token: &TokenPayload{
+			AssetId: assetId,
+			Amount:  amount,
+		},
+	}
+}
+
+func (tx *Transaction) IsToken() bool {
+	switch tx.Type {
+	case TxTypeTokenIssue, TxTypeTokenMint, TxTypeTokenTransfer:
+		return true
+	default:
+		return false
+	}
+}
+
+func (tx *Transaction) validateTokenPayload() error {
+	if tx.Token == nil {
+		return fmt.Errorf("%s transaction needs a token payload", tx.Type)
+	}
+
+	if tx.IsBatch() {
+		return fmt.Errorf("%s transaction cannot have outputs", tx.Type)
+	}
+
+	if tx.Amount != 0 {
+		return fmt.Errorf("%s transaction moves tokens, its native amount must be zero", tx.Type)
+	}
+
+	// The ledger stores token units as signed 64-bit integers
+	if tx.Token.Amount > math.MaxInt64 || tx.Token.MaxSupply > math.MaxInt64 {
+		return fmt.Errorf("token amount and max supply cannot exceed %d", int64(math.MaxInt64))
+	}
+
+	if tx.Type == TxTypeTokenIssue {
+		if !tokenSymbolPattern.MatchString(tx.Token.Symbol) {
+			return errors.New("token symbol must be 1-12 upper case letters or digits")
+		}
+
+		if tx.Token.Decimals > MaxTokenDecimals {
+			return fmt.Errorf("token decimals cannot exceed %d", MaxTokenDecimals)
+		}
+
+		if tx.Token.MaxSupply == 0 {
+			return errors.New("token max supply must be positive")
+		}
+
+		if tx.Token.Amount > tx.Token.MaxSupply {
+			return errors.New("initial supply exceeds the max supply")
+		}
+
+		if tx.Token.AssetId != "" || tx.To != "" {
+			return errors.New("token issue must not set asset_id or 'to'")
+		}
+
+		return nil
+	}
+
+	// mint and transfer
+	if tx.Token.AssetId == "" {
+		return errors.New("asset_id is required")
+	}
+
+	if tx.Token.Amount == 0 {
+		return errors.New("token amount must be positive")
+	}
+
+	if tx.To == "" {
+		return fmt.Errorf("%s transaction needs a recipient", tx.Type)
+	}
+
+	if tx.Token.Symbol != "" || tx.Token.Decimals != 0 || tx.Token.MaxSupply != 0 {
+		return fmt.Errorf("%s transaction must not set symbol, decimals or max_supply", tx.Type)
+	}
+
+	return nil
+}
+
+func (bc *Blockchain) GetAsset(assetId string) (*Asset, error) {
+	dbAsset, err := bc.Database.GetAsset(assetId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAssetNotFound
+		}
+
+		return nil, err
+	}
+
+	return parseDBAsset(dbAsset), nil
+}
+
+func (bc *Blockchain) GetAssets() ([]Asset, error) {
+	dbAssets, err := bc.Database.GetAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]Asset, 0, len(dbAssets))
+	for idx := range dbAssets {
+		assets = append(assets, *parseDBAsset(&dbAssets[idx]))
+	}
+
+	return assets, nil
+}
+
+// GetTokenBalance -> Confirmed balance minus the units pending in outgoing transfers
+func (bc *Blockchain) GetTokenBalance(address, assetId string) (uint64, error) {
+	confirmedBalance, err := bc.Database.GetTokenBalance(address, assetId)
+	if err != nil {
+		return 0, err
+	}
+
+	var pendingOutgoing uint64
+	for _, tx := range bc.Mempool.GetTransactionsCopy() {
+		if tx.Type == TxTypeTokenTransfer && tx.From == address && tx.Token.AssetId == assetId {
+			pendingOutgoing += tx.Token.Amount
+		}
+	}
+
+	if confirmedBalance < pendingOutgoing {
+		return 0, nil
+	}
+
+	return confirmedBalance - pendingOutgoing, nil
+}
+
+// GetTokenBalances -> Confirmed balances of every asset held by address
+func (bc *Blockchain) GetTokenBalances(address string) ([]TokenBalance, error) {
+	dbBalances, err := bc.Database.GetTokenBalances(address)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]TokenBalance, 0, len(dbBalances))
+	for _, dbBalance := range dbBalances {
+		asset, err := bc.GetAsset(dbBalance.AssetId)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, TokenBalance{
+			AssetId: dbBalance.AssetId,
+			Symbol:  asset.Symbol,
+			Balance: dbBalance.Balance,
+		})
+	}
+
+	return balances, nil
+}
+
+// validateToken -> Checks a mint or transfer against the asset ledger and the mempool
+func (bc *Blockchain) validateToken(tx *Transaction) error {
+	if tx.Type == TxTypeTokenIssue {
+		return nil
+	}
+
+	asset, err := bc.GetAsset(tx.Token.AssetId)
+	if err != nil {
+		return err
+	}
+
+	if tx.Type == TxTypeTokenTransfer {
+		balance, err := bc.GetTokenBalance(tx.From, asset.Id)
+		if err != nil {
+			return err
+		}
+
+		if balance < tx.Token.Amount {
+			return fmt.Errorf("%s balance is insufficient", asset.Symbol)
+		}
+
+		return nil
+	}
+
+	if tx.From != asset.Issuer {
+		return fmt.Errorf("only the issuer can mint %s", asset.Symbol)
+	}
+
+	pendingMints := tx.Token.Amount
+	for _, pending := range bc.Mempool.GetTransactionsCopy() {
+		if pending.Type == TxTypeTokenMint && pending.Token.AssetId == asset.Id {
+			pendingMints += pending.Token.Amount
+		}
+	}
+
+	if pendingMints > asset.MaxSupply-asset.Supply {
+		return fmt.Errorf("minting %d %s exceeds the max supply of %d",
+			tx.Token.Amount, asset.Symbol, asset.MaxSupply)
+	}
+
+	return nil
+}
+
+// applyToken -> Updates the asset ledger and token balances for a mined token tx
+func (bc *Blockchain) applyToken(sqlTx *sql.Tx, tx *Transaction) error {
+	switch tx.Type {
+	case TxTypeTokenIssue:
+		assetId := tx.LedgerId()
+
+		err := bc.Database.AddAsset(sqlTx, database.DBAssetSchema{
+			AssetId:         assetId,
+			Symbol:          tx.Token.Symbol,
+			Decimals:        tx.Token.Decimals,
+			MaxSupply:       tx.Token.MaxSupply,
+			Supply:          tx.Token.Amount,
+			Issuer:          tx.From,
+			IssuerPublicKey: tx.PublicKey,
+		})
+		if err != nil {
+			return err
+		}
+
+		if tx.Token.Amount == 0 {
+			return nil
+		}
+
+		return bc.Database.IncreaseTokenBalance(sqlTx, tx.From, assetId, tx.Token.Amount)
+	case TxTypeTokenMint:
+		if err := bc.Database.IncreaseAssetSupply(sqlTx, tx.Token.AssetId, tx.From, tx.Token.Amount); err != nil {
+			return err
+		}
+
+		return bc.Database.IncreaseTokenBalance(sqlTx, tx.To, tx.Token.AssetId, tx.Token.Amount)
+	case TxTypeTokenTransfer:
+		if err := bc.Database.DecreaseTokenBalance(sqlTx, tx.From, tx.Token.AssetId, tx.Token.Amount); err != nil {
+			return err
+		}
+
+		return bc.Database.IncreaseTokenBalance(sqlTx, tx.To, tx.Token.AssetId, tx.Token.Amount)
+	default:
+		return fmt.Errorf("%s is not a token transaction", tx.Type)
+	}
+}
+
+func parseDBAsset(dbAsset *database.DBAssetSchema) *Asset {
+	return &Asset{
+		Id:              dbAsset.AssetId,
+		Symbol:          dbAsset.Symbol,
+		Decimals:        dbAsset.Decimals,
+		MaxSupply:       dbAsset.MaxSupply,
+		Supply:          dbAsset.Supply,
+		Issuer:          dbAsset.Issuer,
+		IssuerPublicKey: dbAsset.IssuerPublicKey,
+	}
+}
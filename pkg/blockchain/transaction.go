@@ -2,17 +2,52 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/script"
 	"github.com/Nikolat27/simple_blockchain/pkg/utils"
 )
 
 const CoinbaseTxFee = 0
 
+// MaxTxDataSize -> Upper bound (in bytes) of the free-form Data payload
+const MaxTxDataSize = 256
+
+// MaxTxOutputs -> Upper bound of recipients in a single batch transfer
+const MaxTxOutputs = 1000
+
+// Transaction types, a plain transfer has none
+const (
+	TxTypeHTLCLock   = "htlc_lock"
+	TxTypeHTLCClaim  = "htlc_claim"
+	TxTypeHTLCRefund = "htlc_refund"
+
+	TxTypeChannelOpen   = "channel_open"
+	TxTypeChannelClose  = "channel_close"
+	TxTypeChannelSettle = "channel_settle"
+
+	TxTypeTokenIssue    = "token_issue"
+	TxTypeTokenMint     = "token_mint"
+	TxTypeTokenTransfer = "token_transfer"
+
+	TxTypeNameRegister = "name_register"
+	TxTypeNameRenew    = "name_renew"
+	TxTypeNameTransfer = "name_transfer"
+
+	TxTypeVestingCreate = "vesting_create"
+)
+
+// LockTimeThreshold -> Lock times below it are block heights, from it on unix milliseconds
+const LockTimeThreshold = 500_000_000
+
 type Transaction struct {
 	From       string `json:"from,omitempty"`
 	To         string `json:"to"`
@@ -23,6 +58,47 @@ type Transaction struct {
 	Fee        uint64 `json:"fee"`
 	Status     string `json:"status"`
 	IsCoinbase bool   `json:"is_coinbase"`
+
+	// Not valid in a block below this height (or median time, see LockTimeThreshold)
+	LockTime int64 `json:"lock_time,omitempty"`
+
+	// Free-form payload, e.g. payment references, covered by the signature and the txid
+	Data string `json:"data,omitempty"`
+
+	// Batch transfer, one entry per recipient. To must be empty and Amount is their sum.
+	Outputs []TxOutput `json:"outputs,omitempty"`
+
+	// Fee sponsorship, FeePayer pays Fee instead of From and co-signs the tx
+	FeePayer          string `json:"fee_payer,omitempty"`
+	FeePayerPublicKey string `json:"fee_payer_public_key,omitempty"` // hex
+	FeePayerSignature []byte `json:"fee_payer_signature,omitempty"`  // not part of the hash
+
+	// Type selects the rules for the payload below, empty for a plain transfer
+	Type    string          `json:"type,omitempty"`
+	HTLC    *HTLCPayload    `json:"htlc,omitempty"`
+	Channel *ChannelPayload `json:"channel,omitempty"`
+	Token   *TokenPayload   `json:"token,omitempty"`
+	Name    *NamePayload    `json:"name,omitempty"`
+	Vesting *VestingPayload `json:"vesting,omitempty"`
+
+	// Script spending, From is the address of Script, which runs on top of the Witness
+	Script  string   `json:"script,omitempty"`  // hex
+	Witness []string `json:"witness,omitempty"` // hex stack items, not part of the hash
+
+	// M-of-N multisig spending, From is the multisig address
+	MultisigKeys []string            `json:"multisig_keys,omitempty"` // sorted public keys (hex)
+	Threshold    int                 `json:"threshold,omitempty"`     // M
+	Signatures   []MultisigSignature `json:"signatures,omitempty"`    // not part of the hash
+}
+
+type TxOutput struct {
+	To     string `json:"to"`
+	Amount uint64 `json:"amount"`
+}
+
+type MultisigSignature struct {
+	PublicKey string `json:"public_key"` // hex
+	Signature []byte `json:"signature"`
 }
 
 type TxHash []byte
@@ -35,6 +111,9 @@ func (tx *Transaction) Hash() TxHash {
 	txCopy := *tx
 
 	txCopy.Signature = nil
+	txCopy.Signatures = nil
+	txCopy.FeePayerSignature = nil
+	txCopy.Witness = nil
 	// Keep PublicKey for hashing as it's part of the transaction data
 
 	data, _ := json.Marshal(txCopy)
@@ -42,35 +121,405 @@ func (tx *Transaction) Hash() TxHash {
 	return hash[:]
 }
 
-func (tx *Transaction) Sign(keyPair *CryptoGraphy.KeyPair) error {
+func (tx *Transaction) Sign(keyPair *CryptoGraphy.KeyPair, chainId string) error {
 	tx.PublicKey = keyPair.GetPublicKeyHex() // Set PublicKey BEFORE hashing
-	hash := tx.Hash()
+	hash := tx.SigHash(chainId)
 
 	tx.Signature = keyPair.Sign(hash)
 	return nil
 }
 
-func (tx *Transaction) SignWithHexKeys(privateKeyHex, publicKeyHex string) error {
+func (tx *Transaction) SignWithHexKeys(privateKeyHex, publicKeyHex, chainId string) error {
 	keyPair, err := CryptoGraphy.LoadKeyPairFromHex(privateKeyHex, publicKeyHex)
 	if err != nil {
 		return err
 	}
 
 	tx.PublicKey = keyPair.GetPublicKeyHex()
-	hash := tx.Hash()
+	hash := tx.SigHash(chainId)
 	tx.Signature = keyPair.Sign(hash)
 	return nil
 }
 
-func (tx *Transaction) Verify() bool {
+// Verify -> Checks the sender's signature(s) and, if the tx is sponsored, the fee payer's,
+// all over the SigHash of chainId. Script spends only pass if they carry no key
+// signature, their script runs in VerifyScript.
+func (tx *Transaction) Verify(chainId string) bool {
+	if tx.HasFeePayer() && !tx.verifyFeePayerSignature(chainId) {
+		return false
+	}
+
+	if tx.IsMultisig() {
+		return tx.verifyMultisig(chainId)
+	}
+
+	// The script checks its own signatures, see VerifyScript
+	if tx.IsScript() {
+		return tx.PublicKey == "" && tx.Signature == nil
+	}
+
 	if tx.Signature == nil || tx.PublicKey == "" {
 		return false
 	}
 
-	hash := tx.Hash()
+	hash := tx.SigHash(chainId)
 	return CryptoGraphy.VerifySignature(tx.PublicKey, hash, tx.Signature)
 }
 
+func (tx *Transaction) HasFeePayer() bool {
+	return tx.FeePayer != ""
+}
+
+// FeeSource -> Address whose balance pays the fee
+func (tx *Transaction) FeeSource() string {
+	if tx.HasFeePayer() {
+		return tx.FeePayer
+	}
+
+	return tx.From
+}
+
+// SignAsFeePayer -> Co-signs the tx as its sponsor. FeePayer and FeePayerPublicKey
+// must be set before the sender signs, since both are covered by the hash.
+func (tx *Transaction) SignAsFeePayer(keyPair *CryptoGraphy.KeyPair, chainId string) error {
+	if !tx.HasFeePayer() {
+		return errors.New("transaction has no fee payer")
+	}
+
+	if keyPair.GetPublicKeyHex() != tx.FeePayerPublicKey {
+		return errors.New("key does not belong to the fee payer")
+	}
+
+	tx.FeePayerSignature = keyPair.Sign(tx.SigHash(chainId))
+	return nil
+}
+
+func (tx *Transaction) SignAsFeePayerWithHexKeys(privateKeyHex, publicKeyHex, chainId string) error {
+	keyPair, err := CryptoGraphy.LoadKeyPairFromHex(privateKeyHex, publicKeyHex)
+	if err != nil {
+		return err
+	}
+
+	return tx.SignAsFeePayer(keyPair, chainId)
+}
+
+func (tx *Transaction) verifyFeePayerSignature(chainId string) bool {
+	if tx.FeePayerSignature == nil || tx.FeePayerPublicKey == "" {
+		return false
+	}
+
+	return CryptoGraphy.VerifySignature(tx.FeePayerPublicKey, tx.SigHash(chainId), tx.FeePayerSignature)
+}
+
+// VerifyFeePayer -> The FeePayer address must belong to the key that co-signed the tx
+func (tx *Transaction) VerifyFeePayer() error {
+	if !tx.HasFeePayer() {
+		if tx.FeePayerPublicKey != "" || len(tx.FeePayerSignature) > 0 {
+			return fmt.Errorf("transaction %x has a fee payer signature but no fee payer", tx.Hash())
+		}
+
+		return nil
+	}
+
+	if tx.IsCoinbase {
+		return errors.New("coinbase transaction cannot have a fee payer")
+	}
+
+	derivedAddress, err := CryptoGraphy.DeriveAddressFromPublicKey(tx.FeePayerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	if derivedAddress != tx.FeePayer {
+		return fmt.Errorf("transaction %x fee payer address mismatch", tx.Hash())
+	}
+
+	return nil
+}
+
+// IsFinal -> Whether the tx may be included in a block at this height, whose
+// previous blocks have the given median time
+func (tx *Transaction) IsFinal(height, medianTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	if tx.LockTime < LockTimeThreshold {
+		return height >= tx.LockTime
+	}
+
+	return medianTime >= tx.LockTime
+}
+
+func (tx *Transaction) IsBatch() bool {
+	return len(tx.Outputs) > 0
+}
+
+// SumOutputs -> Total amount of a batch, errors on overflow
+func SumOutputs(outputs []TxOutput) (uint64, error) {
+	var total uint64
+	for _, output := range outputs {
+		if total+output.Amount < total {
+			return 0, errors.New("sum of output amounts overflows")
+		}
+
+		total += output.Amount
+	}
+
+	return total, nil
+}
+
+// Credits -> Every (recipient, amount) pair the tx pays out
+func (tx *Transaction) Credits() []TxOutput {
+	if tx.IsBatch() {
+		return tx.Outputs
+	}
+
+	if tx.To == "" {
+		return nil
+	}
+
+	return []TxOutput{{To: tx.To, Amount: tx.Amount}}
+}
+
+// validateOutputs -> Every output of a batch needs a recipient and a non-zero
+// amount, and Amount must equal their sum
+func (tx *Transaction) validateOutputs() error {
+	if !tx.IsBatch() {
+		return nil
+	}
+
+	if tx.IsCoinbase {
+		return errors.New("coinbase transaction cannot have outputs")
+	}
+
+	if tx.To != "" {
+		return errors.New("batch transaction must not set 'to'")
+	}
+
+	if len(tx.Outputs) > MaxTxOutputs {
+		return fmt.Errorf("batch transaction has more than %d outputs", MaxTxOutputs)
+	}
+
+	for idx, output := range tx.Outputs {
+		if output.To == "" {
+			return fmt.Errorf("output %d has no recipient", idx)
+		}
+
+		if output.Amount == 0 {
+			return fmt.Errorf("output %d has zero amount", idx)
+		}
+	}
+
+	total, err := SumOutputs(tx.Outputs)
+	if err != nil {
+		return err
+	}
+
+	if total != tx.Amount {
+		return fmt.Errorf("amount %d does not match the sum of outputs %d", tx.Amount, total)
+	}
+
+	return nil
+}
+
+// validateType -> Stateless checks of the tx type and its payload
+func (tx *Transaction) validateType() error {
+	if tx.payloadCount() > 1 {
+		return errors.New("transaction carries more than one payload")
+	}
+
+	switch tx.Type {
+	case "":
+		if tx.payloadCount() != 0 {
+			return errors.New("plain transfer must not carry a payload")
+		}
+
+		return nil
+	case TxTypeHTLCLock, TxTypeHTLCClaim, TxTypeHTLCRefund:
+		return tx.validateHTLCPayload()
+	case TxTypeChannelOpen, TxTypeChannelClose, TxTypeChannelSettle:
+		return tx.validateChannelPayload()
+	case TxTypeTokenIssue, TxTypeTokenMint, TxTypeTokenTransfer:
+		return tx.validateTokenPayload()
+	case TxTypeNameRegister, TxTypeNameRenew, TxTypeNameTransfer:
+		return tx.validateNamePayload()
+	case TxTypeVestingCreate:
+		return tx.validateVestingPayload()
+	default:
+		return fmt.Errorf("unknown transaction type %q", tx.Type)
+	}
+}
+
+// payloadCount -> Number of type specific payloads set on the tx
+func (tx *Transaction) payloadCount() int {
+	count := 0
+	for _, isSet := range []bool{tx.HTLC != nil, tx.Channel != nil, tx.Token != nil, tx.Name != nil, tx.Vesting != nil} {
+		if isSet {
+			count++
+		}
+	}
+
+	return count
+}
+
+// hasNativeAmount -> Whether Amount moves native coins from the sender. Settlements
+// pay out of a ledger entry, token txs move tokens and name transfers move a name,
+// their Amount is zero.
+func (tx *Transaction) hasNativeAmount() bool {
+	return !tx.IsSettlement() && !tx.IsToken() && tx.Type != TxTypeNameTransfer
+}
+
+// encodePayload -> JSON of the type specific payload, as stored in the database
+func (tx *Transaction) encodePayload() (string, error) {
+	var payload any
+	switch {
+	case tx.HTLC != nil:
+		payload = tx.HTLC
+	case tx.Channel != nil:
+		payload = tx.Channel
+	case tx.Token != nil:
+		payload = tx.Token
+	case tx.Name != nil:
+		payload = tx.Name
+	case tx.Vesting != nil:
+		payload = tx.Vesting
+	default:
+		return "", nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// decodePayload -> Restores the payload of tx.Type from its stored JSON
+func (tx *Transaction) decodePayload(payload string) error {
+	if payload == "" {
+		return nil
+	}
+
+	switch tx.Type {
+	case TxTypeHTLCLock, TxTypeHTLCClaim, TxTypeHTLCRefund:
+		tx.HTLC = &HTLCPayload{}
+		return json.Unmarshal([]byte(payload), tx.HTLC)
+	case TxTypeChannelOpen, TxTypeChannelClose, TxTypeChannelSettle:
+		tx.Channel = &ChannelPayload{}
+		return json.Unmarshal([]byte(payload), tx.Channel)
+	case TxTypeTokenIssue, TxTypeTokenMint, TxTypeTokenTransfer:
+		tx.Token = &TokenPayload{}
+		return json.Unmarshal([]byte(payload), tx.Token)
+	case TxTypeNameRegister, TxTypeNameRenew, TxTypeNameTransfer:
+		tx.Name = &NamePayload{}
+		return json.Unmarshal([]byte(payload), tx.Name)
+	case TxTypeVestingCreate:
+		tx.Vesting = &VestingPayload{}
+		return json.Unmarshal([]byte(payload), tx.Vesting)
+	default:
+		return fmt.Errorf("unknown transaction type %q", tx.Type)
+	}
+}
+
+func (tx *Transaction) IsMultisig() bool {
+	return len(tx.MultisigKeys) > 0
+}
+
+// AddMultisigSignature -> Adds one key holder's partial signature
+func (tx *Transaction) AddMultisigSignature(keyPair *CryptoGraphy.KeyPair, chainId string) error {
+	if !tx.IsMultisig() {
+		return errors.New("transaction is not a multisig transaction")
+	}
+
+	pubKeyHex := keyPair.GetPublicKeyHex()
+	if !slices.Contains(tx.MultisigKeys, pubKeyHex) {
+		return errors.New("public key is not part of the multisig key set")
+	}
+
+	for _, sig := range tx.Signatures {
+		if sig.PublicKey == pubKeyHex {
+			return errors.New("public key has already signed this transaction")
+		}
+	}
+
+	tx.Signatures = append(tx.Signatures, MultisigSignature{
+		PublicKey: pubKeyHex,
+		Signature: keyPair.Sign(tx.SigHash(chainId)),
+	})
+
+	return nil
+}
+
+func (tx *Transaction) AddMultisigSignatureWithHexKeys(privateKeyHex, publicKeyHex, chainId string) error {
+	keyPair, err := CryptoGraphy.LoadKeyPairFromHex(privateKeyHex, publicKeyHex)
+	if err != nil {
+		return err
+	}
+
+	return tx.AddMultisigSignature(keyPair, chainId)
+}
+
+// verifyMultisig -> At least Threshold distinct key holders signed, and every signature is valid
+func (tx *Transaction) verifyMultisig(chainId string) bool {
+	if tx.Threshold < 1 || tx.Threshold > len(tx.MultisigKeys) ||
+		len(tx.MultisigKeys) > CryptoGraphy.MaxMultisigKeys {
+		return false
+	}
+
+	hash := tx.SigHash(chainId)
+	signers := make(map[string]bool, len(tx.Signatures))
+
+	for _, sig := range tx.Signatures {
+		if !slices.Contains(tx.MultisigKeys, sig.PublicKey) || signers[sig.PublicKey] {
+			return false
+		}
+
+		if !CryptoGraphy.VerifySignature(sig.PublicKey, hash, sig.Signature) {
+			return false
+		}
+
+		signers[sig.PublicKey] = true
+	}
+
+	return len(signers) >= tx.Threshold
+}
+
+// SenderAddress -> Address derived from the tx's key material
+func (tx *Transaction) SenderAddress() (string, error) {
+	if tx.IsMultisig() {
+		return CryptoGraphy.DeriveMultisigAddress(tx.MultisigKeys, tx.Threshold)
+	}
+
+	if tx.IsScript() {
+		spendScript, err := script.Decode(tx.Script)
+		if err != nil {
+			return "", err
+		}
+
+		return spendScript.Address(), nil
+	}
+
+	return CryptoGraphy.DeriveAddressFromPublicKey(tx.PublicKey)
+}
+
+// VerifySender -> The From address must belong to the keys that signed the tx
+func (tx *Transaction) VerifySender() error {
+	derivedAddress, err := tx.SenderAddress()
+	if err != nil {
+		return err
+	}
+
+	if derivedAddress != tx.From {
+		return fmt.Errorf("transaction %x sender address mismatch", tx.Hash())
+	}
+
+	return nil
+}
+
+// Size -> Serialized size of the tx in bytes. Base fees and block limits depend on
+// it, so it leaves out Status, which differs between nodes.
 func (tx *Transaction) Size() int {
 	var buf bytes.Buffer
 
@@ -101,9 +550,6 @@ func (tx *Transaction) Size() int {
 	// Fee is uint64, write 8 bytes
 	_ = binary.Write(&buf, binary.BigEndian, tx.Fee)
 
-	// Status string
-	writeString(tx.Status)
-
 	// Bool as single byte
 	if tx.IsCoinbase {
 		buf.WriteByte(1)
@@ -111,9 +557,182 @@ func (tx *Transaction) Size() int {
 		buf.WriteByte(0)
 	}
 
+	if tx.LockTime != 0 {
+		_ = binary.Write(&buf, binary.BigEndian, tx.LockTime) // int64
+	}
+
+	if tx.Data != "" {
+		writeString(tx.Data)
+	}
+
+	for _, output := range tx.Outputs {
+		writeString(output.To)
+		_ = binary.Write(&buf, binary.BigEndian, output.Amount) // uint64
+	}
+
+	if tx.Type != "" {
+		writeString(tx.Type)
+	}
+
+	if tx.HTLC != nil {
+		writeString(tx.HTLC.HashLock)
+		_ = binary.Write(&buf, binary.BigEndian, tx.HTLC.Expiry) // int64
+		writeString(tx.HTLC.HTLCId)
+		writeString(tx.HTLC.Preimage)
+	}
+
+	if tx.Channel != nil {
+		_ = binary.Write(&buf, binary.BigEndian, tx.Channel.DisputePeriod) // int64
+		writeString(tx.Channel.ChannelId)
+
+		if state := tx.Channel.State; state != nil {
+			writeString(state.ChannelId)
+			_ = binary.Write(&buf, binary.BigEndian, state.Nonce) // uint64
+			_ = binary.Write(&buf, binary.BigEndian, state.Paid)  // uint64
+			writeBytes(state.Signature)
+		}
+	}
+
+	if tx.Token != nil {
+		writeString(tx.Token.AssetId)
+		writeString(tx.Token.Symbol)
+		buf.WriteByte(tx.Token.Decimals)
+		_ = binary.Write(&buf, binary.BigEndian, tx.Token.MaxSupply) // uint64
+		_ = binary.Write(&buf, binary.BigEndian, tx.Token.Amount)    // uint64
+	}
+
+	if tx.Name != nil {
+		writeString(tx.Name.Name)
+		_ = binary.Write(&buf, binary.BigEndian, tx.Name.Duration) // int64
+	}
+
+	if tx.Vesting != nil {
+		_ = binary.Write(&buf, binary.BigEndian, tx.Vesting.StartHeight) // int64
+		_ = binary.Write(&buf, binary.BigEndian, tx.Vesting.EndHeight)   // int64
+	}
+
+	if tx.HasFeePayer() {
+		writeString(tx.FeePayer)
+		writeString(tx.FeePayerPublicKey)
+		writeBytes(tx.FeePayerSignature)
+	}
+
+	if tx.IsScript() {
+		writeString(tx.Script)
+
+		for _, item := range tx.Witness {
+			writeString(item)
+		}
+	}
+
+	if tx.IsMultisig() {
+		for _, pubKey := range tx.MultisigKeys {
+			writeString(pubKey)
+		}
+		_ = binary.Write(&buf, binary.BigEndian, uint32(tx.Threshold))
+
+		for _, sig := range tx.Signatures {
+			writeString(sig.PublicKey)
+			writeBytes(sig.Signature)
+		}
+	}
+
 	return buf.Len()
 }
 
+// SignedSize -> Size of the tx once signed, used for fee calculation before signing
+func (tx *Transaction) SignedSize() int {
+	size := tx.Size()
+
+	if tx.HasFeePayer() && tx.FeePayerSignature == nil {
+		size += ed25519.SignatureSize
+	}
+
+	if tx.IsMultisig() {
+		// length-prefixed public key (hex) and signature per missing signer
+		missingSigs := tx.Threshold - len(tx.Signatures)
+		if missingSigs > 0 {
+			size += missingSigs * (8 + 2*ed25519.PublicKeySize + ed25519.SignatureSize)
+		}
+
+		return size
+	}
+
+	// The witness is filled in before the fee is calculated
+	if tx.Signature == nil && !tx.IsScript() {
+		size += ed25519.SignatureSize
+	}
+
+	return size
+}
+
+// merkleLeaf -> Serialization of the tx committed to by the block's merkle root
+func (tx *Transaction) merkleLeaf() string {
+	var leaf string
+	if tx.IsCoinbase {
+		// For coinbase transactions, include timestamp for uniqueness
+		leaf = fmt.Sprintf("%s-%s-%d-%d-%t",
+			tx.From, tx.To, tx.Amount, tx.Timestamp, tx.IsCoinbase)
+	} else {
+		// For regular transactions, use signature for uniqueness
+		leaf = fmt.Sprintf("%s-%s-%d-%x-%d-%t",
+			tx.From, tx.To, tx.Amount, tx.Signature, tx.Timestamp, tx.IsCoinbase)
+	}
+
+	// Plain transfers keep the original encoding so existing blocks still verify
+	if tx.hasExtensions() {
+		leaf += fmt.Sprintf("-%x-%x", tx.contentHash(), tx.witnessHash())
+	}
+
+	return leaf
+}
+
+// hasExtensions -> Whether the tx uses any field beyond the original transfer format
+func (tx *Transaction) hasExtensions() bool {
+	return tx.IsMultisig() || tx.LockTime != 0 || tx.Data != "" || tx.IsBatch() || tx.HasFeePayer() || tx.Type != "" || tx.IsScript()
+}
+
+// contentHash -> Hash of everything the tx commits to, independent of its local status
+func (tx *Transaction) contentHash() []byte {
+	txCopy := *tx
+
+	txCopy.Signature = nil
+	txCopy.Signatures = nil
+	txCopy.FeePayerSignature = nil
+	txCopy.Witness = nil
+	txCopy.Status = ""
+
+	data, _ := json.Marshal(txCopy)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// LedgerId -> Id of the tx that does not depend on its signatures or local status,
+// used to reference it from ledger tables
+func (tx *Transaction) LedgerId() string {
+	return hex.EncodeToString(tx.contentHash())
+}
+
+// witnessHash -> Hash of the signature data, which Hash() leaves out
+func (tx *Transaction) witnessHash() []byte {
+	hasher := sha256.New()
+
+	fmt.Fprintf(hasher, "%x", tx.Signature)
+	for _, sig := range tx.Signatures {
+		fmt.Fprintf(hasher, "|%s-%x", sig.PublicKey, sig.Signature)
+	}
+
+	if tx.HasFeePayer() {
+		fmt.Fprintf(hasher, "|fee-payer-%x", tx.FeePayerSignature)
+	}
+
+	for _, item := range tx.Witness {
+		fmt.Fprintf(hasher, "|witness-%s", item)
+	}
+
+	return hasher.Sum(nil)
+}
+
 func CreateCoinbaseTx(minerAddress string, miningReward uint64) *Transaction {
 	return &Transaction{
 		To:         minerAddress,
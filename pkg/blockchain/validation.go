@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"fmt"
+	"slices"
+)
+
+// VerifyBlockTransactions -> Checks the base fee and size of a received block and
+// every non-coinbase tx in it
+func (bc *Blockchain) VerifyBlockTransactions(block *Block) error {
+	baseFee, err := bc.expectedBaseFee(block.Id)
+	if err != nil {
+		return err
+	}
+
+	if block.BaseFee != baseFee {
+		return fmt.Errorf("block %d has base fee %d, expected %d", block.Id, block.BaseFee, baseFee)
+	}
+
+	if size := block.CalculateSize(); size > MaxBlockSize {
+		return fmt.Errorf("block %d size %d exceeds the maximum of %d bytes", block.Id, size, MaxBlockSize)
+	}
+
+	medianTime := bc.MedianTimePast(block.Id)
+	settled := make(map[string]bool)
+
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase {
+			if tx.Vesting != nil && block.Id != 0 {
+				return fmt.Errorf("only the genesis block can open vesting accounts, block %d", block.Id)
+			}
+
+			continue
+		}
+
+		if err := bc.verifyTransactionAt(&tx, block.Id, medianTime); err != nil {
+			return err
+		}
+
+		if minFee := tx.MinFee(block.BaseFee); tx.Fee < minFee {
+			return fmt.Errorf("transaction %x fee %d is below the base fee of %d", tx.Hash(), tx.Fee, minFee)
+		}
+
+		if tx.Type == TxTypeHTLCLock && tx.HTLC.Expiry <= block.Id {
+			return fmt.Errorf("transaction %x locks an htlc that expires at height %d, block height %d",
+				tx.Hash(), tx.HTLC.Expiry, block.Id)
+		}
+
+		if tx.IsSettlement() {
+			key := tx.settlementKey()
+			if settled[key] {
+				return fmt.Errorf("%s is settled twice in block %d", key, block.Id)
+			}
+
+			if err := bc.verifySettlement(&tx, block.Id); err != nil {
+				return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+			}
+
+			settled[key] = true
+		}
+
+		if tx.IsNameOp() {
+			key := tx.nameKey()
+			if settled[key] {
+				return fmt.Errorf("%s is used twice in block %d", key, block.Id)
+			}
+
+			if err := bc.verifyNameOp(&tx, block.Id); err != nil {
+				return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+			}
+
+			settled[key] = true
+		}
+
+		if !tx.IsFinal(block.Id, medianTime) {
+			return fmt.Errorf("transaction %x is locked until %d, block height %d",
+				tx.Hash(), tx.LockTime, block.Id)
+		}
+	}
+
+	return nil
+}
+
+// VerifyTransaction -> Signature, sender/fee payer address and amount checks, no balance lookups.
+// Scripts run as if the tx is mined in the next block.
+func (bc *Blockchain) VerifyTransaction(tx *Transaction) error {
+	nextHeight := bc.GetHeight() + 1
+	return bc.verifyTransactionAt(tx, nextHeight, bc.MedianTimePast(nextHeight))
+}
+
+func (bc *Blockchain) verifyTransactionAt(tx *Transaction, height, medianTime int64) error {
+	if !tx.Verify(bc.chainId) {
+		return fmt.Errorf("transaction %x has invalid signature", tx.Hash())
+	}
+
+	if tx.IsScript() {
+		if err := tx.VerifyScript(height, medianTime, bc.chainId); err != nil {
+			return fmt.Errorf("transaction %x script failed: %w", tx.Hash(), err)
+		}
+	}
+
+	if err := tx.VerifySender(); err != nil {
+		return err
+	}
+
+	if err := tx.VerifyFeePayer(); err != nil {
+		return err
+	}
+
+	if err := tx.validateType(); err != nil {
+		return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+	}
+
+	if tx.Amount == 0 && tx.hasNativeAmount() {
+		return fmt.Errorf("transaction %x has zero amount", tx.Hash())
+	}
+
+	if err := tx.validateOutputs(); err != nil {
+		return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+	}
+
+	if len(tx.Data) > MaxTxDataSize {
+		return fmt.Errorf("transaction %x data exceeds %d bytes", tx.Hash(), MaxTxDataSize)
+	}
+
+	if tx.LockTime < 0 {
+		return fmt.Errorf("transaction %x has negative lock time", tx.Hash())
+	}
+
+	if tx.Amount+tx.Fee <= 0 {
+		return fmt.Errorf("transaction %x has invalid amount/fee combination", tx.Hash())
+	}
+
+	return nil
+}
+
+// medianTimeSpan -> Number of previous blocks the median time past is taken over
+const medianTimeSpan = 11
+
+// MedianTimePast -> Median timestamp of the (up to 11) blocks right below height.
+// Time based lock times are compared against it instead of the block's own timestamp.
+func (bc *Blockchain) MedianTimePast(height int64) int64 {
+	bc.Mutex.RLock()
+	defer bc.Mutex.RUnlock()
+
+	timestamps := make([]int64, 0, medianTimeSpan)
+	for idx := len(bc.Blocks) - 1; idx >= 0 && len(timestamps) < medianTimeSpan; idx-- {
+		if bc.Blocks[idx].Id >= height {
+			continue
+		}
+
+		timestamps = append(timestamps, bc.Blocks[idx].Timestamp)
+	}
+
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	slices.Sort(timestamps)
+
+	return timestamps[len(timestamps)/2]
+}
+
+// filterFinalTransactions -> Drops txs whose lock time has not been reached at height
+func (bc *Blockchain) filterFinalTransactions(txs []Transaction, height int64) []Transaction {
+	medianTime := bc.MedianTimePast(height)
+
+	finalTxs := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsFinal(height, medianTime) {
+			finalTxs = append(finalTxs, tx)
+		}
+	}
+
+	return finalTxs
+}
@@ -0,0 +1,235 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// VestingPayload -> Fields of the vesting_create tx type. The Amount credited to To
+// is locked until StartHeight and unlocks linearly until EndHeight.
+type VestingPayload struct {
+	StartHeight int64 `json:"start_height"`
+	EndHeight   int64 `json:"end_height"`
+}
+
+// VestingSchedule -> Coins of an address under a linear unlock schedule, as kept in the ledger
+type VestingSchedule struct {
+	Address     string `json:"address"`
+	Amount      uint64 `json:"amount"`
+	StartHeight int64  `json:"start_height"`
+	EndHeight   int64  `json:"end_height"`
+	CreatedTx   string `json:"created_tx"`
+}
+
+// VestingBalance -> Sum of an address's schedules at a height
+type VestingBalance struct {
+	Vested   uint64 `json:"vested"`
+	Unvested uint64 `json:"unvested"`
+}
+
+// GenesisVesting -> Vesting account created by the genesis block
+type GenesisVesting struct {
+	Address     string `json:"address"`
+	Amount      uint64 `json:"amount"`
+	StartHeight int64  `json:"start_height"`
+	EndHeight   int64  `json:"end_height"`
+}
+
+// LoadGenesisVesting -> Reads the vesting accounts of a new genesis block from a JSON
+// array, none if path is empty
+func LoadGenesisVesting(path string) ([]GenesisVesting, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var vesting []GenesisVesting
+	if err := json.Unmarshal(data, &vesting); err != nil {
+		return nil, fmt.Errorf("invalid genesis vesting file %s: %w", path, err)
+	}
+
+	return vesting, nil
+}
+
+// VestedAt -> Unlocked part of the schedule in a block at height
+func (schedule *VestingSchedule) VestedAt(height int64) uint64 {
+	if height <= schedule.StartHeight {
+		return 0
+	}
+
+	if height >= schedule.EndHeight {
+		return schedule.Amount
+	}
+
+	elapsed := uint64(height - schedule.StartHeight)
+	span := uint64(schedule.EndHeight - schedule.StartHeight)
+
+	// elapsed < span, so the quotient fits and Div64 cannot panic
+	hi, lo := bits.Mul64(schedule.Amount, elapsed)
+	vested, _ := bits.Div64(hi, lo, span)
+
+	return vested
+}
+
+// NewVestingTransaction -> Unsigned tx paying amount to `to`, locked until startHeight
+// and unlocking linearly until endHeight
+func NewVestingTransaction(from, to string, amount uint64, startHeight, endHeight int64) *Transaction {
+	return &Transaction{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Timestamp: utils.GetTimestamp(),
+		Status:    "pending",
+		Type:      TxTypeVestingCreate,
+		Vesting: &VestingPayload{
+			StartHeight: startHeight,
+			EndHeight:   endHeight,
+		},
+	}
+}
+
+// newGenesisVestingTx -> Coinbase tx of the genesis block creating a vesting account
+func newGenesisVestingTx(grant GenesisVesting) *Transaction {
+	tx := CreateCoinbaseTx(grant.Address, grant.Amount)
+	tx.Type = TxTypeVestingCreate
+	tx.Vesting = &VestingPayload{
+		StartHeight: grant.StartHeight,
+		EndHeight:   grant.EndHeight,
+	}
+
+	return tx
+}
+
+func (tx *Transaction) validateVestingPayload() error {
+	if tx.Vesting == nil {
+		return fmt.Errorf("%s transaction needs a vesting payload", tx.Type)
+	}
+
+	if tx.IsBatch() {
+		return fmt.Errorf("%s transaction cannot have outputs", tx.Type)
+	}
+
+	if tx.To == "" {
+		return errors.New("vesting account needs an address")
+	}
+
+	if tx.Amount == 0 {
+		return errors.New("vesting amount must be positive")
+	}
+
+	if tx.Vesting.StartHeight < 0 || tx.Vesting.EndHeight <= tx.Vesting.StartHeight {
+		return errors.New("vesting end height must be above a non-negative start height")
+	}
+
+	return nil
+}
+
+func (bc *Blockchain) GetVestingSchedules(address string) ([]VestingSchedule, error) {
+	dbSchedules, err := bc.Database.GetVestingSchedules(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDBVestingSchedules(dbSchedules), nil
+}
+
+// GetVestingBalance -> Vested and unvested coins of address in a block at height
+func (bc *Blockchain) GetVestingBalance(address string, height int64) (*VestingBalance, error) {
+	schedules, err := bc.GetVestingSchedules(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return sumVesting(schedules, height), nil
+}
+
+// addVestingSchedule -> Records the schedule of a mined vesting_create tx, its
+// Amount is credited like a plain transfer
+func (bc *Blockchain) addVestingSchedule(sqlTx *sql.Tx, tx *Transaction) error {
+	return bc.Database.AddVestingSchedule(sqlTx, database.DBVestingSchedule{
+		Address:     tx.To,
+		Amount:      tx.Amount,
+		StartHeight: tx.Vesting.StartHeight,
+		EndHeight:   tx.Vesting.EndHeight,
+		CreatedTx:   tx.LedgerId(),
+	})
+}
+
+// verifyVestingLocks -> After the txs of the block at height are applied, every
+// address they debit must still hold its unvested coins
+func (bc *Blockchain) verifyVestingLocks(sqlTx *sql.Tx, height int64, txs []Transaction) error {
+	checked := make(map[string]bool)
+
+	for _, tx := range txs {
+		if tx.IsCoinbase {
+			continue
+		}
+
+		for _, address := range []string{tx.From, tx.FeePayer} {
+			if address == "" || checked[address] {
+				continue
+			}
+			checked[address] = true
+
+			dbSchedules, err := bc.Database.GetVestingSchedulesTx(sqlTx, address)
+			if err != nil {
+				return err
+			}
+
+			if len(dbSchedules) == 0 {
+				continue
+			}
+
+			unvested := sumVesting(parseDBVestingSchedules(dbSchedules), height).Unvested
+
+			balance, err := bc.Database.GetConfirmedBalanceTx(sqlTx, address)
+			if err != nil {
+				return err
+			}
+
+			if balance < unvested {
+				return fmt.Errorf("block %d spends %d unvested coins of %s", height, unvested-balance, address)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sumVesting(schedules []VestingSchedule, height int64) *VestingBalance {
+	var balance VestingBalance
+	for idx := range schedules {
+		vested := schedules[idx].VestedAt(height)
+
+		balance.Vested += vested
+		balance.Unvested += schedules[idx].Amount - vested
+	}
+
+	return &balance
+}
+
+func parseDBVestingSchedules(dbSchedules []database.DBVestingSchedule) []VestingSchedule {
+	schedules := make([]VestingSchedule, 0, len(dbSchedules))
+	for _, dbSchedule := range dbSchedules {
+		schedules = append(schedules, VestingSchedule{
+			Address:     dbSchedule.Address,
+			Amount:      dbSchedule.Amount,
+			StartHeight: dbSchedule.StartHeight,
+			EndHeight:   dbSchedule.EndHeight,
+			CreatedTx:   dbSchedule.CreatedTx,
+		})
+	}
+
+	return schedules
+}
@@ -25,6 +25,26 @@ func (db *Database) GetConfirmedBalance(address string) (uint64, error) {
 	return balance, nil
 }
 
+// GetConfirmedBalanceTx -> GetConfirmedBalance inside sqlTx, sees the changes of earlier txs in the same block
+func (db *Database) GetConfirmedBalanceTx(sqlTx *sql.Tx, address string) (uint64, error) {
+	query := `
+		SELECT balance
+		FROM balances
+		WHERE address = ?
+	`
+
+	var balance uint64
+	if err := sqlTx.QueryRow(query, address).Scan(&balance); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return balance, nil
+}
+
 func (db *Database) IncreaseUserBalance(sqlTx *sql.Tx, address string, amount uint64) error {
 	query := `
 		INSERT INTO balances(address, balance)
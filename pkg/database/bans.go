@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DBBanSchema represents a banned peer as stored in the database
+type DBBanSchema struct {
+	Address   string
+	IP        string
+	Reason    string
+	Score     int
+	BannedAt  int64
+	ExpiresAt int64
+}
+
+// AddBan -> Bans the peer, replacing an earlier ban of the same address
+func (db *Database) AddBan(sqlTx *sql.Tx, record DBBanSchema) error {
+	query := `
+		INSERT INTO bans(address, ip, reason, score, banned_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (address) DO UPDATE
+		SET ip = excluded.ip, reason = excluded.reason, score = excluded.score,
+		    banned_at = excluded.banned_at, expires_at = excluded.expires_at
+	`
+
+	_, err := sqlTx.Exec(query, record.Address, record.IP, record.Reason, record.Score,
+		record.BannedAt, record.ExpiresAt)
+
+	return err
+}
+
+// GetActiveBans -> Bans that have not expired at now, a unix timestamp in milliseconds
+func (db *Database) GetActiveBans(now int64) ([]DBBanSchema, error) {
+	query := `
+		SELECT address, ip, reason, score, banned_at, expires_at
+		FROM bans WHERE expires_at > ? ORDER BY banned_at
+	`
+
+	rows, err := db.DB.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []DBBanSchema
+	for rows.Next() {
+		var record DBBanSchema
+		if err := rows.Scan(&record.Address, &record.IP, &record.Reason, &record.Score,
+			&record.BannedAt, &record.ExpiresAt); err != nil {
+
+			return nil, err
+		}
+
+		bans = append(bans, record)
+	}
+
+	return bans, rows.Err()
+}
+
+// RemoveBan -> Lifts the ban of address
+func (db *Database) RemoveBan(sqlTx *sql.Tx, address string) error {
+	result, err := sqlTx.Exec("DELETE FROM bans WHERE address = ?", address)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("peer %q is not banned", address))
+}
@@ -4,14 +4,14 @@ import (
 	"database/sql"
 )
 
-func (db *Database) AddBlock(sqlTx *sql.Tx, prevHash, hash, merkleRoot string, nonce, timestamp, blockHeight int64) (int64, error) {
+func (db *Database) AddBlock(sqlTx *sql.Tx, prevHash, hash, merkleRoot string, nonce, timestamp, blockHeight int64, baseFee uint64) (int64, error) {
 
 	query := `
-		INSERT INTO blocks(prev_hash, hash, merkle_root, nonce, timestamp, block_height)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO blocks(prev_hash, hash, merkle_root, nonce, timestamp, block_height, base_fee)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := sqlTx.Exec(query, prevHash, hash, merkleRoot, nonce, timestamp, blockHeight)
+	result, err := sqlTx.Exec(query, prevHash, hash, merkleRoot, nonce, timestamp, blockHeight, baseFee)
 	if err != nil {
 		return 0, err
 	}
@@ -26,7 +26,7 @@ func (db *Database) AddBlock(sqlTx *sql.Tx, prevHash, hash, merkleRoot string, n
 
 func (db *Database) GetAllBlocks() (*sql.Rows, error) {
 	query := `
-			SELECT id, prev_hash, hash, merkle_root, nonce, timestamp, block_height
+			SELECT id, prev_hash, hash, merkle_root, nonce, timestamp, block_height, base_fee
 			FROM blocks ORDER BY block_height
 		`
 
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// DBChannelSchema represents a payment channel ledger entry as stored in the database
+type DBChannelSchema struct {
+	ChannelId       string
+	Sender          string
+	SenderPublicKey string
+	Recipient       string
+	Deposit         uint64
+	DisputePeriod   int64
+	Status          string
+	Nonce           uint64
+	Paid            uint64
+	CloseFee        uint64
+	CloseHeight     int64
+	SettleTx        string
+}
+
+const channelColumns = `channel_id, sender, sender_public_key, recipient, deposit, dispute_period,
+	status, nonce, paid, close_fee, close_height, settle_tx`
+
+func (db *Database) AddChannel(sqlTx *sql.Tx, channel DBChannelSchema) error {
+	query := `
+		INSERT INTO channels(channel_id, sender, sender_public_key, recipient, deposit, dispute_period, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sqlTx.Exec(query, channel.ChannelId, channel.Sender, channel.SenderPublicKey,
+		channel.Recipient, channel.Deposit, channel.DisputePeriod, channel.Status)
+
+	return err
+}
+
+// GetChannel -> sql.ErrNoRows if there is no channel with this id
+func (db *Database) GetChannel(channelId string) (*DBChannelSchema, error) {
+	query := "SELECT " + channelColumns + " FROM channels WHERE channel_id = ?"
+
+	return scanChannel(db.DB.QueryRow(query, channelId))
+}
+
+// GetChannelTx -> GetChannel inside sqlTx, sees the changes of earlier txs in the same block
+func (db *Database) GetChannelTx(sqlTx *sql.Tx, channelId string) (*DBChannelSchema, error) {
+	query := "SELECT " + channelColumns + " FROM channels WHERE channel_id = ?"
+
+	return scanChannel(sqlTx.QueryRow(query, channelId))
+}
+
+// UpdateChannel -> Stores the closing state of a channel, sql.ErrNoRows if its
+// status is no longer prevStatus
+func (db *Database) UpdateChannel(sqlTx *sql.Tx, channel DBChannelSchema, prevStatus string) error {
+	query := `
+		UPDATE channels
+		SET status = ?, nonce = ?, paid = ?, close_fee = ?, close_height = ?, settle_tx = ?
+		WHERE channel_id = ? AND status = ?
+	`
+
+	var settleTx any = nil
+	if channel.SettleTx != "" {
+		settleTx = channel.SettleTx
+	}
+
+	result, err := sqlTx.Exec(query, channel.Status, channel.Nonce, channel.Paid, channel.CloseFee,
+		channel.CloseHeight, settleTx, channel.ChannelId, prevStatus)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func scanChannel(row *sql.Row) (*DBChannelSchema, error) {
+	var channel DBChannelSchema
+	var settleTx sql.NullString
+
+	if err := row.Scan(&channel.ChannelId, &channel.Sender, &channel.SenderPublicKey, &channel.Recipient,
+		&channel.Deposit, &channel.DisputePeriod, &channel.Status, &channel.Nonce, &channel.Paid,
+		&channel.CloseFee, &channel.CloseHeight, &settleTx); err != nil {
+
+		return nil, err
+	}
+
+	if settleTx.Valid {
+		channel.SettleTx = settleTx.String
+	}
+
+	return &channel, nil
+}
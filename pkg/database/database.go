@@ -49,9 +49,33 @@ func (db *Database) BeginTx() (*sql.Tx, error) {
 	return db.DB.Begin()
 }
 
+// Savepoint -> Marks a point of sqlTx that RollbackToSavepoint can undo the later
+// statements back to
+func (db *Database) Savepoint(sqlTx *sql.Tx, name string) error {
+	_, err := sqlTx.Exec("SAVEPOINT " + name)
+	return err
+}
+
+func (db *Database) RollbackToSavepoint(sqlTx *sql.Tx, name string) error {
+	_, err := sqlTx.Exec("ROLLBACK TO SAVEPOINT " + name)
+	return err
+}
+
+func (db *Database) ReleaseSavepoint(sqlTx *sql.Tx, name string) error {
+	_, err := sqlTx.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
+
 // ClearAllData -> Flush the database
 func (db *Database) ClearAllData(sqlTx *sql.Tx) error {
 	queries := []string{
+		"DELETE FROM vesting_schedules",
+		"DELETE FROM names",
+		"DELETE FROM token_balances",
+		"DELETE FROM assets",
+		"DELETE FROM channels",
+		"DELETE FROM htlcs",
+		"DELETE FROM transaction_outputs",
 		"DELETE FROM transactions",
 		"DELETE FROM blocks",
 		"DELETE FROM balances",
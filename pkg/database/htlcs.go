@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// DBHTLCSchema represents an HTLC ledger entry as stored in the database
+type DBHTLCSchema struct {
+	HTLCId    string
+	Sender    string
+	Recipient string
+	Amount    uint64
+	HashLock  string
+	Expiry    int64
+	Status    string
+	Preimage  string
+	SettleTx  string
+}
+
+func (db *Database) AddHTLC(sqlTx *sql.Tx, htlc DBHTLCSchema) error {
+	query := `
+		INSERT INTO htlcs(htlc_id, sender, recipient, amount, hash_lock, expiry, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sqlTx.Exec(query, htlc.HTLCId, htlc.Sender, htlc.Recipient, htlc.Amount,
+		htlc.HashLock, htlc.Expiry, htlc.Status)
+
+	return err
+}
+
+// GetHTLC -> sql.ErrNoRows if there is no HTLC with this id
+func (db *Database) GetHTLC(htlcId string) (*DBHTLCSchema, error) {
+	query := `
+		SELECT htlc_id, sender, recipient, amount, hash_lock, expiry, status, preimage, settle_tx
+		FROM htlcs
+		WHERE htlc_id = ?
+	`
+
+	return scanHTLC(db.DB.QueryRow(query, htlcId))
+}
+
+// SettleHTLC -> Moves a locked HTLC to status, sql.ErrNoRows if it is not locked
+func (db *Database) SettleHTLC(sqlTx *sql.Tx, htlcId, status, preimage, settleTx string) (*DBHTLCSchema, error) {
+	query := `
+		UPDATE htlcs
+		SET status = ?, preimage = ?, settle_tx = ?
+		WHERE htlc_id = ? AND status = 'locked'
+		RETURNING htlc_id, sender, recipient, amount, hash_lock, expiry, status, preimage, settle_tx
+	`
+
+	var preimageValue any = nil
+	if preimage != "" {
+		preimageValue = preimage
+	}
+
+	return scanHTLC(sqlTx.QueryRow(query, status, preimageValue, settleTx, htlcId))
+}
+
+func scanHTLC(row *sql.Row) (*DBHTLCSchema, error) {
+	var htlc DBHTLCSchema
+	var preimage sql.NullString
+	var settleTx sql.NullString
+
+	if err := row.Scan(&htlc.HTLCId, &htlc.Sender, &htlc.Recipient, &htlc.Amount, &htlc.HashLock,
+		&htlc.Expiry, &htlc.Status, &preimage, &settleTx); err != nil {
+
+		return nil, err
+	}
+
+	if preimage.Valid {
+		htlc.Preimage = preimage.String
+	}
+	if settleTx.Valid {
+		htlc.SettleTx = settleTx.String
+	}
+
+	return &htlc, nil
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// DBNameSchema represents a registered name as stored in the database
+type DBNameSchema struct {
+	Name             string
+	Owner            string
+	Expiry           int64
+	RegisteredHeight int64
+	LastTx           string
+}
+
+const nameColumns = "name, owner, expiry, registered_height, last_tx"
+
+// GetName -> sql.ErrNoRows if the name was never registered, expired names are returned too
+func (db *Database) GetName(name string) (*DBNameSchema, error) {
+	query := "SELECT " + nameColumns + " FROM names WHERE name = ?"
+
+	return scanName(db.DB.QueryRow(query, name))
+}
+
+// GetNamesByOwner -> Names owned by owner that are still active at height
+func (db *Database) GetNamesByOwner(owner string, height int64) ([]DBNameSchema, error) {
+	query := "SELECT " + nameColumns + " FROM names WHERE owner = ? AND expiry > ? ORDER BY name"
+
+	rows, err := db.DB.Query(query, owner, height)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []DBNameSchema
+	for rows.Next() {
+		var record DBNameSchema
+		if err := rows.Scan(&record.Name, &record.Owner, &record.Expiry, &record.RegisteredHeight,
+			&record.LastTx); err != nil {
+
+			return nil, err
+		}
+
+		names = append(names, record)
+	}
+
+	return names, rows.Err()
+}
+
+// RegisterName -> Adds the name, or takes it over if it expired before height
+func (db *Database) RegisterName(sqlTx *sql.Tx, record DBNameSchema, height int64) error {
+	query := `
+		INSERT INTO names(name, owner, expiry, registered_height, last_tx)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE
+		SET owner = excluded.owner, expiry = excluded.expiry,
+		    registered_height = excluded.registered_height, last_tx = excluded.last_tx
+		WHERE names.expiry <= ?
+	`
+
+	result, err := sqlTx.Exec(query, record.Name, record.Owner, record.Expiry, record.RegisteredHeight,
+		record.LastTx, height)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("name %q is already registered", record.Name))
+}
+
+// RenewName -> Extends an active name of owner by duration blocks
+func (db *Database) RenewName(sqlTx *sql.Tx, name, owner string, duration, height int64, lastTx string) error {
+	query := `
+		UPDATE names
+		SET expiry = expiry + ?, last_tx = ?
+		WHERE name = ? AND owner = ? AND expiry > ?
+	`
+
+	result, err := sqlTx.Exec(query, duration, lastTx, name, owner, height)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("name %q is not active or not owned by %s", name, owner))
+}
+
+// TransferName -> Hands an active name of owner to newOwner, keeping its expiry
+func (db *Database) TransferName(sqlTx *sql.Tx, name, owner, newOwner string, height int64, lastTx string) error {
+	query := `
+		UPDATE names
+		SET owner = ?, last_tx = ?
+		WHERE name = ? AND owner = ? AND expiry > ?
+	`
+
+	result, err := sqlTx.Exec(query, newOwner, lastTx, name, owner, height)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("name %q is not active or not owned by %s", name, owner))
+}
+
+func expectOneRow(result sql.Result, message string) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errors.New(message)
+	}
+
+	return nil
+}
+
+func scanName(row *sql.Row) (*DBNameSchema, error) {
+	var record DBNameSchema
+
+	if err := row.Scan(&record.Name, &record.Owner, &record.Expiry, &record.RegisteredHeight,
+		&record.LastTx); err != nil {
+
+		return nil, err
+	}
+
+	return &record, nil
+}
@@ -61,3 +61,15 @@ func (db *Database) PeerExist(tcpAddress string) (bool, error) {
 
 	return true, nil
 }
+
+func (db *Database) RemovePeer(sqlTx *sql.Tx, tcpAddress string) error {
+	query := `
+		DELETE FROM peers WHERE tcp_address = ?;
+	`
+
+	if _, err := sqlTx.Exec(query, tcpAddress); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// DBAssetSchema represents a token issued on-chain as stored in the database
+type DBAssetSchema struct {
+	AssetId         string
+	Symbol          string
+	Decimals        uint8
+	MaxSupply       uint64
+	Supply          uint64
+	Issuer          string
+	IssuerPublicKey string
+}
+
+// DBTokenBalance is the balance of one asset held by an address
+type DBTokenBalance struct {
+	AssetId string
+	Balance uint64
+}
+
+const assetColumns = "asset_id, symbol, decimals, max_supply, supply, issuer, issuer_public_key"
+
+func (db *Database) AddAsset(sqlTx *sql.Tx, asset DBAssetSchema) error {
+	query := `
+		INSERT INTO assets(asset_id, symbol, decimals, max_supply, supply, issuer, issuer_public_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var issuerPublicKey any = nil
+	if asset.IssuerPublicKey != "" {
+		issuerPublicKey = asset.IssuerPublicKey
+	}
+
+	_, err := sqlTx.Exec(query, asset.AssetId, asset.Symbol, asset.Decimals, asset.MaxSupply,
+		asset.Supply, asset.Issuer, issuerPublicKey)
+
+	return err
+}
+
+// GetAsset -> sql.ErrNoRows if there is no asset with this id
+func (db *Database) GetAsset(assetId string) (*DBAssetSchema, error) {
+	query := "SELECT " + assetColumns + " FROM assets WHERE asset_id = ?"
+
+	row := db.DB.QueryRow(query, assetId)
+	return scanAsset(row.Scan)
+}
+
+// GetAssets -> All issued assets, oldest first
+func (db *Database) GetAssets() ([]DBAssetSchema, error) {
+	query := "SELECT " + assetColumns + " FROM assets ORDER BY id"
+
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assets []DBAssetSchema
+	for rows.Next() {
+		asset, err := scanAsset(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+
+		assets = append(assets, *asset)
+	}
+
+	return assets, rows.Err()
+}
+
+// IncreaseAssetSupply -> Mints amount of the asset, fails if minter is not its
+// issuer or the supply would exceed the max supply
+func (db *Database) IncreaseAssetSupply(sqlTx *sql.Tx, assetId, minter string, amount uint64) error {
+	query := `
+		UPDATE assets
+		SET supply = supply + ?
+		WHERE asset_id = ? AND issuer = ? AND supply + ? <= max_supply
+	`
+
+	result, err := sqlTx.Exec(query, amount, assetId, minter, amount)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("cannot mint %d of asset %s as %s", amount, assetId, minter)
+	}
+
+	return nil
+}
+
+func (db *Database) GetTokenBalance(address, assetId string) (uint64, error) {
+	query := `
+		SELECT balance
+		FROM token_balances
+		WHERE address = ? AND asset_id = ?
+	`
+
+	var balance uint64
+	if err := db.DB.QueryRow(query, address, assetId).Scan(&balance); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// GetTokenBalances -> Non-zero token balances of an address, in order of issuance
+func (db *Database) GetTokenBalances(address string) ([]DBTokenBalance, error) {
+	query := `
+		SELECT token_balances.asset_id, token_balances.balance
+		FROM token_balances
+		JOIN assets ON assets.asset_id = token_balances.asset_id
+		WHERE token_balances.address = ? AND token_balances.balance > 0
+		ORDER BY assets.id
+	`
+
+	rows, err := db.DB.Query(query, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []DBTokenBalance
+	for rows.Next() {
+		var balance DBTokenBalance
+		if err := rows.Scan(&balance.AssetId, &balance.Balance); err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+func (db *Database) IncreaseTokenBalance(sqlTx *sql.Tx, address, assetId string, amount uint64) error {
+	query := `
+		INSERT INTO token_balances(address, asset_id, balance)
+		VALUES (?, ?, ?)
+		ON CONFLICT (address, asset_id) DO UPDATE SET balance = balance + excluded.balance
+	`
+
+	_, err := sqlTx.Exec(query, address, assetId, amount)
+	return err
+}
+
+func (db *Database) DecreaseTokenBalance(sqlTx *sql.Tx, address, assetId string, amount uint64) error {
+	query := `
+		UPDATE token_balances
+		SET balance = balance - ?
+		WHERE address = ? AND asset_id = ? AND balance >= ?
+	`
+
+	result, err := sqlTx.Exec(query, amount, address, assetId, amount)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("insufficient %s token balance for address %s", assetId, address)
+	}
+
+	return nil
+}
+
+func scanAsset(scan func(dest ...any) error) (*DBAssetSchema, error) {
+	var asset DBAssetSchema
+	var issuerPublicKey sql.NullString
+
+	if err := scan(&asset.AssetId, &asset.Symbol, &asset.Decimals, &asset.MaxSupply, &asset.Supply,
+		&asset.Issuer, &issuerPublicKey); err != nil {
+
+		return nil, err
+	}
+
+	if issuerPublicKey.Valid {
+		asset.IssuerPublicKey = issuerPublicKey.String
+	}
+
+	return &asset, nil
+}
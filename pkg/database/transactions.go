@@ -2,10 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"strings"
 )
 
 // DBTransactionSchema represents a transaction as stored in the database
 type DBTransactionSchema struct {
+	Id         int64
 	From       string
 	To         string
 	Amount     uint64
@@ -15,11 +17,45 @@ type DBTransactionSchema struct {
 	Signature  string
 	Status     string
 	IsCoinbase bool
+	LockTime   int64
+	Data       string
+
+	MultisigKeys string // comma separated public keys
+	Threshold    int
+	Signatures   string // JSON encoded multisig signatures
+
+	FeePayer          string
+	FeePayerPublicKey string
+	FeePayerSignature string // hex
+
+	Type    string
+	Payload string // JSON encoded payload of the tx type
+
+	Script  string // hex
+	Witness string // JSON encoded hex stack items
+
+	Outputs []DBTransactionOutput // batch transfer recipients, in order
+}
+
+// DBTransactionOutput is one recipient of a batch transaction
+type DBTransactionOutput struct {
+	To     string
+	Amount uint64
 }
 
+// DBTransactionMatch is a confirmed transaction together with the height of its block
+type DBTransactionMatch struct {
+	DBTransactionSchema
+	BlockHeight int64
+}
+
+const transactionColumns = `id, sender, recipient, amount, fee, timestamp, public_key, signature, status, is_coin_base,
+			       lock_time, data, multisig_keys, threshold, signatures,
+			       fee_payer, fee_payer_public_key, fee_payer_signature, tx_type, payload, script, witness`
+
 func (db *Database) GetTransactionsByBlockId(blockId int) ([]DBTransactionSchema, error) {
 	query := `
-			SELECT sender, recipient, amount, fee, timestamp, public_key, signature, status, is_coin_base
+			SELECT ` + transactionColumns + `
 			FROM transactions
 			WHERE block_id = ?
 			ORDER BY id
@@ -33,41 +69,198 @@ func (db *Database) GetTransactionsByBlockId(blockId int) ([]DBTransactionSchema
 
 	var transactions []DBTransactionSchema
 	for rows.Next() {
-		var tx DBTransactionSchema
-		var sender sql.NullString
-		var publicKey sql.NullString
-		var signature sql.NullString
-
-		err := rows.Scan(&sender, &tx.To, &tx.Amount, &tx.Fee, &tx.Timestamp,
-			&publicKey, &signature, &tx.Status, &tx.IsCoinbase)
+		tx, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if sender.Valid {
-			tx.From = sender.String
-		}
-		if publicKey.Valid {
-			tx.PublicKey = publicKey.String
-		}
-		if signature.Valid {
-			tx.Signature = signature.String
+		transactions = append(transactions, *tx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := db.loadTransactionOutputs(transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// GetTransactionsByDataPrefix -> Confirmed transactions whose data starts with prefix (case-sensitive)
+func (db *Database) GetTransactionsByDataPrefix(prefix string, limit int) ([]DBTransactionMatch, error) {
+	query := `
+			SELECT (SELECT block_height FROM blocks WHERE blocks.id = block_id), ` + transactionColumns + `
+			FROM transactions
+			WHERE data GLOB ?
+			ORDER BY block_id, id
+			LIMIT ?
+		`
+
+	rows, err := db.DB.Query(query, escapeGlob(prefix)+"*", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []DBTransactionMatch
+	for rows.Next() {
+		var blockHeight int64
+
+		tx, err := scanTransaction(rows, &blockHeight)
+		if err != nil {
+			return nil, err
 		}
 
-		transactions = append(transactions, tx)
+		matches = append(matches, DBTransactionMatch{
+			DBTransactionSchema: *tx,
+			BlockHeight:         blockHeight,
+		})
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return transactions, nil
+	txs := make([]DBTransactionSchema, len(matches))
+	for idx := range matches {
+		txs[idx] = matches[idx].DBTransactionSchema
+	}
+
+	if err := db.loadTransactionOutputs(txs); err != nil {
+		return nil, err
+	}
+
+	for idx := range matches {
+		matches[idx].Outputs = txs[idx].Outputs
+	}
+
+	return matches, nil
+}
+
+// loadTransactionOutputs -> Fills in the outputs of the batch txs among txs
+func (db *Database) loadTransactionOutputs(txs []DBTransactionSchema) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	indexById := make(map[int64]int, len(txs))
+	args := make([]any, len(txs))
+	for idx, tx := range txs {
+		indexById[tx.Id] = idx
+		args[idx] = tx.Id
+	}
+
+	query := `
+			SELECT transaction_id, recipient, amount
+			FROM transaction_outputs
+			WHERE transaction_id IN (?` + strings.Repeat(", ?", len(txs)-1) + `)
+			ORDER BY transaction_id, output_index
+		`
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txId int64
+		var output DBTransactionOutput
+
+		if err := rows.Scan(&txId, &output.To, &output.Amount); err != nil {
+			return err
+		}
+
+		idx := indexById[txId]
+		txs[idx].Outputs = append(txs[idx].Outputs, output)
+	}
+
+	return rows.Err()
+}
+
+// scanTransaction -> Scans transactionColumns, after any extra leading columns
+func scanTransaction(rows *sql.Rows, leading ...any) (*DBTransactionSchema, error) {
+	var tx DBTransactionSchema
+	var sender sql.NullString
+	var publicKey sql.NullString
+	var signature sql.NullString
+	var data sql.NullString
+	var multisigKeys sql.NullString
+	var signatures sql.NullString
+	var feePayer sql.NullString
+	var feePayerPublicKey sql.NullString
+	var feePayerSignature sql.NullString
+	var txType sql.NullString
+	var payload sql.NullString
+	var spendScript sql.NullString
+	var witness sql.NullString
+
+	dest := append(leading, &tx.Id, &sender, &tx.To, &tx.Amount, &tx.Fee, &tx.Timestamp,
+		&publicKey, &signature, &tx.Status, &tx.IsCoinbase,
+		&tx.LockTime, &data, &multisigKeys, &tx.Threshold, &signatures,
+		&feePayer, &feePayerPublicKey, &feePayerSignature, &txType, &payload, &spendScript, &witness)
+
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if sender.Valid {
+		tx.From = sender.String
+	}
+	if publicKey.Valid {
+		tx.PublicKey = publicKey.String
+	}
+	if signature.Valid {
+		tx.Signature = signature.String
+	}
+	if data.Valid {
+		tx.Data = data.String
+	}
+	if multisigKeys.Valid {
+		tx.MultisigKeys = multisigKeys.String
+	}
+	if signatures.Valid {
+		tx.Signatures = signatures.String
+	}
+	if feePayer.Valid {
+		tx.FeePayer = feePayer.String
+	}
+	if feePayerPublicKey.Valid {
+		tx.FeePayerPublicKey = feePayerPublicKey.String
+	}
+	if feePayerSignature.Valid {
+		tx.FeePayerSignature = feePayerSignature.String
+	}
+	if txType.Valid {
+		tx.Type = txType.String
+	}
+	if payload.Valid {
+		tx.Payload = payload.String
+	}
+	if spendScript.Valid {
+		tx.Script = spendScript.String
+	}
+	if witness.Valid {
+		tx.Witness = witness.String
+	}
+
+	return &tx, nil
+}
+
+// escapeGlob -> Makes GLOB treat *, ? and [ literally
+func escapeGlob(value string) string {
+	replacer := strings.NewReplacer("[", "[[]", "*", "[*]", "?", "[?]")
+	return replacer.Replace(value)
 }
 
 func (db *Database) AddTransaction(sqlTx *sql.Tx, tx DBTransactionSchema, blockId int) error {
 	query := `
-		INSERT INTO transactions(block_id, sender, recipient, amount, fee, timestamp, public_key, signature, status, is_coin_base)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO transactions(block_id, sender, recipient, amount, fee, timestamp, public_key, signature, status, is_coin_base,
+		                         lock_time, data, multisig_keys, threshold, signatures,
+		                         fee_payer, fee_payer_public_key, fee_payer_signature, tx_type, payload, script, witness)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	var sender any = nil
@@ -85,8 +278,66 @@ func (db *Database) AddTransaction(sqlTx *sql.Tx, tx DBTransactionSchema, blockI
 		signature = tx.Signature
 	}
 
-	_, err := sqlTx.Exec(query, blockId, sender, tx.To, tx.Amount, tx.Fee, tx.Timestamp,
-		publicKey, signature, tx.Status, tx.IsCoinbase)
+	var data any = nil
+	if tx.Data != "" {
+		data = tx.Data
+	}
+
+	var multisigKeys any = nil
+	if tx.MultisigKeys != "" {
+		multisigKeys = tx.MultisigKeys
+	}
+
+	var signatures any = nil
+	if tx.Signatures != "" {
+		signatures = tx.Signatures
+	}
+
+	var feePayer, feePayerPublicKey, feePayerSignature any = nil, nil, nil
+	if tx.FeePayer != "" {
+		feePayer = tx.FeePayer
+		feePayerPublicKey = tx.FeePayerPublicKey
+		feePayerSignature = tx.FeePayerSignature
+	}
+
+	var txType, payload any = nil, nil
+	if tx.Type != "" {
+		txType = tx.Type
+		payload = tx.Payload
+	}
+
+	var spendScript, witness any = nil, nil
+	if tx.Script != "" {
+		spendScript = tx.Script
+		witness = tx.Witness
+	}
+
+	result, err := sqlTx.Exec(query, blockId, sender, tx.To, tx.Amount, tx.Fee, tx.Timestamp,
+		publicKey, signature, tx.Status, tx.IsCoinbase, tx.LockTime, data, multisigKeys, tx.Threshold, signatures,
+		feePayer, feePayerPublicKey, feePayerSignature, txType, payload, spendScript, witness)
+	if err != nil {
+		return err
+	}
+
+	if len(tx.Outputs) == 0 {
+		return nil
+	}
+
+	txId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for idx, output := range tx.Outputs {
+		query := `
+			INSERT INTO transaction_outputs(transaction_id, output_index, recipient, amount)
+			VALUES (?, ?, ?, ?)
+		`
+
+		if _, err := sqlTx.Exec(query, txId, idx, output.To, output.Amount); err != nil {
+			return err
+		}
+	}
 
-	return err
+	return nil
 }
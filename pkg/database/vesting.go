@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// DBVestingSchedule represents coins of an address unlocking linearly between two heights
+type DBVestingSchedule struct {
+	Address     string
+	Amount      uint64
+	StartHeight int64
+	EndHeight   int64
+	CreatedTx   string
+}
+
+// rowsQuerier -> *sql.DB or *sql.Tx
+type rowsQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func (db *Database) AddVestingSchedule(sqlTx *sql.Tx, schedule DBVestingSchedule) error {
+	query := `
+		INSERT INTO vesting_schedules(address, amount, start_height, end_height, created_tx)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := sqlTx.Exec(query, schedule.Address, schedule.Amount, schedule.StartHeight,
+		schedule.EndHeight, schedule.CreatedTx)
+
+	return err
+}
+
+// GetVestingSchedules -> Schedules of address, oldest first
+func (db *Database) GetVestingSchedules(address string) ([]DBVestingSchedule, error) {
+	return getVestingSchedules(db.DB, address)
+}
+
+// GetVestingSchedulesTx -> GetVestingSchedules inside sqlTx, sees the changes of earlier txs in the same block
+func (db *Database) GetVestingSchedulesTx(sqlTx *sql.Tx, address string) ([]DBVestingSchedule, error) {
+	return getVestingSchedules(sqlTx, address)
+}
+
+func getVestingSchedules(querier rowsQuerier, address string) ([]DBVestingSchedule, error) {
+	query := `
+		SELECT address, amount, start_height, end_height, created_tx
+		FROM vesting_schedules
+		WHERE address = ?
+		ORDER BY id
+	`
+
+	rows, err := querier.Query(query, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []DBVestingSchedule
+	for rows.Next() {
+		var schedule DBVestingSchedule
+		if err := rows.Scan(&schedule.Address, &schedule.Amount, &schedule.StartHeight,
+			&schedule.EndHeight, &schedule.CreatedTx); err != nil {
+
+			return nil, err
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
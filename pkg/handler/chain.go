@@ -12,10 +12,12 @@ import (
 // Response: 200 OK with JSON body:
 //
 //	{
-//	  "blockchain": {...}  // Complete blockchain object
+//	  "chain_id": "mainnet",  // Network every tx signature is bound to
+//	  "blockchain": {...}     // Complete blockchain object
 //	}
 func (handler *Handler) GetBlockchain(w http.ResponseWriter, r *http.Request) {
 	resp := map[string]any{
+		"chain_id":   handler.Node.Blockchain.ChainId(),
 		"blockchain": handler.Node.Blockchain,
 	}
 
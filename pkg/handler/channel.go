@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// OpenChannel handles POST /api/channels requests.
+// Escrows a deposit for off-chain payments from the sender to the recipient.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's wallet address
+//	  "to": "address",          // Recipient's wallet address
+//	  "deposit": 1000,          // Amount to escrow
+//	  "dispute_period": 10,     // Blocks the recipient has to answer a close by the sender
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "channel_id": "hex",        // Id used for balance updates and to close the channel once mined
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if validation fails or balance is insufficient
+func (handler *Handler) OpenChannel(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Deposit       uint64 `json:"deposit"`
+		DisputePeriod int64  `json:"dispute_period"`
+		PrivateKey    string `json:"private_key"`
+		PublicKey     string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Deposit == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, "Your channel deposit must be more than 0")
+		return
+	}
+
+	newTx := blockchain.NewChannelOpenTransaction(input.From, input.To, input.Deposit, input.DisputePeriod)
+
+	if !handler.submitTransaction(w, newTx, input.PrivateKey, input.PublicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"channel_id":       newTx.LedgerId(),
+		"transaction_hash": newTx.Hash().EncodeToString(),
+		"fee":              newTx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetChannel handles GET /api/channels/{id} requests.
+// Returns the confirmed state of a payment channel.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "id": "hex",
+//	  "sender": "address",
+//	  "sender_public_key": "hex",
+//	  "recipient": "address",
+//	  "deposit": 1000,
+//	  "dispute_period": 10,
+//	  "status": "open",         // open, closing or settled
+//	  "nonce": 3,               // Nonce of the state the channel closes with
+//	  "paid": 250,              // Amount that state pays the recipient
+//	  "close_fee": 10,          // Fee of the sender's close, taken from the deposit
+//	  "close_height": 120,      // Height of the close, the channel settles from close_height + dispute_period on
+//	  "settle_tx": "hex"        // Id of the transaction that paid the channel out
+//	}
+//
+// Response: 404 Not Found if no mined channel has this id
+func (handler *Handler) GetChannel(w http.ResponseWriter, r *http.Request) {
+	channel, ok := handler.findChannel(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, channel)
+}
+
+// SignChannelState handles POST /api/channels/{id}/state requests.
+// Signs an off-chain balance update with the sender's key. Nothing is broadcast,
+// the sender hands the state to the recipient.
+//
+// Request body (JSON):
+//
+//	{
+//	  "nonce": 3,               // Must grow with every update
+//	  "paid": 250,              // Total paid to the recipient so far
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with the signed state:
+//
+//	{
+//	  "channel_id": "hex",
+//	  "nonce": 3,
+//	  "paid": 250,
+//	  "signature": "base64"
+//	}
+//
+// Response: 400 Bad Request if the key is not the sender's or paid exceeds the deposit
+func (handler *Handler) SignChannelState(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Nonce      uint64 `json:"nonce"`
+		Paid       uint64 `json:"paid"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	channel, ok := handler.findChannel(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	state := blockchain.NewChannelState(channel.Id, input.Nonce, input.Paid)
+	if err := state.SignWithHexKeys(input.PrivateKey, input.PublicKey, handler.Node.Blockchain.ChainId()); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := blockchain.VerifyChannelState(channel, state, handler.Node.Blockchain.ChainId()); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, state)
+}
+
+// VerifyChannelState handles POST /api/channels/{id}/state/verify requests.
+// Lets the recipient check a balance update before delivering what it pays for.
+//
+// Request body (JSON): a signed state as returned by POST /api/channels/{id}/state
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "valid": false,
+//	  "error": "state is not signed by the channel sender"    // Omitted when valid
+//	}
+//
+// Response: 404 Not Found if no mined channel has this id
+func (handler *Handler) VerifyChannelState(w http.ResponseWriter, r *http.Request) {
+	var state blockchain.ChannelState
+	if err := utils.ParseJSON(r, 10_000, &state); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	channel, ok := handler.findChannel(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	resp := map[string]any{
+		"valid": true,
+	}
+
+	if err := blockchain.VerifyChannelState(channel, &state, handler.Node.Blockchain.ChainId()); err != nil {
+		resp["valid"] = false
+		resp["error"] = err.Error()
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// CloseChannel handles POST /api/channels/{id}/close requests.
+// A close by the recipient settles the channel with the given state right away, the
+// fee is taken from the recipient's share. A close by the sender starts the dispute
+// period, in which the recipient can still close with a later state, the fee is taken
+// from the sender's share.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's or recipient's wallet address
+//	  "state": {...},           // Latest signed state, required from the recipient
+//	  "private_key": "hex",     // Closer's private key (hex encoded)
+//	  "public_key": "hex"       // Closer's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the state is invalid or older, or the channel is already closed
+func (handler *Handler) CloseChannel(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string                   `json:"from"`
+		State      *blockchain.ChannelState `json:"state"`
+		PrivateKey string                   `json:"private_key"`
+		PublicKey  string                   `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewChannelCloseTransaction(input.From, chi.URLParam(r, "id"), input.State)
+
+	handler.settleChannel(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// SettleChannel handles POST /api/channels/{id}/settle requests.
+// Pays out a channel the sender closed once its dispute period has passed, the fee
+// is taken from the settler's share.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's or recipient's wallet address
+//	  "private_key": "hex",     // Settler's private key (hex encoded)
+//	  "public_key": "hex"       // Settler's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the channel is not closing or the dispute period has not passed
+func (handler *Handler) SettleChannel(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewChannelSettleTransaction(input.From, chi.URLParam(r, "id"))
+
+	handler.settleChannel(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+func (handler *Handler) findChannel(w http.ResponseWriter, channelId string) (*blockchain.Channel, bool) {
+	channel, err := handler.Node.Blockchain.GetChannel(channelId)
+	if err != nil {
+		if errors.Is(err, blockchain.ErrChannelNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, err)
+			return nil, false
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return channel, true
+}
+
+func (handler *Handler) settleChannel(w http.ResponseWriter, tx *blockchain.Transaction, privateKey, publicKey string) {
+	if _, ok := handler.findChannel(w, tx.Channel.ChannelId); !ok {
+		return
+	}
+
+	if !handler.submitTransaction(w, tx, privateKey, publicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": tx.Hash().EncodeToString(),
+		"fee":              tx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
@@ -2,6 +2,7 @@
 package handler
 
 import (
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
 	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
 )
 
@@ -9,11 +10,15 @@ import (
 // It maintains a reference to the P2P node which provides access to the blockchain and network.
 type Handler struct {
 	Node *p2p.Node
+
+	// PartialTxs holds multisig transactions that are still collecting signatures.
+	PartialTxs *blockchain.PartialTxStore
 }
 
 // New creates a new Handler instance with the given P2P node.
 func New(node *p2p.Node) *Handler {
 	return &Handler{
-		Node: node,
+		Node:       node,
+		PartialTxs: blockchain.NewPartialTxStore(),
 	}
 }
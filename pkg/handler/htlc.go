@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateHTLC handles POST /api/htlc requests.
+// Locks funds to a recipient, who can claim them by revealing the preimage of
+// hash_lock before the expiry height. From the expiry height on, the sender can refund them.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's wallet address
+//	  "to": "address",          // Recipient's wallet address
+//	  "amount": 1000,           // Amount to lock
+//	  "hash_lock": "hex",       // sha256 of the secret preimage (hex encoded)
+//	  "expiry": 150,            // Block height from which the sender can refund
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "htlc_id": "hex",           // Id used to inspect, claim and refund the HTLC once mined
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "expiry": 150,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if validation fails, the expiry has passed or balance is insufficient
+func (handler *Handler) CreateHTLC(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Amount     uint64 `json:"amount"`
+		HashLock   string `json:"hash_lock"`
+		Expiry     int64  `json:"expiry"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Amount == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, "Your transaction amount must be more than 0")
+		return
+	}
+
+	if input.Expiry <= handler.Node.Blockchain.GetHeight()+1 {
+		utils.WriteJSON(w, http.StatusBadRequest, "expiry must be above the next block height")
+		return
+	}
+
+	newTx := blockchain.NewHTLCLockTransaction(input.From, input.To, input.Amount, input.HashLock, input.Expiry)
+
+	if !handler.submitTransaction(w, newTx, input.PrivateKey, input.PublicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"htlc_id":          newTx.LedgerId(),
+		"transaction_hash": newTx.Hash().EncodeToString(),
+		"fee":              newTx.Fee,
+		"expiry":           input.Expiry,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetHTLC handles GET /api/htlc/{id} requests.
+// Returns the confirmed state of an HTLC.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "id": "hex",
+//	  "sender": "address",
+//	  "recipient": "address",
+//	  "amount": 1000,
+//	  "hash_lock": "hex",
+//	  "expiry": 150,
+//	  "status": "locked",       // locked, claimed or refunded
+//	  "preimage": "hex",        // Revealed by the claim, lets the sender claim the other side of a swap
+//	  "settle_tx": "hex"        // Id of the claim or refund transaction
+//	}
+//
+// Response: 404 Not Found if no mined HTLC has this id
+func (handler *Handler) GetHTLC(w http.ResponseWriter, r *http.Request) {
+	htlc, err := handler.Node.Blockchain.GetHTLC(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, blockchain.ErrHTLCNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, htlc)
+}
+
+// ClaimHTLC handles POST /api/htlc/{id}/claim requests.
+// Releases the HTLC to its recipient, the fee is taken from the released amount.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Recipient's wallet address
+//	  "preimage": "hex",        // Secret whose sha256 is the hash lock
+//	  "private_key": "hex",     // Recipient's private key (hex encoded)
+//	  "public_key": "hex"       // Recipient's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the preimage is wrong, the HTLC expired or is already settled
+func (handler *Handler) ClaimHTLC(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		Preimage   string `json:"preimage"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewHTLCClaimTransaction(input.From, chi.URLParam(r, "id"), input.Preimage)
+
+	handler.settleHTLC(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// RefundHTLC handles POST /api/htlc/{id}/refund requests.
+// Returns an expired HTLC to its sender, the fee is taken from the refunded amount.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's wallet address
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the HTLC has not expired yet or is already settled
+func (handler *Handler) RefundHTLC(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewHTLCRefundTransaction(input.From, chi.URLParam(r, "id"))
+
+	handler.settleHTLC(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+func (handler *Handler) settleHTLC(w http.ResponseWriter, tx *blockchain.Transaction, privateKey, publicKey string) {
+	if _, err := handler.Node.Blockchain.GetHTLC(tx.HTLC.HTLCId); errors.Is(err, blockchain.ErrHTLCNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, err)
+		return
+	}
+
+	if !handler.submitTransaction(w, tx, privateKey, publicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": tx.Hash().EncodeToString(),
+		"fee":              tx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
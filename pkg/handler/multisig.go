@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateMultisigAddress handles POST /api/multisig/address requests.
+// Derives the M-of-N address for a set of public keys and a threshold.
+// The key order does not matter, keys are sorted before the address is derived.
+//
+// Request body (JSON):
+//
+//	{
+//	  "public_keys": ["hex", "hex", "hex"],  // Key holders' public keys (hex encoded)
+//	  "threshold": 2                         // Signatures required to spend (M)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "address": "address",        // Multisig wallet address
+//	  "public_keys": [...],        // Sorted public keys
+//	  "threshold": 2
+//	}
+//
+// Response: 400 Bad Request if the key set or threshold is invalid
+func (handler *Handler) CreateMultisigAddress(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PublicKeys []string `json:"public_keys"`
+		Threshold  int      `json:"threshold"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sortedKeys, err := CryptoGraphy.SortPublicKeys(input.PublicKeys)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	address, err := CryptoGraphy.DeriveMultisigAddress(sortedKeys, input.Threshold)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := map[string]any{
+		"address":     address,
+		"public_keys": sortedKeys,
+		"threshold":   input.Threshold,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// CreateMultisigTransaction handles POST /api/multisig/tx requests.
+// Creates an unsigned transaction spending from a multisig address.
+// Key holders then add their signatures through /api/multisig/tx/{hash}/sign.
+//
+// Request body (JSON):
+//
+//	{
+//	  "public_keys": ["hex", "hex", "hex"],  // Key set of the multisig address
+//	  "threshold": 2,                        // Signatures required to spend (M)
+//	  "to": "address",                       // Recipient's wallet address
+//	  "amount": 1000                         // Amount to transfer
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",  // Hash every key holder signs
+//	  "transaction": {...},       // Unsigned transaction
+//	  "required_signatures": 2
+//	}
+//
+// Response: 400 Bad Request if the key set, threshold or amount is invalid
+func (handler *Handler) CreateMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PublicKeys []string `json:"public_keys"`
+		Threshold  int      `json:"threshold"`
+		To         string   `json:"to"`
+		Amount     uint64   `json:"amount"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Amount == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, "Your transaction amount must be more than 0")
+		return
+	}
+
+	if !handler.resolveAddresses(w, &input.To) {
+		return
+	}
+
+	newTx, err := blockchain.NewMultisigTransaction(input.PublicKeys, input.Threshold, input.To, input.Amount)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	newTx.Fee = handler.Node.Blockchain.CalculateFee(newTx)
+
+	txHash := handler.PartialTxs.Add(newTx)
+
+	resp := map[string]any{
+		"transaction_hash":    txHash,
+		"transaction":         newTx,
+		"required_signatures": newTx.Threshold,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetMultisigTransaction handles GET /api/multisig/tx/{hash} requests.
+// Returns a multisig transaction that is still collecting signatures.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction": {...},
+//	  "signatures_collected": 1,
+//	  "required_signatures": 2
+//	}
+//
+// Response: 404 Not Found if no pending multisig transaction has this hash
+func (handler *Handler) GetMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	tx, exists := handler.PartialTxs.Get(chi.URLParam(r, "hash"))
+	if !exists {
+		utils.WriteJSON(w, http.StatusNotFound, "multisig transaction not found")
+		return
+	}
+
+	resp := map[string]any{
+		"transaction":          tx,
+		"signatures_collected": len(tx.Signatures),
+		"required_signatures":  tx.Threshold,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// SignMultisigTransaction handles POST /api/multisig/tx/{hash}/sign requests.
+// Adds one key holder's partial signature to a pending multisig transaction.
+//
+// Request body (JSON):
+//
+//	{
+//	  "private_key": "hex",  // Key holder's private key (hex encoded)
+//	  "public_key": "hex"    // Key holder's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "signatures_collected": 2,
+//	  "required_signatures": 2
+//	}
+//
+// Response: 400 Bad Request if the key is not in the key set or has already signed
+func (handler *Handler) SignMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := handler.PartialTxs.AddSignature(chi.URLParam(r, "hash"), input.PrivateKey, input.PublicKey,
+		handler.Node.Blockchain.ChainId())
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, "Failed to sign transaction: "+err.Error())
+		return
+	}
+
+	resp := map[string]any{
+		"signatures_collected": len(tx.Signatures),
+		"required_signatures":  tx.Threshold,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// FinalizeMultisigTransaction handles POST /api/multisig/tx/{hash}/finalize requests.
+// Verifies that enough key holders signed, then validates and broadcasts the transaction.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "message": "...",
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if signatures are missing, validation fails or the mempool policy rejects it
+// Response: 404 Not Found if no pending multisig transaction has this hash
+func (handler *Handler) FinalizeMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	txHash := chi.URLParam(r, "hash")
+
+	tx, exists := handler.PartialTxs.Get(txHash)
+	if !exists {
+		utils.WriteJSON(w, http.StatusNotFound, "multisig transaction not found")
+		return
+	}
+
+	if len(tx.Signatures) < tx.Threshold {
+		utils.WriteJSON(w, http.StatusBadRequest,
+			fmt.Sprintf("not enough signatures: %d of %d", len(tx.Signatures), tx.Threshold))
+		return
+	}
+
+	if err := handler.Node.Blockchain.VerifyTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := handler.Node.Blockchain.ValidateTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := handler.Node.Blockchain.AddTransactionToMempool(tx); err != nil {
+		writeTxRejection(w, err)
+		return
+	}
+
+	handler.PartialTxs.Remove(txHash)
+
+	if err := handler.Node.BroadcastTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": txHash,
+		"message":          "Transaction added to mempool",
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
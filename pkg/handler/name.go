@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterName handles POST /api/names requests.
+// Registers an unused or expired name to the sender for a number of blocks. The
+// registration fee (blockchain.NameFee of the duration) is burned on top of the tx fee.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Owner's wallet address
+//	  "name": "alice",          // 3-32 lower case letters, digits or hyphens
+//	  "duration": 1000,         // Blocks the name is registered for
+//	  "private_key": "hex",     // Owner's private key (hex encoded)
+//	  "public_key": "hex"       // Owner's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "registration_fee": 1000,
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the name is taken, invalid or balance is insufficient
+func (handler *Handler) RegisterName(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		Name       string `json:"name"`
+		Duration   int64  `json:"duration"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewNameRegisterTransaction(input.From, input.Name, input.Duration)
+
+	handler.submitNameTransaction(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// GetName handles GET /api/names/{name} requests.
+// Returns the ledger entry of a name and whether it resolves at the current tip.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "name": "alice",
+//	  "owner": "address",       // Address the name resolves to
+//	  "expiry": 1150,           // Height from which the name no longer resolves
+//	  "registered_height": 150,
+//	  "last_tx": "hex",         // Id of the last register, renew or transfer
+//	  "active": true
+//	}
+//
+// Response: 404 Not Found if the name was never registered
+func (handler *Handler) GetName(w http.ResponseWriter, r *http.Request) {
+	bc := handler.Node.Blockchain
+
+	record, err := bc.GetName(chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, blockchain.ErrNameNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]any{
+		"name":              record.Name,
+		"owner":             record.Owner,
+		"expiry":            record.Expiry,
+		"registered_height": record.RegisteredHeight,
+		"last_tx":           record.LastTx,
+		"active":            record.IsActiveAt(bc.GetHeight()),
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// RenewName handles POST /api/names/{name}/renew requests.
+// Extends the expiry of an active name by a number of blocks, for the registration
+// fee of that duration.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Owner's wallet address
+//	  "duration": 1000,         // Blocks added to the expiry
+//	  "private_key": "hex",     // Owner's private key (hex encoded)
+//	  "public_key": "hex"       // Owner's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with the same JSON body as RegisterName
+//
+// Response: 400 Bad Request if the sender does not own the name or it has expired
+func (handler *Handler) RenewName(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		Duration   int64  `json:"duration"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewNameRenewTransaction(input.From, chi.URLParam(r, "name"), input.Duration)
+
+	handler.submitNameTransaction(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// TransferName handles POST /api/names/{name}/transfer requests.
+// Hands an active name to a new owner, its expiry stays the same.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Owner's wallet address
+//	  "to": "address",          // New owner, may itself be a name
+//	  "private_key": "hex",     // Owner's private key (hex encoded)
+//	  "public_key": "hex"       // Owner's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "registration_fee": 0,
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the sender does not own the name or it has expired
+func (handler *Handler) TransferName(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		To         string `json:"to"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewNameTransferTransaction(input.From, input.To, chi.URLParam(r, "name"))
+
+	handler.submitNameTransaction(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+func (handler *Handler) submitNameTransaction(w http.ResponseWriter, tx *blockchain.Transaction, privateKey, publicKey string) {
+	if !handler.submitTransaction(w, tx, privateKey, publicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": tx.Hash().EncodeToString(),
+		"registration_fee": tx.Amount,
+		"fee":              tx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// resolveAddresses -> Replaces every registered name among the values with the
+// address it resolves to at the current tip, other values are left as they are.
+// Writes the error response and returns false if a name does not resolve.
+func (handler *Handler) resolveAddresses(w http.ResponseWriter, values ...*string) bool {
+	for _, value := range values {
+		if !blockchain.IsValidName(*value) {
+			continue
+		}
+
+		address, err := handler.Node.Blockchain.ResolveName(*value)
+		if err != nil {
+			if errors.Is(err, blockchain.ErrNameNotFound) || errors.Is(err, blockchain.ErrNameExpired) {
+				utils.WriteJSON(w, http.StatusBadRequest, "Name "+*value+" does not resolve: "+err.Error())
+				return false
+			}
+
+			utils.WriteJSON(w, http.StatusInternalServerError, err)
+			return false
+		}
+
+		*value = address
+	}
+
+	return true
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetPeers handles GET /api/peers requests.
+// Returns every known peer with what it advertised in its version handshake.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "total": 1,
+//	  "peers": [
+//	    {
+//	      "address": "node2:8080",
+//	      "version": {                      // Omitted until a session completed the handshake
+//	        "protocol_version": 1,
+//	        "chain_id": "mainnet",
+//	        "address": "node2:8080",
+//	        "services": 1,
+//	        "best_height": 42,
+//	        "user_agent": "simple_blockchain/1.0"
+//	      },
+//	      "inbound": false,
+//	      "connected": true,
+//	      "connected_at": 1700000000,
+//	      "score": 20                         // Misbehavior so far, banned at 100
+//	    }
+//	  ]
+//	}
+func (handler *Handler) GetPeers(w http.ResponseWriter, r *http.Request) {
+	peers := handler.Node.GetPeers()
+
+	resp := map[string]any{
+		"total": len(peers),
+		"peers": peers,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetBans handles GET /api/peers/bans requests.
+// Returns the peers banned for misbehaving, oldest ban first. A banned peer is
+// neither dialed nor accepted, by its address or by the IP it connected from.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "total": 1,
+//	  "bans": [
+//	    {
+//	      "address": "node3:8080",
+//	      "ip": "10.0.0.3",
+//	      "reason": "invalid block",
+//	      "score": 100,
+//	      "banned_at": 1700000000000,      // Unix milliseconds
+//	      "expires_at": 1700086400000
+//	    }
+//	  ]
+//	}
+func (handler *Handler) GetBans(w http.ResponseWriter, r *http.Request) {
+	bans := handler.Node.GetBans()
+
+	resp := map[string]any{
+		"total": len(bans),
+		"bans":  bans,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// LiftBan handles DELETE /api/peers/bans/{address} requests.
+// Lifts the ban of a peer before it expires.
+//
+// Response: 200 OK with success message
+// Response: 404 Not Found if the peer is not banned
+// Response: 500 Internal Server Error if the ban cannot be removed
+func (handler *Handler) LiftBan(w http.ResponseWriter, r *http.Request) {
+	if err := handler.Node.Unban(chi.URLParam(r, "address")); err != nil {
+		if errors.Is(err, p2p.ErrPeerNotBanned) {
+			utils.WriteJSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "ban lifted successfully")
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/script"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// CreateScriptAddress handles POST /api/script/address requests.
+// Derives the address that commits to a spending script. Funds sent to it are spent
+// by a tx carrying the script and the witness items it runs on.
+//
+// Request body (JSON), one of:
+//
+//	{
+//	  "asm": "OP_SHA256 <hex> OP_EQUALVERIFY <pubkey> OP_CHECKSIG",  // Opcode names and hex data
+//	  "script": "hex"                                                  // Serialized script
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "address": "address",    // Script hash address
+//	  "script": "hex",
+//	  "asm": "..."             // Disassembled script
+//	}
+//
+// Response: 400 Bad Request if the script does not parse
+func (handler *Handler) CreateScriptAddress(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Asm    string `json:"asm"`
+		Script string `json:"script"`
+	}
+
+	if err := utils.ParseJSON(r, 50_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if (input.Asm == "") == (input.Script == "") {
+		utils.WriteJSON(w, http.StatusBadRequest, "exactly one of 'asm' and 'script' is required")
+		return
+	}
+
+	var spendScript script.Script
+	var err error
+	if input.Asm != "" {
+		spendScript, err = script.Assemble(input.Asm)
+	} else {
+		spendScript, err = script.Decode(input.Script)
+	}
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asm, err := script.Disassemble(spendScript)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := map[string]any{
+		"address": spendScript.Address(),
+		"script":  spendScript.String(),
+		"asm":     asm,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
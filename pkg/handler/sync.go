@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// GetSyncStatus handles GET /api/sync/status requests.
+// Returns the progress of the running block download, or of the last one.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "syncing": true,
+//	  "start_height": 120,               // Tip when the download started
+//	  "current_height": 450,             // Tip now, blocks are connected in height order
+//	  "target_height": 2120,
+//	  "in_flight": 64,                   // Block requests waiting for a peer
+//	  "peers": ["node2:8080", "node3:8080"],
+//	  "started_at": 1700000000000
+//	}
+func (handler *Handler) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, handler.Node.GetSyncStatus())
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// IssueToken handles POST /api/tokens requests.
+// Defines a new asset with `from` as its issuer, the initial supply is credited to
+// the issuer. The fee is paid in native coins.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Issuer's wallet address
+//	  "symbol": "GOLD",         // 1-12 upper case letters or digits, not unique
+//	  "decimals": 2,            // Display precision, up to 18
+//	  "max_supply": 1000000,    // Units that can ever exist
+//	  "initial_supply": 1000,   // Units credited to the issuer, optional
+//	  "private_key": "hex",     // Issuer's private key (hex encoded)
+//	  "public_key": "hex"       // Issuer's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "asset_id": "hex",          // Id used to mint, transfer and query the asset once mined
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if validation fails or the native balance cannot pay the fee
+func (handler *Handler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From          string `json:"from"`
+		Symbol        string `json:"symbol"`
+		Decimals      uint8  `json:"decimals"`
+		MaxSupply     uint64 `json:"max_supply"`
+		InitialSupply uint64 `json:"initial_supply"`
+		PrivateKey    string `json:"private_key"`
+		PublicKey     string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewTokenIssueTransaction(input.From, input.Symbol, input.Decimals,
+		input.MaxSupply, input.InitialSupply)
+
+	if !handler.submitTransaction(w, newTx, input.PrivateKey, input.PublicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"asset_id":         newTx.LedgerId(),
+		"transaction_hash": newTx.Hash().EncodeToString(),
+		"fee":              newTx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetTokens handles GET /api/tokens requests.
+// Returns every mined asset, oldest first.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "assets": [{...}]
+//	}
+func (handler *Handler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	assets, err := handler.Node.Blockchain.GetAssets()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]any{
+		"assets": assets,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetToken handles GET /api/tokens/{id} requests.
+// Returns the confirmed state of an asset.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "id": "hex",
+//	  "symbol": "GOLD",
+//	  "decimals": 2,
+//	  "max_supply": 1000000,
+//	  "supply": 1000,           // Units minted so far
+//	  "issuer": "address",
+//	  "issuer_public_key": "hex"
+//	}
+//
+// Response: 404 Not Found if no mined asset has this id
+func (handler *Handler) GetToken(w http.ResponseWriter, r *http.Request) {
+	asset, err := handler.Node.Blockchain.GetAsset(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, blockchain.ErrAssetNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, asset)
+}
+
+// GetTokenBalances handles GET /api/tokens/balances?address=<addr> requests.
+// Returns the confirmed balance of every asset the address holds.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "address": "address",
+//	  "balances": [{"asset_id": "hex", "symbol": "GOLD", "balance": 250}]
+//	}
+//
+// Response: 400 Bad Request if the address is missing
+func (handler *Handler) GetTokenBalances(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if address == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, "Address parameter required")
+		return
+	}
+
+	if !handler.resolveAddresses(w, &address) {
+		return
+	}
+
+	balances, err := handler.Node.Blockchain.GetTokenBalances(address)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]any{
+		"address":  address,
+		"balances": balances,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// MintToken handles POST /api/tokens/{id}/mint requests.
+// Creates new units of an asset for a recipient, only the issuer can mint and the
+// supply can never exceed the max supply.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Issuer's wallet address
+//	  "to": "address",          // Recipient of the new units
+//	  "amount": 500,            // Units to mint
+//	  "private_key": "hex",     // Issuer's private key (hex encoded)
+//	  "public_key": "hex"       // Issuer's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the sender is not the issuer or the max supply would be exceeded
+func (handler *Handler) MintToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Amount     uint64 `json:"amount"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewTokenMintTransaction(input.From, input.To, chi.URLParam(r, "id"), input.Amount)
+
+	handler.submitTokenTransaction(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// TransferToken handles POST /api/tokens/{id}/transfer requests.
+// Sends units of an asset, the fee is paid in native coins.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's wallet address
+//	  "to": "address",          // Recipient's wallet address
+//	  "amount": 250,            // Units to send
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the token or native balance is insufficient
+func (handler *Handler) TransferToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Amount     uint64 `json:"amount"`
+		PrivateKey string `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newTx := blockchain.NewTokenTransferTransaction(input.From, input.To, chi.URLParam(r, "id"), input.Amount)
+
+	handler.submitTokenTransaction(w, newTx, input.PrivateKey, input.PublicKey)
+}
+
+// submitTokenTransaction -> Submits a mint or transfer, 404 if its asset is not mined yet
+func (handler *Handler) submitTokenTransaction(w http.ResponseWriter, tx *blockchain.Transaction, privateKey, publicKey string) {
+	if _, err := handler.Node.Blockchain.GetAsset(tx.Token.AssetId); err != nil {
+		if errors.Is(err, blockchain.ErrAssetNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !handler.submitTransaction(w, tx, privateKey, publicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": tx.Hash().EncodeToString(),
+		"fee":              tx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
@@ -1,15 +1,28 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
 	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
 	"github.com/Nikolat27/simple_blockchain/pkg/utils"
 )
 
+// defaultFeeTarget -> Confirmation target used by /api/tx/fee when none is given
+const defaultFeeTarget = 6
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
 // SendTransaction handles POST /api/tx/send requests.
 // Creates, signs, validates, and broadcasts a new transaction to the network.
+// Any address may also be given as a registered name, see RegisterName.
+// The signature is bound to the node's chain id and is invalid on any other chain.
 //
 // Request body (JSON):
 //
@@ -18,7 +31,25 @@ import (
 //	  "to": "address",          // Recipient's wallet address
 //	  "amount": 1000,           // Amount to transfer
 //	  "private_key": "hex",     // Sender's private key (hex encoded)
-//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	  "public_key": "hex",      // Sender's public key (hex encoded)
+//	  "lock_time": 120,         // Optional: earliest block height, or unix ms timestamp (>= 500000000)
+//	  "data": "INV-2024-0042",  // Optional: memo or payment reference, up to 256 bytes
+//	  "fee_payer_public_key": "hex",   // Optional: sponsor paying the fee instead of the sender
+//	  "fee_payer_private_key": "hex",  // Required with fee_payer_public_key, sponsor co-signs
+//	  "chain_id": "mainnet"     // Optional: rejected unless it is the node's chain id
+//	}
+//
+// Batch transfer: instead of "to" and "amount", send "outputs" to pay many
+// recipients (up to 1000) with one signature and one fee:
+//
+//	{
+//	  "from": "address",
+//	  "outputs": [
+//	    {"to": "address1", "amount": 1000},
+//	    {"to": "address2", "amount": 2500}
+//	  ],
+//	  "private_key": "hex",
+//	  "public_key": "hex"
 //	}
 //
 // Response: 200 OK with JSON body:
@@ -26,13 +57,25 @@ import (
 //	{
 //	  "transaction_hash": "hex",  // Hash of the transaction
 //	  "message": "...",           // Success message
-//	  "amount": 1000,             // Amount recipient receives
+//	  "amount": 1000,             // Amount recipient receives (sum of outputs for a batch)
+//	  "outputs": 2,               // Number of recipients, only for a batch
 //	  "fee": 10,                  // Fee paid to miner
-//	  "total_cost": 1010,         // Total deducted from sender
+//	  "total_cost": 1010,         // Total deducted from sender (amount only if sponsored)
+//	  "fee_payer": "address",     // Sponsor's address, only if sponsored
+//	  "lock_time": 120,           // Lock time, 0 if the transaction is not time locked
+//	  "chain_id": "mainnet",      // Chain the signature is bound to
 //	  "status": "pending"
 //	}
 //
+// Time locked transactions are held in the mempool and are only mined once final.
+//
 // Response: 400 Bad Request if validation fails or insufficient balance
+// Response: 400 Bad Request with JSON body if the mempool policy rejects the transaction:
+//
+//	{
+//	  "error": "...",       // Human-readable explanation
+//	  "reason": "dust"      // sender_tx_limit, sender_byte_limit, dust, fee_too_low, tx_too_large, mempool_full
+//	}
 func (handler *Handler) SendTransaction(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		From       string `json:"from"`
@@ -40,36 +83,113 @@ func (handler *Handler) SendTransaction(w http.ResponseWriter, r *http.Request)
 		Amount     uint64 `json:"amount"`
 		PrivateKey string `json:"private_key"`
 		PublicKey  string `json:"public_key"`
+		LockTime   int64  `json:"lock_time"`
+		Data       string `json:"data"`
+
+		Outputs []blockchain.TxOutput `json:"outputs"`
+
+		FeePayerPublicKey  string `json:"fee_payer_public_key"`
+		FeePayerPrivateKey string `json:"fee_payer_private_key"`
+
+		ChainId string `json:"chain_id"`
 	}
 
-	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+	if err := utils.ParseJSON(r, 200_000, &input); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	chainId := handler.Node.Blockchain.ChainId()
+	if input.ChainId != "" && input.ChainId != chainId {
+		utils.WriteJSON(w, http.StatusBadRequest,
+			fmt.Sprintf("chain_id %q does not match this node's chain %q", input.ChainId, chainId))
+		return
+	}
+
+	if !handler.resolveAddresses(w, &input.From, &input.To) {
+		return
+	}
+
+	for idx := range input.Outputs {
+		if !handler.resolveAddresses(w, &input.Outputs[idx].To) {
+			return
+		}
+	}
+
+	if len(input.Outputs) > 0 {
+		if input.To != "" || input.Amount != 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, "Use either 'to' and 'amount' or 'outputs', not both")
+			return
+		}
+
+		if len(input.Outputs) > blockchain.MaxTxOutputs {
+			utils.WriteJSON(w, http.StatusBadRequest,
+				fmt.Sprintf("A batch transaction can have at most %d outputs", blockchain.MaxTxOutputs))
+			return
+		}
+
+		for idx, output := range input.Outputs {
+			if output.To == "" || output.Amount == 0 {
+				utils.WriteJSON(w, http.StatusBadRequest,
+					fmt.Sprintf("Output %d needs a recipient and an amount more than 0", idx))
+				return
+			}
+		}
+
+		total, err := blockchain.SumOutputs(input.Outputs)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		input.Amount = total
+	}
+
 	if input.Amount == 0 {
 		utils.WriteJSON(w, http.StatusBadRequest, "Your transaction amount must be more than 0")
 		return
 	}
 
+	if input.LockTime < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, "lock_time must not be negative")
+		return
+	}
+
+	if len(input.Data) > blockchain.MaxTxDataSize {
+		utils.WriteJSON(w, http.StatusBadRequest,
+			fmt.Sprintf("data must not exceed %d bytes", blockchain.MaxTxDataSize))
+		return
+	}
+
 	newTx := blockchain.Transaction{
 		From:       input.From,
 		To:         input.To,
 		Amount:     input.Amount, // Full amount recipient receives
+		PublicKey:  input.PublicKey,
 		Status:     "pending",
 		Timestamp:  utils.GetTimestamp(),
+		LockTime:   input.LockTime,
+		Data:       input.Data,
+		Outputs:    input.Outputs,
 		IsCoinbase: false,
 	}
 
-	txFee := handler.Node.Blockchain.Mempool.CalculateFee(&newTx)
-
-	newTx.Fee = txFee
+	if input.FeePayerPublicKey != "" {
+		feePayer, err := CryptoGraphy.DeriveAddressFromPublicKey(input.FeePayerPublicKey)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, "Invalid fee payer public key format")
+			return
+		}
 
-	if handler.Node.Blockchain.Mempool.WillExceedCapacity(&newTx) {
-		utils.WriteJSON(w, http.StatusBadRequest, "Mempool capacity exceeded. Try again later")
-		return
+		// Set before signing, the sender's signature covers the sponsor
+		newTx.FeePayer = feePayer
+		newTx.FeePayerPublicKey = input.FeePayerPublicKey
 	}
 
+	txFee := handler.Node.Blockchain.CalculateFee(&newTx)
+
+	newTx.Fee = txFee
+
 	// Validate that the 'from' address matches the public key
 	derivedAddress, err := CryptoGraphy.DeriveAddressFromPublicKey(input.PublicKey)
 	if err != nil {
@@ -84,12 +204,19 @@ func (handler *Handler) SendTransaction(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Sign the transaction with the provided keys
-	if err := newTx.SignWithHexKeys(input.PrivateKey, input.PublicKey); err != nil {
+	if err := newTx.SignWithHexKeys(input.PrivateKey, input.PublicKey, chainId); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, "Failed to sign transaction: "+err.Error())
 		return
 	}
 
-	if !newTx.Verify() {
+	if newTx.HasFeePayer() {
+		if err := newTx.SignAsFeePayerWithHexKeys(input.FeePayerPrivateKey, input.FeePayerPublicKey, chainId); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, "Failed to sign transaction as fee payer: "+err.Error())
+			return
+		}
+	}
+
+	if !newTx.Verify(chainId) {
 		utils.WriteJSON(w, http.StatusBadRequest, "Invalid signature")
 		return
 	}
@@ -99,25 +226,115 @@ func (handler *Handler) SendTransaction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	handler.Node.Blockchain.Mempool.AddTransaction(&newTx)
+	if err := handler.Node.Blockchain.AddTransactionToMempool(&newTx); err != nil {
+		writeTxRejection(w, err)
+		return
+	}
 
-	if err := handler.Node.BroadcastMempool(handler.Node.Blockchain.Mempool); err != nil {
+	if err := handler.Node.BroadcastTransaction(&newTx); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, err)
 		return
 	}
 
+	message := "Transaction added to mempool"
+
+	bc := handler.Node.Blockchain
+	nextHeight := bc.GetHeight() + 1
+	if !newTx.IsFinal(nextHeight, bc.MedianTimePast(nextHeight)) {
+		message = "Transaction held in mempool until its lock time is reached"
+	}
+
 	resp := map[string]any{
 		"transaction_hash": newTx.Hash().EncodeToString(),
-		"message":          "Transaction added to mempool",
+		"message":          message,
 		"amount":           input.Amount,         // Amount recipient receives
 		"fee":              txFee,                // Fee paid to miner
 		"total_cost":       input.Amount + txFee, // Total cost to sender
+		"lock_time":        newTx.LockTime,
+		"chain_id":         chainId,
 		"status":           "pending",
 	}
 
+	if newTx.IsBatch() {
+		resp["outputs"] = len(newTx.Outputs)
+	}
+
+	if newTx.HasFeePayer() {
+		resp["fee_payer"] = newTx.FeePayer
+		resp["total_cost"] = input.Amount
+	}
+
 	utils.WriteJSON(w, http.StatusOK, resp)
 }
 
+// submitTransaction -> Prices, signs, validates and broadcasts a tx built by an
+// API handler. Writes the error response and returns false if any step fails.
+func (handler *Handler) submitTransaction(w http.ResponseWriter, tx *blockchain.Transaction,
+	privateKey, publicKey string) bool {
+
+	if !handler.resolveAddresses(w, &tx.From, &tx.To) {
+		return false
+	}
+
+	derivedAddress, err := CryptoGraphy.DeriveAddressFromPublicKey(publicKey)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, "Invalid public key format")
+		return false
+	}
+
+	if derivedAddress != tx.From {
+		utils.WriteJSON(w, http.StatusBadRequest,
+			"'From' address does not match the provided public key")
+		return false
+	}
+
+	bc := handler.Node.Blockchain
+
+	tx.PublicKey = publicKey
+	tx.Fee = bc.CalculateFee(tx)
+
+	if err := tx.SignWithHexKeys(privateKey, publicKey, handler.Node.Blockchain.ChainId()); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, "Failed to sign transaction: "+err.Error())
+		return false
+	}
+
+	if err := bc.VerifyTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := bc.ValidateTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := bc.AddTransactionToMempool(tx); err != nil {
+		writeTxRejection(w, err)
+		return false
+	}
+
+	if err := handler.Node.BroadcastTransaction(tx); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+// writeTxRejection -> Policy rejections carry a machine-readable reason
+func writeTxRejection(w http.ResponseWriter, err error) {
+	var policyErr *blockchain.PolicyError
+	if errors.As(err, &policyErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, map[string]string{
+			"error":  policyErr.Message,
+			"reason": policyErr.Reason,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusBadRequest, err)
+}
+
 // GetTransactions handles GET /api/txs requests.
 // Returns all pending transactions currently in the mempool.
 //
@@ -138,25 +355,115 @@ func (handler *Handler) GetTransactions(w http.ResponseWriter, r *http.Request)
 	utils.WriteJSON(w, http.StatusOK, resp)
 }
 
+// SearchTransactionsByData handles GET /api/txs/search requests.
+// Finds confirmed and pending transactions whose data field starts with the given prefix.
+// The match is case-sensitive.
+//
+// Query parameters:
+//   - data_prefix: Prefix of the transaction data to look for (required)
+//   - limit: Maximum number of confirmed transactions to return (optional, default: 100)
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "confirmed": [{"block_height": 12, "transaction": {...}}],  // Mined transactions
+//	  "pending": [...]                                            // Matching mempool transactions
+//	}
+//
+// Response: 400 Bad Request if data_prefix is missing or limit is invalid
+// Response: 500 Internal Server Error if database query fails
+func (handler *Handler) SearchTransactionsByData(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("data_prefix")
+	if prefix == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, "data_prefix parameter required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxSearchLimit {
+			utils.WriteJSON(w, http.StatusBadRequest,
+				fmt.Sprintf("limit must be a number between 1 and %d", maxSearchLimit))
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	confirmed, err := handler.Node.Blockchain.FindTransactionsByDataPrefix(prefix, limit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]any{
+		"confirmed": confirmed,
+		"pending":   handler.Node.Blockchain.Mempool.FindByDataPrefix(prefix),
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
 // GetCurrentTxFee handles GET /api/tx/fee requests.
-// Returns the current transaction fee rate based on mempool congestion.
+// Returns the fee rate (per byte) needed for a transaction to confirm within the target
+// number of blocks, based on how long recent transactions of each fee rate took to confirm.
+// Falls back to the next base fee plus the mempool congestion rate while there is not
+// enough history. The base fee part of a fee is burned, the rest goes to the miner.
+//
+// Query parameters:
+//   - target: Confirmation target in blocks, 1 to 25 (optional, default: 6)
 //
 // Response: 200 OK with JSON body:
 //
 //	{
-//	  "current_fee_percentage": 0.1,  // Fee as percentage (e.g., 0.1 = 0.1%)
-//	  "current_fee_basis": 10,        // Fee basis points (e.g., 10 = 0.1%)
-//	  "description": "..."            // Explanation of fee calculation
+//	  "target_blocks": 6,     // Requested confirmation target
+//	  "fee_rate": 11,         // Fee per byte of transaction size
+//	  "base_fee": 1,          // Burned fee per byte the next block requires at least
+//	  "confidence": 0.92,     // Share of recent txs at this rate confirmed within target
+//	  "source": "estimator",  // "estimator" or "mempool_congestion"
+//	  "description": "..."    // Explanation of fee calculation
 //	}
+//
+// Response: 400 Bad Request if target is not a number or is out of range
 func (handler *Handler) GetCurrentTxFee(w http.ResponseWriter, r *http.Request) {
-	txFee := handler.Node.Blockchain.Mempool.CalculateTxFee()
+	target := defaultFeeTarget
+
+	if targetStr := r.URL.Query().Get("target"); targetStr != "" {
+		parsedTarget, err := strconv.Atoi(targetStr)
+		if err != nil || parsedTarget < 1 || parsedTarget > blockchain.MaxConfirmTarget {
+			utils.WriteJSON(w, http.StatusBadRequest,
+				fmt.Sprintf("target must be a number between 1 and %d", blockchain.MaxConfirmTarget))
+			return
+		}
+
+		target = parsedTarget
+	}
+
+	bc := handler.Node.Blockchain
+	baseFee := bc.NextBaseFee()
+	source := "estimator"
+
+	estimate, err := bc.FeeEstimator.EstimateFee(target)
+	if err != nil {
+		source = "mempool_congestion"
+		estimate = &blockchain.FeeEstimate{
+			TargetBlocks: target,
+			FeeRate:      baseFee + bc.Mempool.CalculateTxFee(),
+			Confidence:   0,
+		}
+	}
 
-	txFeePercentage := float64(txFee) / 100
+	// Recent rates may no longer cover a risen base fee
+	estimate.FeeRate = max(estimate.FeeRate, baseFee)
 
 	resp := map[string]any{
-		"current_fee_percentage": txFeePercentage,
-		"current_fee_basis":      txFee,
-		"description":            "Fee is calculated as (amount * fee_basis) / 10000",
+		"target_blocks": estimate.TargetBlocks,
+		"fee_rate":      estimate.FeeRate,
+		"base_fee":      baseFee,
+		"confidence":    estimate.Confidence,
+		"source":        source,
+		"description":   "Fee is calculated as fee_rate * transaction size in bytes",
 	}
 
 	utils.WriteJSON(w, http.StatusOK, resp)
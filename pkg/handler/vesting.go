@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// CreateVesting handles POST /api/vesting requests.
+// Pays coins into a vesting account of the recipient. They are locked until the
+// start height and unlock linearly until the end height.
+//
+// Request body (JSON):
+//
+//	{
+//	  "from": "address",        // Sender's wallet address
+//	  "to": "address",          // Vesting account's address
+//	  "amount": 100000,         // Amount to vest
+//	  "start_height": 100,      // Nothing is spendable below this height
+//	  "end_height": 10100,      // Everything is spendable from this height on
+//	  "private_key": "hex",     // Sender's private key (hex encoded)
+//	  "public_key": "hex"       // Sender's public key (hex encoded)
+//	}
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "transaction_hash": "hex",
+//	  "fee": 10,
+//	  "status": "pending"
+//	}
+//
+// Response: 400 Bad Request if the schedule is invalid or balance is insufficient
+func (handler *Handler) CreateVesting(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From        string `json:"from"`
+		To          string `json:"to"`
+		Amount      uint64 `json:"amount"`
+		StartHeight int64  `json:"start_height"`
+		EndHeight   int64  `json:"end_height"`
+		PrivateKey  string `json:"private_key"`
+		PublicKey   string `json:"public_key"`
+	}
+
+	if err := utils.ParseJSON(r, 10_000, &input); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Amount == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, "Your transaction amount must be more than 0")
+		return
+	}
+
+	newTx := blockchain.NewVestingTransaction(input.From, input.To, input.Amount, input.StartHeight, input.EndHeight)
+
+	if !handler.submitTransaction(w, newTx, input.PrivateKey, input.PublicKey) {
+		return
+	}
+
+	resp := map[string]any{
+		"transaction_hash": newTx.Hash().EncodeToString(),
+		"fee":              newTx.Fee,
+		"status":           "pending",
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetVesting handles GET /api/vesting?address=<addr> requests.
+// Returns the vesting schedules of an address and how much of them is unlocked
+// in the next block.
+//
+// Response: 200 OK with JSON body:
+//
+//	{
+//	  "address": "address",
+//	  "schedules": [{"amount": 100000, "start_height": 100, "end_height": 10100, ...}],
+//	  "vested": 2500,
+//	  "unvested": 97500
+//	}
+//
+// Response: 400 Bad Request if the address is missing
+func (handler *Handler) GetVesting(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if address == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, "Address parameter required")
+		return
+	}
+
+	if !handler.resolveAddresses(w, &address) {
+		return
+	}
+
+	bc := handler.Node.Blockchain
+
+	schedules, err := bc.GetVestingSchedules(address)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	vesting, err := bc.GetVestingBalance(address, bc.GetHeight()+1)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]any{
+		"address":   address,
+		"schedules": schedules,
+		"vested":    vesting.Vested,
+		"unvested":  vesting.Unvested,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
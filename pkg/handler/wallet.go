@@ -11,12 +11,14 @@ import (
 // Returns the confirmed balance for a given wallet address, accounting for pending transactions.
 //
 // Query parameters:
-//   - address: The wallet address to check (required)
+//   - address: The wallet address or registered name to check (required)
 //
 // Response: 200 OK with JSON body:
 //
 //	{
-//	  "balance": 10000  // Available balance in base units
+//	  "balance": 10000,  // Available balance in base units, unvested coins excluded
+//	  "vested": 2500,    // Unlocked part of the address's vesting accounts
+//	  "unvested": 7500   // Part still locked in the next block
 //	}
 //
 // Response: 400 Bad Request if address parameter is missing
@@ -28,14 +30,28 @@ func (handler *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	balance, err := handler.Node.Blockchain.GetBalance(address)
+	if !handler.resolveAddresses(w, &address) {
+		return
+	}
+
+	bc := handler.Node.Blockchain
+
+	balance, err := bc.GetBalance(address)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	vesting, err := bc.GetVestingBalance(address, bc.GetHeight()+1)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	resp := map[string]any{
-		"balance": balance,
+		"balance":  balance,
+		"vested":   vesting.Vested,
+		"unvested": vesting.Unvested,
 	}
 
 	utils.WriteJSON(w, http.StatusOK, resp)
@@ -0,0 +1,325 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/database"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+const (
+	// BanThreshold -> Misbehavior score at which a peer is disconnected and banned
+	BanThreshold = 100
+
+	// DefaultBanDuration -> How long a ban lasts unless it is lifted
+	DefaultBanDuration = 24 * time.Hour
+)
+
+var (
+	ErrPeerBanned    = errors.New("peer is banned")
+	ErrPeerNotBanned = errors.New("peer is not banned")
+)
+
+// violation -> Kind of misbehavior and how much it adds to the peer's score
+type violation struct {
+	reason  string
+	penalty int
+}
+
+var (
+	violationMalformedMessage   = violation{"malformed message", 10}
+	violationOversizedInventory = violation{"oversized inventory", 20}
+	violationInvalidTransaction = violation{"invalid transaction", 10}
+	violationMismatchedBlock    = violation{"block does not match its header", 50}
+	violationCorruptHeaders     = violation{"corrupted headers", 50}
+	violationInvalidBlock       = violation{"invalid block", 100}
+)
+
+// misbehaviorError -> An error caused by the peer breaking the protocol
+type misbehaviorError struct {
+	violation violation
+	err       error
+}
+
+func (misbehavior *misbehaviorError) Error() string {
+	return misbehavior.err.Error()
+}
+
+func (misbehavior *misbehaviorError) Unwrap() error {
+	return misbehavior.err
+}
+
+func misbehavior(v violation, err error) error {
+	return &misbehaviorError{violation: v, err: err}
+}
+
+// Ban -> A peer refused until ExpiresAt, a unix timestamp in milliseconds
+type Ban struct {
+	Address   string `json:"address"`
+	IP        string `json:"ip"`
+	Reason    string `json:"reason"`
+	Score     int    `json:"score"`
+	BannedAt  int64  `json:"banned_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// penalize -> Adds to the score of session if err shows its peer misbehaved. Timeouts
+// and other failures that are not the peer's fault are ignored.
+func (node *Node) penalize(session *Session, err error) {
+	var misbehaviorErr *misbehaviorError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &misbehaviorErr):
+		node.misbehaving(session, misbehaviorErr.violation)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		node.misbehaving(session, violationMalformedMessage)
+	}
+}
+
+// penalizePeer -> Penalizes the session open to peerAddress, the one a failed request
+// to the peer went over
+func (node *Node) penalizePeer(peerAddress string, err error) {
+	node.sessionsMutex.Lock()
+	session := node.sessions[peerAddress]
+	node.sessionsMutex.Unlock()
+
+	if session != nil {
+		node.penalize(session, err)
+	}
+}
+
+// misbehaving -> Adds the penalty of v to the score of session and bans its peer once
+// the score reaches BanThreshold. The score belongs to the connection that misbehaved,
+// not to the address it claims, so nobody can get another peer banned.
+func (node *Node) misbehaving(session *Session, v violation) {
+	// Peers that have not completed the handshake are disconnected anyway
+	if !session.isHandshaked() {
+		return
+	}
+
+	session.mutex.Lock()
+	session.score += v.penalty
+	score := session.score
+	session.mutex.Unlock()
+
+	peerAddress := session.PeerAddress()
+	log.Printf("Peer %s (%s) misbehaved: %s (+%d, score %d)", peerAddress, session.conn.RemoteAddr(), v.reason, v.penalty, score)
+
+	if score >= BanThreshold {
+		if err := node.banPeer(session, v.reason, score); err != nil {
+			log.Printf("Failed to ban peer %s: %v", peerAddress, err)
+		}
+	}
+}
+
+// banPeer -> Stores the ban of the address and IP of session, forgets the peer and
+// closes the session
+func (node *Node) banPeer(session *Session, reason string, score int) error {
+	peerAddress := session.PeerAddress()
+	ip := remoteIP(session.conn.RemoteAddr())
+
+	now := utils.GetTimestamp()
+	ban := &Ban{
+		Address:   peerAddress,
+		IP:        ip,
+		Reason:    reason,
+		Score:     score,
+		BannedAt:  now,
+		ExpiresAt: now + node.BanDuration.Milliseconds(),
+	}
+
+	sqlTx, err := node.Blockchain.Database.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	if err := node.Blockchain.Database.AddBan(sqlTx, database.DBBanSchema(*ban)); err != nil {
+		return err
+	}
+
+	if err := node.Blockchain.Database.RemovePeer(sqlTx, peerAddress); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return err
+	}
+
+	node.bansMutex.Lock()
+	node.bans[peerAddress] = ban
+	node.bansMutex.Unlock()
+
+	node.Mutex.Lock()
+	delete(node.Peers, peerAddress)
+	node.Mutex.Unlock()
+
+	node.closeSession(session)
+
+	log.Printf("Banned peer %s (%s) until %d: %s", peerAddress, ip, ban.ExpiresAt, reason)
+
+	return nil
+}
+
+// sessionScores -> Misbehavior score of every registered session, by peer address
+func (node *Node) sessionScores() map[string]int {
+	node.sessionsMutex.Lock()
+	defer node.sessionsMutex.Unlock()
+
+	scores := make(map[string]int, len(node.sessions))
+	for address, session := range node.sessions {
+		session.mutex.RLock()
+		scores[address] = session.score
+		session.mutex.RUnlock()
+	}
+
+	return scores
+}
+
+// isBanned -> Whether a ban covers the peer's address or, with BanIPs, the IP it
+// connects from; either may be empty
+func (node *Node) isBanned(peerAddress, ip string) bool {
+	node.bansMutex.Lock()
+	defer node.bansMutex.Unlock()
+
+	now := utils.GetTimestamp()
+	for address, ban := range node.bans {
+		if ban.ExpiresAt <= now {
+			delete(node.bans, address)
+			continue
+		}
+
+		if (peerAddress != "" && ban.Address == peerAddress) || (node.BanIPs && ip != "" && ban.IP == ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadBans -> Enforces the bans stored by a previous run
+func (node *Node) LoadBans() error {
+	records, err := node.Blockchain.Database.GetActiveBans(utils.GetTimestamp())
+	if err != nil {
+		return err
+	}
+
+	node.bansMutex.Lock()
+	defer node.bansMutex.Unlock()
+
+	for _, record := range records {
+		ban := Ban(record)
+		node.bans[ban.Address] = &ban
+	}
+
+	return nil
+}
+
+// GetBans -> Bans in effect, oldest first
+func (node *Node) GetBans() []Ban {
+	node.bansMutex.Lock()
+	defer node.bansMutex.Unlock()
+
+	now := utils.GetTimestamp()
+
+	bans := make([]Ban, 0, len(node.bans))
+	for _, ban := range node.bans {
+		if ban.ExpiresAt > now {
+			bans = append(bans, *ban)
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].BannedAt < bans[j].BannedAt
+	})
+
+	return bans
+}
+
+// Unban -> Lifts the ban of peerAddress
+func (node *Node) Unban(peerAddress string) error {
+	node.bansMutex.Lock()
+	defer node.bansMutex.Unlock()
+
+	ban, ok := node.bans[peerAddress]
+	if !ok || ban.ExpiresAt <= utils.GetTimestamp() {
+		return ErrPeerNotBanned
+	}
+
+	sqlTx, err := node.Blockchain.Database.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	if err := node.Blockchain.Database.RemoveBan(sqlTx, peerAddress); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return err
+	}
+
+	delete(node.bans, peerAddress)
+
+	log.Printf("Lifted the ban of peer %s", peerAddress)
+
+	return nil
+}
+
+// connectPeerBlock -> Connects a block a peer sent, blaming the peer only if the block
+// extends our tip and its header does not match its contents or lacks proof of work
+func (node *Node) connectPeerBlock(block *blockchain.Block) error {
+	err := node.connectBlock(block)
+	if err == nil || node.Blockchain.GetBlockByHash(block.Hash) != nil {
+		return nil
+	}
+
+	if parent := node.Blockchain.GetBlockByHash(block.PrevHash); parent == nil || parent.Id < node.Blockchain.GetHeight() {
+		return fmt.Errorf("block %d no longer extends our tip: %w", block.Id, err)
+	}
+
+	// Only checks every node agrees on whatever its state earn a ban, the txs may be
+	// rejected because of our state rather than the peer's
+	intact, hashErr := hasValidProofOfWork(block)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	if !intact {
+		return misbehavior(violationInvalidBlock, err)
+	}
+
+	return err
+}
+
+// hasValidProofOfWork -> Whether the header hash of block matches its contents,
+// merkle root included, and meets the difficulty
+func hasValidProofOfWork(block *blockchain.Block) (bool, error) {
+	computed := *block
+	computed.MerkleRoot = nil
+	if err := computed.HashBlock(); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(computed.Hash, block.Hash) && block.IsValidHash(), nil
+}
+
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
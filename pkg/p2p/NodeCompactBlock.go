@@ -0,0 +1,131 @@
+package p2p
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+// compactBlockTimeout -> Longest the missing txs of a compact block, or the full block
+// instead, may take to arrive
+const compactBlockTimeout = 30 * time.Second
+
+// handleCompactBlock -> Rebuilds a block announced as a compact block from our mempool,
+// then accepts it like a full block
+func (node *Node) handleCompactBlock(session *Session, payload types.Payload) error {
+	var compact blockchain.CompactBlock
+	if err := payload.Unmarshal(&compact); err != nil {
+		return fmt.Errorf("failed to unmarshal compact block: %w", err)
+	}
+
+	hash := hex.EncodeToString(compact.Header.Hash)
+	session.markKnown(hash)
+
+	if !node.seenBlocks.add(hash) || node.Blockchain.GetBlockByHash(compact.Header.Hash) != nil {
+		return nil
+	}
+
+	// Missing txs are requested over this session, whose read loop must keep running
+	go node.reconstructBlock(session, &compact)
+
+	return nil
+}
+
+func (node *Node) reconstructBlock(session *Session, compact *blockchain.CompactBlock) {
+	peer := session.PeerAddress()
+
+	block, err := node.rebuildCompactBlock(peer, compact)
+	if err != nil {
+		log.Printf("Failed to rebuild compact block %d from %s, requesting the full block: %v", compact.Header.Id, peer, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), compactBlockTimeout)
+		defer cancel()
+
+		block, err = node.requestBlock(ctx, peer, &compact.Header)
+		if err != nil {
+			log.Printf("Failed to download block %d from %s: %v", compact.Header.Id, peer, err)
+			node.penalize(session, err)
+			return
+		}
+	}
+
+	if err := node.acceptBlock(block, peer); err != nil {
+		log.Printf("Failed to accept block %d from %s: %v", block.Id, peer, err)
+		node.penalize(session, err)
+	}
+}
+
+// rebuildCompactBlock -> Fills compact in from our mempool, asking peer for the txs
+// that are not in it
+func (node *Node) rebuildCompactBlock(peer string, compact *blockchain.CompactBlock) (*blockchain.Block, error) {
+	block, missing := compact.Rebuild(node.Blockchain.Mempool)
+
+	var txs []blockchain.Transaction
+	if len(missing) > 0 {
+		requestPayload, err := json.Marshal(types.GetBlockTxsPayload{
+			BlockHash: compact.Header.Hash,
+			Indexes:   missing,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), compactBlockTimeout)
+		defer cancel()
+
+		payload, err := node.request(ctx, peer, types.GetBlockTxsMsg, types.BlockTxsMsg, requestPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := payload.Unmarshal(&txs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal block transactions: %w", err)
+		}
+	}
+
+	if err := compact.Complete(block, missing, txs); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// handleGetBlockTxs -> Replies with the asked for txs of a block we relayed, none if
+// the block is unknown
+func (node *Node) handleGetBlockTxs(session *Session, requestId uint64, payload types.Payload) error {
+	var request types.GetBlockTxsPayload
+	if err := payload.Unmarshal(&request); err != nil {
+		return fmt.Errorf("failed to unmarshal get block txs payload: %w", err)
+	}
+
+	txs := []blockchain.Transaction{}
+
+	if block := node.Blockchain.GetBlockByHash(request.BlockHash); block != nil {
+		for _, idx := range request.Indexes {
+			// Index 0 is the first tx after the coinbase
+			if idx < 0 || idx+1 >= len(block.Transactions) {
+				return misbehavior(violationMalformedMessage, fmt.Errorf("block %d has no transaction %d", block.Id, idx+1))
+			}
+
+			txs = append(txs, block.Transactions[idx+1])
+		}
+	}
+
+	txsPayload, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	msg := types.NewMessage(types.BlockTxsMsg, node.GetCurrentTcpAddress(), txsPayload).WithRequestId(requestId)
+
+	return session.Send(ctx, msg.Marshal())
+}
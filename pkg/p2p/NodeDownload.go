@@ -0,0 +1,409 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+const (
+	// downloadWindow -> Blocks requested or waiting to be connected, counted from the
+	// lowest block not connected yet
+	downloadWindow = 128
+
+	// maxInFlightPerPeer -> Block requests a single peer serves at once
+	maxInFlightPerPeer = 16
+
+	// maxPeerFailures -> Failed or stalled requests after which a peer is no longer asked
+	maxPeerFailures = 3
+
+	// DefaultBlockStallTimeout -> A block not received within it is requested elsewhere
+	DefaultBlockStallTimeout = 15 * time.Second
+)
+
+// SyncStatus -> Progress of the block download
+type SyncStatus struct {
+	Syncing       bool     `json:"syncing"`
+	StartHeight   int64    `json:"start_height"`
+	CurrentHeight int64    `json:"current_height"`
+	TargetHeight  int64    `json:"target_height"`
+	InFlight      int      `json:"in_flight"`
+	Peers         []string `json:"peers"`
+	StartedAt     int64    `json:"started_at,omitempty"`
+	Orphans       int      `json:"orphans"` // Announced blocks waiting for their parent
+}
+
+// blockResult -> Outcome of one block request
+type blockResult struct {
+	idx   int
+	peer  string
+	block *blockchain.Block
+	err   error
+}
+
+// downloadScheduler -> Spreads the blocks of headers over peers and hands them back
+// in height order. It is only used by the goroutine running the download.
+type downloadScheduler struct {
+	headers []blockchain.BlockHeader
+	peers   []string
+
+	inFlight map[int]string // header index -> peer asked for it
+	perPeer  map[string]int
+	failures map[string]int
+	failedBy map[int]map[string]bool
+	retry    []int
+	received map[int]*blockchain.Block
+
+	nextAssign  int
+	nextConnect int
+}
+
+func newDownloadScheduler(headers []blockchain.BlockHeader, peers []string) *downloadScheduler {
+	return &downloadScheduler{
+		headers:  headers,
+		peers:    peers,
+		inFlight: make(map[int]string),
+		perPeer:  make(map[string]int),
+		failures: make(map[string]int),
+		failedBy: make(map[int]map[string]bool),
+		received: make(map[int]*blockchain.Block),
+	}
+}
+
+// next -> Next block to request and the peer to ask, retries first
+func (scheduler *downloadScheduler) next() (int, string, bool) {
+	var idx int
+	switch {
+	case len(scheduler.retry) > 0:
+		idx = scheduler.retry[0]
+	case scheduler.nextAssign < len(scheduler.headers) && scheduler.nextAssign < scheduler.nextConnect+downloadWindow:
+		idx = scheduler.nextAssign
+	default:
+		return 0, "", false
+	}
+
+	peer := scheduler.pickPeer(idx)
+	if peer == "" {
+		return 0, "", false
+	}
+
+	if len(scheduler.retry) > 0 {
+		scheduler.retry = scheduler.retry[1:]
+	} else {
+		scheduler.nextAssign++
+	}
+
+	scheduler.inFlight[idx] = peer
+	scheduler.perPeer[peer]++
+
+	return idx, peer, true
+}
+
+// pickPeer -> Least busy peer with room for another request, avoiding peers that
+// already failed this block while there are others
+func (scheduler *downloadScheduler) pickPeer(idx int) string {
+	var best string
+	bestFailed := true
+
+	for _, peer := range scheduler.peers {
+		if scheduler.perPeer[peer] >= maxInFlightPerPeer {
+			continue
+		}
+
+		failed := scheduler.failedBy[idx][peer]
+		switch {
+		case best == "",
+			bestFailed && !failed,
+			bestFailed == failed && scheduler.perPeer[peer] < scheduler.perPeer[best]:
+			best, bestFailed = peer, failed
+		}
+	}
+
+	return best
+}
+
+// failed -> Requests the block again, from another peer if possible
+func (scheduler *downloadScheduler) failed(idx int, peer string) {
+	delete(scheduler.inFlight, idx)
+	scheduler.perPeer[peer]--
+	scheduler.failures[peer]++
+
+	if scheduler.failedBy[idx] == nil {
+		scheduler.failedBy[idx] = make(map[string]bool)
+	}
+	scheduler.failedBy[idx][peer] = true
+
+	if scheduler.failures[peer] >= maxPeerFailures {
+		for i, p := range scheduler.peers {
+			if p == peer {
+				scheduler.peers = append(scheduler.peers[:i], scheduler.peers[i+1:]...)
+				break
+			}
+		}
+	}
+
+	scheduler.retry = append(scheduler.retry, idx)
+}
+
+func (scheduler *downloadScheduler) receive(idx int, peer string, block *blockchain.Block) {
+	delete(scheduler.inFlight, idx)
+	scheduler.perPeer[peer]--
+	scheduler.received[idx] = block
+}
+
+// popConnectable -> Next block in height order once it has arrived
+func (scheduler *downloadScheduler) popConnectable() *blockchain.Block {
+	block, ok := scheduler.received[scheduler.nextConnect]
+	if !ok {
+		return nil
+	}
+
+	delete(scheduler.received, scheduler.nextConnect)
+	scheduler.nextConnect++
+
+	return block
+}
+
+func (scheduler *downloadScheduler) done() bool {
+	return scheduler.nextConnect == len(scheduler.headers)
+}
+
+// DownloadMissingBlocks -> Downloads the blocks of headers we do not have yet from
+// peerAddress and every other synced peer, and connects them in height order
+func (node *Node) DownloadMissingBlocks(ctx context.Context, peerAddress string, headers []blockchain.BlockHeader) error {
+	// One download at a time, blocks a previous one connected are skipped
+	node.downloadMutex.Lock()
+	defer node.downloadMutex.Unlock()
+
+	missing := make([]blockchain.BlockHeader, 0, len(headers))
+	for _, header := range headers {
+		if node.Blockchain.GetBlockByHash(header.Hash) == nil {
+			missing = append(missing, header)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	peers := node.downloadPeers(peerAddress, missing[len(missing)-1].Id)
+	scheduler := newDownloadScheduler(missing, slices.Clone(peers))
+
+	node.startSyncStatus(missing[len(missing)-1].Id, peers)
+	defer node.finishSyncStatus()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every request sends exactly one result and at most downloadWindow are in flight
+	results := make(chan blockResult, downloadWindow)
+
+	for !scheduler.done() {
+		for {
+			idx, peer, ok := scheduler.next()
+			if !ok {
+				break
+			}
+
+			go node.fetchBlock(ctx, idx, peer, &missing[idx], results)
+		}
+
+		node.setSyncInFlight(len(scheduler.inFlight))
+
+		if len(scheduler.inFlight) == 0 {
+			return fmt.Errorf("no peer left to download block %d from", missing[scheduler.nextConnect].Id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result := <-results:
+			if result.err != nil {
+				log.Printf("Failed to download block %d from %s: %v", missing[result.idx].Id, result.peer, result.err)
+				node.penalizePeer(result.peer, result.err)
+				scheduler.failed(result.idx, result.peer)
+				continue
+			}
+
+			scheduler.receive(result.idx, result.peer, result.block)
+
+			for block := scheduler.popConnectable(); block != nil; block = scheduler.popConnectable() {
+				// An orphan waiting on an earlier block may have connected it already
+				if node.Blockchain.GetBlockByHash(block.Hash) != nil {
+					continue
+				}
+
+				// The block matches the headers, so an invalid one is the fault of their source
+				if err := node.connectPeerBlock(block); err != nil {
+					return fmt.Errorf("failed to connect block %d: %w", block.Id, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// downloadPeers -> The peer the headers came from, then every other connected full
+// node that advertised at least targetHeight
+func (node *Node) downloadPeers(source string, targetHeight int64) []string {
+	peers := []string{source}
+
+	for _, peer := range node.GetPeers() {
+		if peer.Address == source || !peer.Connected || peer.Version == nil {
+			continue
+		}
+
+		if peer.Version.Services&ServiceFullNode == 0 || peer.Version.BestHeight < targetHeight {
+			continue
+		}
+
+		peers = append(peers, peer.Address)
+	}
+
+	return peers
+}
+
+// fetchBlock -> Requests the block of header from peer and checks that it matches
+func (node *Node) fetchBlock(ctx context.Context, idx int, peer string, header *blockchain.BlockHeader, results chan<- blockResult) {
+	ctx, cancel := context.WithTimeout(ctx, node.BlockStallTimeout)
+	defer cancel()
+
+	block, err := node.requestBlock(ctx, peer, header)
+	results <- blockResult{idx: idx, peer: peer, block: block, err: err}
+}
+
+func (node *Node) requestBlock(ctx context.Context, peer string, header *blockchain.BlockHeader) (*blockchain.Block, error) {
+	requestPayload, err := json.Marshal(header.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := node.request(ctx, peer, types.RequestBlockMsg, types.SendBlockMsg, requestPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	var block blockchain.Block
+	if err := payload.Unmarshal(&block); err != nil {
+		return nil, err
+	}
+
+	if block.Id != header.Id {
+		return nil, misbehavior(violationMismatchedBlock,
+			fmt.Errorf("received block ID mismatch: expected %d, got %d", header.Id, block.Id))
+	}
+
+	// The hash commits to the transactions through the merkle root
+	computed := block
+	computed.MerkleRoot = nil
+	if err := computed.HashBlock(); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(block.Hash, header.Hash) || !bytes.Equal(computed.Hash, header.Hash) {
+		return nil, misbehavior(violationMismatchedBlock, fmt.Errorf("received block %d does not match its header", header.Id))
+	}
+
+	return &block, nil
+}
+
+// connectBlock -> Verifies block against our tip, adds it to the chain and connects
+// the orphans that were waiting on it
+func (node *Node) connectBlock(block *blockchain.Block) error {
+	if err := node.attachBlock(block); err != nil {
+		return err
+	}
+
+	node.connectOrphans(block)
+
+	return nil
+}
+
+// attachBlock -> Verifies block against our tip and adds it to the chain
+func (node *Node) attachBlock(block *blockchain.Block) error {
+	valid, err := node.Blockchain.VerifyBlock(block)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return errors.New("block is corrupted")
+	}
+
+	if err := node.Blockchain.VerifyBlockTransactions(block); err != nil {
+		return fmt.Errorf("block contains invalid transactions: %w", err)
+	}
+
+	sqlTx, err := node.Blockchain.Database.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	if err := node.Blockchain.AddBlock(sqlTx, block); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return err
+	}
+
+	node.Blockchain.AddBlockToMemory(block)
+
+	node.Blockchain.Mempool.DeleteMinedTransactions(block.Transactions)
+
+	return nil
+}
+
+func (node *Node) startSyncStatus(targetHeight int64, peers []string) {
+	node.syncMutex.Lock()
+	defer node.syncMutex.Unlock()
+
+	node.syncStatus = SyncStatus{
+		Syncing:      true,
+		StartHeight:  node.Blockchain.GetHeight(),
+		TargetHeight: targetHeight,
+		Peers:        peers,
+		StartedAt:    utils.GetTimestamp(),
+	}
+}
+
+func (node *Node) setSyncInFlight(inFlight int) {
+	node.syncMutex.Lock()
+	node.syncStatus.InFlight = inFlight
+	node.syncMutex.Unlock()
+}
+
+func (node *Node) finishSyncStatus() {
+	node.syncMutex.Lock()
+	defer node.syncMutex.Unlock()
+
+	node.syncStatus.Syncing = false
+	node.syncStatus.InFlight = 0
+}
+
+// GetSyncStatus -> Progress of the running block download, or of the last one
+func (node *Node) GetSyncStatus() SyncStatus {
+	node.syncMutex.Lock()
+	status := node.syncStatus
+	status.Peers = append([]string{}, status.Peers...)
+	node.syncMutex.Unlock()
+
+	status.CurrentHeight = node.Blockchain.GetHeight()
+	status.Orphans = node.orphans.count()
+	if !status.Syncing {
+		status.TargetHeight = max(status.TargetHeight, status.CurrentHeight)
+	}
+
+	return status
+}
@@ -0,0 +1,282 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+const (
+	// ProtocolVersion -> Version of the P2P protocol this node speaks
+	ProtocolVersion = 1
+
+	// MinProtocolVersion -> Oldest peer protocol version still accepted
+	MinProtocolVersion = 1
+
+	// UserAgent -> Software name sent in the version message
+	UserAgent = "simple_blockchain/1.0"
+
+	// DefaultHandshakeTimeout -> Longest a new session may take to exchange version and verack
+	DefaultHandshakeTimeout = 10 * time.Second
+
+	// maxHandshakeFrameSize -> Largest frame read before the handshake is done, a version
+	// message fits in it many times over
+	maxHandshakeFrameSize = 4 << 10
+)
+
+const (
+	// ServiceFullNode -> The node stores the full chain and serves blocks
+	ServiceFullNode uint64 = 1 << iota
+)
+
+var (
+	ErrHandshakeIncomplete = errors.New("peer has not completed the version handshake")
+	ErrAddressInUse        = errors.New("another session already holds the advertised address")
+)
+
+// Peer -> A known peer and, once a session completed the handshake, what it advertised
+type Peer struct {
+	Address     string                `json:"address"`
+	Version     *types.VersionPayload `json:"version,omitempty"`
+	Inbound     bool                  `json:"inbound"`
+	Connected   bool                  `json:"connected"`
+	ConnectedAt int64                 `json:"connected_at,omitempty"`
+	Score       int                   `json:"score"` // Misbehavior of its current session, banned at BanThreshold
+}
+
+// versionPayload -> What this node advertises to its peers
+func (node *Node) versionPayload() types.VersionPayload {
+	return types.VersionPayload{
+		ProtocolVersion: ProtocolVersion,
+		ChainId:         node.Blockchain.ChainId(),
+		GenesisHash:     node.Blockchain.GenesisHash(),
+		Address:         node.GetCurrentTcpAddress(),
+		Services:        ServiceFullNode,
+		BestHeight:      node.Blockchain.GetHeight(),
+		UserAgent:       UserAgent,
+	}
+}
+
+// sendVersion -> Opens the handshake on session
+func (node *Node) sendVersion(session *Session) error {
+	payload, err := json.Marshal(node.versionPayload())
+	if err != nil {
+		return err
+	}
+
+	session.mutex.Lock()
+	session.versionSent = true
+	session.mutex.Unlock()
+
+	msg := types.NewMessage(types.VersionMsg, node.GetCurrentTcpAddress(), payload)
+
+	ctx, cancel := context.WithTimeout(context.Background(), node.HandshakeTimeout)
+	defer cancel()
+
+	return session.Send(ctx, msg.Marshal())
+}
+
+// checkVersion -> Refuses peers on another chain, starting at another genesis block or
+// speaking an incompatible protocol
+func (node *Node) checkVersion(version *types.VersionPayload) error {
+	if version.ProtocolVersion < MinProtocolVersion {
+		return fmt.Errorf("protocol version %d is older than %d", version.ProtocolVersion, MinProtocolVersion)
+	}
+
+	if chainId := node.Blockchain.ChainId(); version.ChainId != chainId {
+		return fmt.Errorf("peer is on chain %q, not %q", version.ChainId, chainId)
+	}
+
+	if genesisHash := node.Blockchain.GenesisHash(); !bytes.Equal(version.GenesisHash, genesisHash) {
+		return fmt.Errorf("peer's chain starts at genesis block %x, not %x", version.GenesisHash, genesisHash)
+	}
+
+	if version.Address == "" {
+		return errors.New("peer did not advertise its address")
+	}
+
+	return nil
+}
+
+func (node *Node) handleVersion(session *Session, payload types.Payload) error {
+	var version types.VersionPayload
+	if err := payload.Unmarshal(&version); err != nil {
+		session.Close()
+		return fmt.Errorf("failed to unmarshal version payload: %w", err)
+	}
+
+	if err := node.checkVersion(&version); err != nil {
+		session.Close()
+		return fmt.Errorf("disconnecting %s: %w", session.conn.RemoteAddr(), err)
+	}
+
+	if node.isBanned(version.Address, "") {
+		session.Close()
+		return fmt.Errorf("disconnecting %s: %w", version.Address, ErrPeerBanned)
+	}
+
+	session.mutex.Lock()
+	duplicate := session.version != nil
+	if !duplicate {
+		session.version = &version
+	}
+	versionSent := session.versionSent
+	session.mutex.Unlock()
+
+	if duplicate {
+		session.Close()
+		return fmt.Errorf("peer %s sent a second version", session.PeerAddress())
+	}
+
+	if session.inbound {
+		if !node.registerSession(session, version.Address) {
+			session.Close()
+			return fmt.Errorf("disconnecting %s: %w", session.conn.RemoteAddr(), ErrAddressInUse)
+		}
+
+		if !versionSent {
+			if err := node.sendVersion(session); err != nil {
+				return err
+			}
+		}
+	}
+
+	msg := types.NewMessage(types.VerackMsg, node.GetCurrentTcpAddress(), types.Payload{})
+	if err := session.Send(context.Background(), msg.Marshal()); err != nil {
+		return err
+	}
+
+	node.completeHandshake(session)
+
+	return nil
+}
+
+func (node *Node) handleVerack(session *Session) error {
+	session.mutex.Lock()
+	versionSent := session.versionSent
+	if versionSent {
+		session.verackReceived = true
+	}
+	session.mutex.Unlock()
+
+	if !versionSent {
+		session.Close()
+		return fmt.Errorf("peer %s sent verack before receiving a version", session.conn.RemoteAddr())
+	}
+
+	node.completeHandshake(session)
+
+	return nil
+}
+
+// completeHandshake -> Marks session usable once both sides received a version and a verack
+func (node *Node) completeHandshake(session *Session) {
+	session.mutex.RLock()
+	version := session.version
+	done := version != nil && session.verackReceived
+	session.mutex.RUnlock()
+
+	if !done {
+		return
+	}
+
+	session.handshakeOnce.Do(func() {
+		node.setPeerConnected(session.PeerAddress(), version, session.inbound)
+		close(session.handshakeCh)
+
+		log.Printf("Handshake with %s done: %s, protocol %d, height %d", session.PeerAddress(), version.UserAgent, version.ProtocolVersion, version.BestHeight)
+	})
+}
+
+// waitHandshake -> Blocks until session completed the handshake
+func (session *Session) waitHandshake(ctx context.Context, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-session.handshakeCh:
+		return nil
+	case <-session.closeCh:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrHandshakeIncomplete
+	}
+}
+
+// enforceHandshakeDeadline -> Closes an inbound session whose peer does not complete
+// the handshake in time, so idle connections do not hold a session forever
+func (node *Node) enforceHandshakeDeadline(session *Session) {
+	if err := session.waitHandshake(context.Background(), node.HandshakeTimeout); errors.Is(err, ErrHandshakeIncomplete) {
+		log.Printf("Closing session with %s: %v", session.conn.RemoteAddr(), err)
+		node.closeSession(session)
+	}
+}
+
+// isHandshaked -> Reports whether the session completed the handshake
+func (session *Session) isHandshaked() bool {
+	select {
+	case <-session.handshakeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// setPeerConnected -> Records what the peer advertised during the handshake
+func (node *Node) setPeerConnected(address string, version *types.VersionPayload, inbound bool) {
+	node.Mutex.Lock()
+	defer node.Mutex.Unlock()
+
+	peer, ok := node.Peers[address]
+	if !ok {
+		peer = &Peer{Address: address}
+		node.Peers[address] = peer
+	}
+
+	peer.Version = version
+	peer.Inbound = inbound
+	peer.Connected = true
+	peer.ConnectedAt = utils.GetTimestamp()
+}
+
+// setPeerDisconnected -> Keeps the peer known but marks it as no longer connected
+func (node *Node) setPeerDisconnected(address string) {
+	node.Mutex.Lock()
+	defer node.Mutex.Unlock()
+
+	if peer, ok := node.Peers[address]; ok {
+		peer.Connected = false
+	}
+}
+
+// GetPeers -> Copy of every known peer
+func (node *Node) GetPeers() []Peer {
+	scores := node.sessionScores()
+
+	node.Mutex.RLock()
+	defer node.Mutex.RUnlock()
+
+	peers := make([]Peer, 0, len(node.Peers))
+	for _, peer := range node.Peers {
+		current := *peer
+		current.Score = scores[peer.Address]
+		peers = append(peers, current)
+	}
+
+	slices.SortFunc(peers, func(a, b Peer) int {
+		return strings.Compare(a.Address, b.Address)
+	})
+
+	return peers
+}
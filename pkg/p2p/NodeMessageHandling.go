@@ -2,48 +2,54 @@ package p2p
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
-	"slices"
 	"time"
 
-	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
 	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
 	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
 )
 
-func (node *Node) handleGetBlockHeaders(requestorAddr string) error {
+// handleGetBlockHeaders -> Replies with up to MaxHeadersPerMsg headers after the last
+// block of the requester's locator that is on our chain
+func (node *Node) handleGetBlockHeaders(session *Session, requestId uint64, payload types.Payload) error {
+	requestorAddr := session.PeerAddress()
 	if err := node.AddNewPeer(requestorAddr); err != nil {
 		log.Printf("Failed to add peer %s: %v", requestorAddr, err)
 		// Continue
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	blocks, err := node.Blockchain.GetAllBlocks()
-	if err != nil {
-		return err
+	// An empty request has an empty locator and starts at genesis
+	var request types.GetHeadersPayload
+	if len(payload) > 0 {
+		if err := payload.Unmarshal(&request); err != nil {
+			return fmt.Errorf("failed to unmarshal get headers payload: %w", err)
+		}
 	}
 
-	headers := make([]blockchain.BlockHeader, len(blocks))
-	for idx, block := range blocks {
-		headers[idx] = *block.GetHeader()
+	if len(request.Locator) > blockchain.MaxLocatorHashes {
+		return misbehavior(violationMalformedMessage,
+			fmt.Errorf("peer %s sent a locator of %d hashes, more than %d", requestorAddr, len(request.Locator), blockchain.MaxLocatorHashes))
 	}
 
-	payload, err := json.Marshal(headers)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	headers := node.Blockchain.HeadersAfter(request.Locator, blockchain.MaxHeadersPerMsg)
+
+	headersPayload, err := json.Marshal(headers)
 	if err != nil {
 		return err
 	}
 
-	newMessage := types.NewMessage(types.SendBlockHeadersMsg, node.GetCurrentTcpAddress(), payload)
+	newMessage := types.NewMessage(types.SendBlockHeadersMsg, node.GetCurrentTcpAddress(), headersPayload).WithRequestId(requestId)
 
-	return node.WriteMessage(ctx, requestorAddr, newMessage.Marshal())
+	return session.Send(ctx, newMessage.Marshal())
 }
 
-func (node *Node) handleGetBlock(requestorAddr string, blockId int64) error {
+func (node *Node) handleGetBlock(session *Session, requestId uint64, blockId int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -57,44 +63,14 @@ func (node *Node) handleGetBlock(requestorAddr string, blockId int64) error {
 		return err
 	}
 
-	msg := types.NewMessage(types.SendBlockMsg, node.GetCurrentTcpAddress(), payload)
+	msg := types.NewMessage(types.SendBlockMsg, node.GetCurrentTcpAddress(), payload).WithRequestId(requestId)
 
-	return node.WriteMessage(ctx, requestorAddr, msg.Marshal())
+	return session.Send(ctx, msg.Marshal())
 }
 
-func (node *Node) verifyBlockTransactions(block *blockchain.Block) error {
-	for _, tx := range block.Transactions {
-		if tx.IsCoinbase {
-			continue
-		}
-
-		if !tx.Verify() {
-			return fmt.Errorf("transaction %x has invalid signature", tx.Hash())
-		}
-
-		derivedAddress, err := CryptoGraphy.DeriveAddressFromPublicKey(tx.PublicKey)
-		if err != nil {
-			return err
-		}
-
-		if derivedAddress != tx.From {
-			return fmt.Errorf("transaction %x sender address mismatch", tx.Hash())
-		}
-
-		if tx.Amount == 0 {
-			return fmt.Errorf("transaction %x has zero amount", tx.Hash())
-		}
-
-		if tx.Amount+tx.Fee <= 0 {
-			return fmt.Errorf("transaction %x has invalid amount/fee combination", tx.Hash())
-		}
-	}
-
-	return nil
-}
-
-// handleBlockBroadcasting -> Propose the new block
-func (node *Node) handleBlockBroadcasting(payload types.Payload) error {
+// handleBlockBroadcasting -> Connects a block announced by a peer and relays it to
+// the others
+func (node *Node) handleBlockBroadcasting(session *Session, payload types.Payload) error {
 	var block blockchain.Block
 	if err := payload.Unmarshal(&block); err != nil {
 		return fmt.Errorf("failed to unmarshal broadcast block: %w", err)
@@ -102,52 +78,47 @@ func (node *Node) handleBlockBroadcasting(payload types.Payload) error {
 
 	log.Println("handleBlockBroadcasting Current Node: ", node.GetCurrentTcpAddress())
 
-	valid, err := node.Blockchain.VerifyBlock(&block)
-	if err != nil {
-		return err
-	}
+	hash := hex.EncodeToString(block.Hash)
+	session.markKnown(hash)
 
-	if !valid {
-		return errors.New("block is corrupted")
+	if !node.seenBlocks.add(hash) || node.Blockchain.GetBlockByHash(block.Hash) != nil {
+		return nil
 	}
 
-	if err := node.verifyBlockTransactions(&block); err != nil {
-		return fmt.Errorf("block contains invalid transactions: %w", err)
-	}
+	return node.acceptBlock(&block, session.PeerAddress())
+}
 
-	sqlTx, err := node.Blockchain.Database.BeginTx()
+// sendReject -> Lets the peer know why its transaction was rejected
+func (node *Node) sendReject(session *Session, txHash string, policyErr *blockchain.PolicyError) {
+	payload, err := json.Marshal(types.RejectPayload{
+		TxHash:  txHash,
+		Reason:  policyErr.Reason,
+		Message: policyErr.Message,
+	})
 	if err != nil {
-		return err
+		log.Println("failed to marshal reject payload: ", err)
+		return
 	}
-	defer sqlTx.Rollback()
 
-	if err := node.Blockchain.AddBlock(sqlTx, &block); err != nil {
-		return err
-	}
+	msg := types.NewMessage(types.RejectMsg, node.GetCurrentTcpAddress(), payload)
 
-	if err := sqlTx.Commit(); err != nil {
-		return err
-	}
-
-	node.Blockchain.AddBlockToMemory(&block)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 
-	node.Blockchain.Mempool.DeleteMinedTransactions(block.Transactions)
-
-	log.Println("New block verified successfully")
-
-	return nil
+		if err := session.Send(ctx, msg.Marshal()); err != nil {
+			log.Printf("Failed to send reject to %s: %v", session.PeerAddress(), err)
+		}
+	}()
 }
 
-// handleMempoolBroadcasting -> Propose the new mempool
-func (node *Node) handleMempoolBroadcasting(payload types.Payload) error {
-	var newMempool blockchain.Mempool
-	if err := payload.Unmarshal(&newMempool); err != nil {
-		return fmt.Errorf("failed to unmarshal broadcast block: %w", err)
+func (node *Node) handleReject(senderAddr string, payload types.Payload) error {
+	var reject types.RejectPayload
+	if err := payload.Unmarshal(&reject); err != nil {
+		return fmt.Errorf("failed to unmarshal reject payload: %w", err)
 	}
 
-	log.Println("handleMempoolBroadcasting Current Node: ", node.GetCurrentTcpAddress())
-
-	node.Blockchain.Mempool.SyncMempool(&newMempool)
+	log.Printf("Peer %s rejected tx %s: %s (%s)", senderAddr, reject.TxHash, reject.Message, reject.Reason)
 
 	return nil
 }
@@ -155,17 +126,23 @@ func (node *Node) handleMempoolBroadcasting(payload types.Payload) error {
 func (node *Node) handleCancelMining() error {
 	log.Println("handleCancelMining Current Node: ", node.GetCurrentTcpAddress())
 
-	node.Blockchain.CancelMiningCh <- true
+	// A cancel already pending is enough, never stall the session on a second one
+	select {
+	case node.Blockchain.CancelMiningCh <- true:
+	default:
+	}
 
 	return nil
 }
 
 func (node *Node) AddNewPeer(newPeerAddress string) error {
 	node.Mutex.RLock()
-	if slices.Contains(node.Peers, newPeerAddress) {
+	_, known := node.Peers[newPeerAddress]
+	node.Mutex.RUnlock()
+
+	if known {
 		return nil
 	}
-	node.Mutex.RUnlock()
 
 	sqlTx, err := node.Blockchain.Database.BeginTx()
 	if err != nil {
@@ -189,5 +166,19 @@ func (node *Node) AddNewPeer(newPeerAddress string) error {
 }
 
 func (node *Node) addPeerToMemory(newPeer string) {
-	node.Peers = append(node.Peers, newPeer)
+	if _, ok := node.Peers[newPeer]; ok {
+		return
+	}
+
+	node.Peers[newPeer] = &Peer{Address: newPeer}
+}
+
+// LoadPeers -> Adds peers known from a previous run, e.g. the peers table
+func (node *Node) LoadPeers(addresses []string) {
+	node.Mutex.Lock()
+	defer node.Mutex.Unlock()
+
+	for _, address := range addresses {
+		node.addPeerToMemory(address)
+	}
 }
@@ -8,7 +8,7 @@ import (
 	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
 )
 
-func (node *Node) parseMessage(senderMsg []byte) error {
+func (node *Node) parseMessage(session *Session, senderMsg []byte) error {
 	var msg types.Message
 
 	if err := json.Unmarshal(senderMsg, &msg); err != nil {
@@ -19,14 +19,27 @@ func (node *Node) parseMessage(senderMsg []byte) error {
 		return errors.New("msg senderAddress field is empty")
 	}
 
+	switch msg.Type {
+	case types.VersionMsg:
+		return node.handleVersion(session, msg.Payload)
+
+	case types.VerackMsg:
+		return node.handleVerack(session)
+	}
+
+	if !session.isHandshaked() {
+		session.Close()
+		return fmt.Errorf("%s sent %s before the handshake: %w", session.conn.RemoteAddr(), msg.Type, ErrHandshakeIncomplete)
+	}
+
 	switch msg.Type {
 	// Requesting the blockchain`s data
 	case types.RequestHeadersMsg:
-		return node.handleGetBlockHeaders(msg.SenderAddress)
+		return node.handleGetBlockHeaders(session, msg.RequestId, msg.Payload)
 
-	// Sending the blockchain`s data to the applicant node
-	case types.SendBlockHeadersMsg:
-		node.payloadCh <- msg.Payload
+	// Responses to our own requests
+	case types.SendBlockHeadersMsg, types.SendBlockMsg, types.BlockTxsMsg:
+		node.deliverResponse(session, &msg)
 
 	case types.RequestBlockMsg:
 		var blockId int64
@@ -34,20 +47,32 @@ func (node *Node) parseMessage(senderMsg []byte) error {
 			return err
 		}
 
-		return node.handleGetBlock(msg.SenderAddress, blockId)
-
-	case types.SendBlockMsg:
-		node.payloadCh <- msg.Payload
+		return node.handleGetBlock(session, msg.RequestId, blockId)
 
 	case types.BlockBroadcastMsg:
-		return node.handleBlockBroadcasting(msg.Payload)
+		return node.handleBlockBroadcasting(session, msg.Payload)
+
+	case types.CompactBlockMsg:
+		return node.handleCompactBlock(session, msg.Payload)
+
+	case types.GetBlockTxsMsg:
+		return node.handleGetBlockTxs(session, msg.RequestId, msg.Payload)
 
-	case types.MempoolBroadcastMsg:
-		return node.handleMempoolBroadcasting(msg.Payload)
+	case types.InvMsg:
+		return node.handleInv(session, msg.Payload)
+
+	case types.GetDataMsg:
+		return node.handleGetData(session, msg.Payload)
+
+	case types.TxMsg:
+		return node.handleTransaction(session, msg.Payload)
 
 	case types.CancelMiningMsg:
 		return node.handleCancelMining()
 
+	case types.RejectMsg:
+		return node.handleReject(msg.SenderAddress, msg.Payload)
+
 	default:
 		fmt.Println("meow meow")
 	}
@@ -0,0 +1,250 @@
+package p2p
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+)
+
+const (
+	// maxOrphans -> Blocks waiting for their parent, the oldest is dropped beyond it
+	maxOrphans = 100
+
+	// maxOrphansPerPeer -> Orphans a single peer may have waiting
+	maxOrphansPerPeer = 20
+
+	// orphanExpiry -> How long an orphan waits for its parent
+	orphanExpiry = 10 * time.Minute
+
+	// ancestryTimeout -> Longest fetching the missing ancestors of an orphan may take
+	ancestryTimeout = 2 * time.Minute
+)
+
+var ErrInvalidOrphan = errors.New("orphan block hash does not match its contents or difficulty")
+
+type orphanBlock struct {
+	block     *blockchain.Block
+	peer      string // Peer that announced it
+	expiresAt time.Time
+}
+
+// orphanPool -> Blocks whose parent is not on our chain yet, keyed by their hash and
+// by their parent's hash
+type orphanPool struct {
+	byHash   map[string]*orphanBlock
+	byParent map[string][]string
+	perPeer  map[string]int
+	fetching map[string]bool // Peers we are fetching ancestors from
+	mutex    sync.Mutex
+}
+
+func newOrphanPool() *orphanPool {
+	return &orphanPool{
+		byHash:   make(map[string]*orphanBlock),
+		byParent: make(map[string][]string),
+		perPeer:  make(map[string]int),
+		fetching: make(map[string]bool),
+	}
+}
+
+// add -> Keeps block until its parent arrives, false if it is already waiting or
+// peer has too many orphans waiting
+func (pool *orphanPool) add(block *blockchain.Block, peer string) bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	now := time.Now()
+	for hash, orphan := range pool.byHash {
+		if now.After(orphan.expiresAt) {
+			pool.remove(hash)
+		}
+	}
+
+	hash := hex.EncodeToString(block.Hash)
+	if _, ok := pool.byHash[hash]; ok || pool.perPeer[peer] >= maxOrphansPerPeer {
+		return false
+	}
+
+	if len(pool.byHash) >= maxOrphans {
+		var oldest string
+		for hash, orphan := range pool.byHash {
+			if oldest == "" || orphan.expiresAt.Before(pool.byHash[oldest].expiresAt) {
+				oldest = hash
+			}
+		}
+
+		pool.remove(oldest)
+	}
+
+	parent := hex.EncodeToString(block.PrevHash)
+
+	pool.byHash[hash] = &orphanBlock{block: block, peer: peer, expiresAt: now.Add(orphanExpiry)}
+	pool.byParent[parent] = append(pool.byParent[parent], hash)
+	pool.perPeer[peer]++
+
+	return true
+}
+
+// remove -> Drops the orphan with hash. The caller holds pool.mutex.
+func (pool *orphanPool) remove(hash string) {
+	orphan, ok := pool.byHash[hash]
+	if !ok {
+		return
+	}
+
+	delete(pool.byHash, hash)
+
+	pool.perPeer[orphan.peer]--
+	if pool.perPeer[orphan.peer] <= 0 {
+		delete(pool.perPeer, orphan.peer)
+	}
+
+	parent := hex.EncodeToString(orphan.block.PrevHash)
+	siblings := pool.byParent[parent]
+	for idx, sibling := range siblings {
+		if sibling == hash {
+			siblings = append(siblings[:idx], siblings[idx+1:]...)
+			break
+		}
+	}
+
+	if len(siblings) == 0 {
+		delete(pool.byParent, parent)
+	} else {
+		pool.byParent[parent] = siblings
+	}
+}
+
+// takeChildren -> Removes and returns the unexpired orphans whose parent is parentHash
+func (pool *orphanPool) takeChildren(parentHash []byte) []*orphanBlock {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	now := time.Now()
+
+	var children []*orphanBlock
+	for _, hash := range append([]string{}, pool.byParent[hex.EncodeToString(parentHash)]...) {
+		orphan := pool.byHash[hash]
+		pool.remove(hash)
+
+		if now.Before(orphan.expiresAt) {
+			children = append(children, orphan)
+		}
+	}
+
+	return children
+}
+
+func (pool *orphanPool) count() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return len(pool.byHash)
+}
+
+// startFetch -> Marks peer as being asked for ancestors, false if it already is
+func (pool *orphanPool) startFetch(peer string) bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pool.fetching[peer] {
+		return false
+	}
+
+	pool.fetching[peer] = true
+	return true
+}
+
+func (pool *orphanPool) finishFetch(peer string) {
+	pool.mutex.Lock()
+	delete(pool.fetching, peer)
+	pool.mutex.Unlock()
+}
+
+// acceptBlock -> Connects and relays a block announced by peer, or keeps it as an
+// orphan and fetches its ancestors from peer when its parent is not on our chain
+func (node *Node) acceptBlock(block *blockchain.Block, peer string) error {
+	if node.Blockchain.GetBlockByHash(block.PrevHash) == nil {
+		return node.addOrphan(block, peer)
+	}
+
+	if err := node.connectPeerBlock(block); err != nil {
+		return err
+	}
+
+	log.Println("New block verified successfully")
+
+	return node.relayBlock(block, peer)
+}
+
+func (node *Node) addOrphan(block *blockchain.Block, peer string) error {
+	// Only blocks with valid proof of work may take up room in the pool
+	intact, err := hasValidProofOfWork(block)
+	if err != nil {
+		return err
+	}
+
+	if !intact {
+		return misbehavior(violationInvalidBlock, ErrInvalidOrphan)
+	}
+
+	if !node.orphans.add(block, peer) {
+		log.Printf("Dropped orphan block %d from %s", block.Id, peer)
+		return nil
+	}
+
+	log.Printf("Block %d from %s is an orphan, fetching its ancestors", block.Id, peer)
+
+	go node.fetchAncestors(peer)
+
+	return nil
+}
+
+// fetchAncestors -> Syncs with peer, connecting the orphans it announced once the
+// blocks they build on arrive
+func (node *Node) fetchAncestors(peer string) {
+	if !node.orphans.startFetch(peer) {
+		return
+	}
+	defer node.orphans.finishFetch(peer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ancestryTimeout)
+	defer cancel()
+
+	if err := node.ConnectAndSync(ctx, peer); err != nil {
+		log.Printf("Failed to fetch orphan ancestors from %s: %v", peer, err)
+	}
+}
+
+// connectOrphans -> Connects the orphans waiting on parent, then those waiting on
+// them, and relays each one
+func (node *Node) connectOrphans(parent *blockchain.Block) {
+	queue := [][]byte{parent.Hash}
+
+	for len(queue) > 0 {
+		parentHash := queue[0]
+		queue = queue[1:]
+
+		for _, orphan := range node.orphans.takeChildren(parentHash) {
+			if node.Blockchain.GetBlockByHash(orphan.block.Hash) == nil {
+				if err := node.attachBlock(orphan.block); err != nil {
+					log.Printf("Failed to connect orphan block %d from %s: %v", orphan.block.Id, orphan.peer, err)
+					continue
+				}
+
+				log.Printf("Connected orphan block %d", orphan.block.Id)
+
+				if err := node.relayBlock(orphan.block, orphan.peer); err != nil {
+					log.Printf("Failed to relay block %d: %v", orphan.block.Id, err)
+				}
+			}
+
+			queue = append(queue, orphan.block.Hash)
+		}
+	}
+}
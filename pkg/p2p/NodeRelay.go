@@ -0,0 +1,332 @@
+package p2p
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+const (
+	// seenBlocksSize -> Block hashes remembered so a relayed block is handled once
+	seenBlocksSize = 4096
+
+	// seenTxsSize -> Admitted tx hashes remembered so a relayed tx is handled once
+	seenTxsSize = 65536
+
+	// knownPerPeerSize -> Block and tx hashes remembered per peer as already held by it
+	knownPerPeerSize = 16384
+
+	// maxInvHashes -> Most tx hashes a single inv or get_data may carry
+	maxInvHashes = 1000
+
+	// txRequestTimeout -> How long a tx asked for is not asked for again from another peer
+	txRequestTimeout = 30 * time.Second
+)
+
+var (
+	ErrCoinbaseRelay = errors.New("coinbase transactions are only valid inside a block")
+	ErrInvTooLarge   = fmt.Errorf("inventory carries more than %d hashes", maxInvHashes)
+)
+
+// hashCache -> Bounded set of hashes, the oldest one is forgotten once it is full
+type hashCache struct {
+	hashes map[string]struct{}
+	order  []string // Ring buffer in insertion order
+	next   int
+	mutex  sync.Mutex
+}
+
+func newHashCache(capacity int) *hashCache {
+	return &hashCache{
+		hashes: make(map[string]struct{}, capacity),
+		order:  make([]string, capacity),
+	}
+}
+
+// add -> Remembers hash, false if it was already there
+func (cache *hashCache) add(hash string) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if _, ok := cache.hashes[hash]; ok {
+		return false
+	}
+
+	if evicted := cache.order[cache.next]; evicted != "" {
+		delete(cache.hashes, evicted)
+	}
+
+	cache.order[cache.next] = hash
+	cache.next = (cache.next + 1) % len(cache.order)
+	cache.hashes[hash] = struct{}{}
+
+	return true
+}
+
+func (cache *hashCache) contains(hash string) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	_, ok := cache.hashes[hash]
+	return ok
+}
+
+// BroadcastBlock -> Announces a block mined by this node to every peer
+func (node *Node) BroadcastBlock(block *blockchain.Block) error {
+	node.seenBlocks.add(hex.EncodeToString(block.Hash))
+
+	return node.relayBlock(block, "")
+}
+
+// BroadcastTransaction -> Announces a tx admitted to our mempool to every peer
+func (node *Node) BroadcastTransaction(tx *blockchain.Transaction) error {
+	node.seenTxs.add(tx.Hash().EncodeToString())
+
+	return node.relayTransaction(tx, "")
+}
+
+// relayBlock -> Forwards block as a compact block to every peer except source and
+// those that have it
+func (node *Node) relayBlock(block *blockchain.Block, source string) error {
+	compact, err := blockchain.NewCompactBlock(block)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(compact)
+	if err != nil {
+		return fmt.Errorf("failed to marshal compact block: %w", err)
+	}
+
+	newMessage := types.NewMessage(types.CompactBlockMsg, node.GetCurrentTcpAddress(), payload)
+
+	node.relay(hex.EncodeToString(block.Hash), newMessage.Marshal(), source)
+
+	return nil
+}
+
+// relayTransaction -> Announces tx to every peer except source and those that have
+// it, they ask for it with get_data if they want it
+func (node *Node) relayTransaction(tx *blockchain.Transaction, source string) error {
+	hash := tx.Hash().EncodeToString()
+
+	payload, err := json.Marshal(types.InvPayload{TxHashes: []string{hash}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal inv: %w", err)
+	}
+
+	newMessage := types.NewMessage(types.InvMsg, node.GetCurrentTcpAddress(), payload)
+
+	node.relay(hash, newMessage.Marshal(), source)
+
+	return nil
+}
+
+// relay -> Sends msg about hash to every known peer but source, skipping peers
+// whose session already sent or received it
+func (node *Node) relay(hash string, msg []byte, source string) {
+	for _, peerAddr := range node.getPeersList() {
+		if peerAddr == source || peerAddr == node.GetCurrentTcpAddress() {
+			continue
+		}
+
+		go func(addr string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			session, err := node.getSession(ctx, addr)
+			if err != nil {
+				log.Printf("Failed to relay %s to %s: %v", hash, addr, err)
+				return
+			}
+
+			if !session.markKnown(hash) {
+				return
+			}
+
+			if err := session.Send(ctx, msg); err != nil {
+				log.Printf("Failed to relay %s to %s: %v", hash, addr, err)
+			}
+		}(peerAddr)
+	}
+}
+
+// handleInv -> Asks the peer for the announced txs we neither have nor already asked
+// another peer for
+func (node *Node) handleInv(session *Session, payload types.Payload) error {
+	inv, err := unmarshalInv(payload)
+	if err != nil {
+		return err
+	}
+
+	wanted := make([]string, 0, len(inv.TxHashes))
+	for _, hash := range inv.TxHashes {
+		session.markKnown(hash)
+
+		if node.seenTxs.contains(hash) || node.Blockchain.Mempool.HasTransaction(hash) {
+			continue
+		}
+
+		if node.markRequested(hash) {
+			wanted = append(wanted, hash)
+		}
+	}
+
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	getDataPayload, err := json.Marshal(types.InvPayload{TxHashes: wanted})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	msg := types.NewMessage(types.GetDataMsg, node.GetCurrentTcpAddress(), getDataPayload)
+
+	return session.Send(ctx, msg.Marshal())
+}
+
+// handleGetData -> Sends the asked for txs that are in our mempool
+func (node *Node) handleGetData(session *Session, payload types.Payload) error {
+	getData, err := unmarshalInv(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, hash := range getData.TxHashes {
+		tx, ok := node.Blockchain.Mempool.GetTransaction(hash)
+		if !ok {
+			continue
+		}
+
+		txPayload, err := json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+
+		session.markKnown(hash)
+
+		msg := types.NewMessage(types.TxMsg, node.GetCurrentTcpAddress(), txPayload)
+		if err := session.Send(ctx, msg.Marshal()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalInv(payload types.Payload) (*types.InvPayload, error) {
+	var inv types.InvPayload
+	if err := payload.Unmarshal(&inv); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inv payload: %w", err)
+	}
+
+	if len(inv.TxHashes) > maxInvHashes {
+		return nil, misbehavior(violationOversizedInventory, ErrInvTooLarge)
+	}
+
+	return &inv, nil
+}
+
+// markRequested -> Records that hash is being asked for, false while an earlier
+// request for it has not timed out
+func (node *Node) markRequested(hash string) bool {
+	node.requestedMutex.Lock()
+	defer node.requestedMutex.Unlock()
+
+	now := time.Now()
+	if until, ok := node.requestedTxs[hash]; ok && now.Before(until) {
+		return false
+	}
+
+	// Peers that never answer would otherwise grow the map forever
+	if len(node.requestedTxs) >= seenTxsSize {
+		for requested, until := range node.requestedTxs {
+			if !now.Before(until) {
+				delete(node.requestedTxs, requested)
+			}
+		}
+	}
+
+	node.requestedTxs[hash] = now.Add(txRequestTimeout)
+
+	return true
+}
+
+func (node *Node) clearRequested(hash string) {
+	node.requestedMutex.Lock()
+	delete(node.requestedTxs, hash)
+	node.requestedMutex.Unlock()
+}
+
+// handleTransaction -> Admits a tx sent by a peer and announces it to the others
+func (node *Node) handleTransaction(session *Session, payload types.Payload) error {
+	var tx blockchain.Transaction
+	if err := payload.Unmarshal(&tx); err != nil {
+		return fmt.Errorf("failed to unmarshal transaction: %w", err)
+	}
+
+	hash := tx.Hash().EncodeToString()
+	session.markKnown(hash)
+	node.clearRequested(hash)
+
+	// Only admitted txs are seen, one rejected while we are behind may be sent again
+	if node.seenTxs.contains(hash) {
+		return nil
+	}
+
+	return node.acceptTransaction(session, &tx)
+}
+
+// acceptTransaction -> Checks the signature, sender address, balance and mempool
+// policy of a tx from session, adds it to the mempool and, once it is admitted,
+// marks it seen and relays it if it was not there yet
+func (node *Node) acceptTransaction(session *Session, tx *blockchain.Transaction) error {
+	if tx.IsCoinbase {
+		return misbehavior(violationInvalidTransaction, ErrCoinbaseRelay)
+	}
+
+	hash := tx.Hash().EncodeToString()
+	if node.Blockchain.Mempool.HasTransaction(hash) {
+		return nil
+	}
+
+	if err := node.Blockchain.VerifyTransaction(tx); err != nil {
+		return misbehavior(violationInvalidTransaction, err)
+	}
+
+	// A spend our state cannot pay for yet may be valid on the peer's chain
+	if err := node.Blockchain.ValidateTransaction(tx); err != nil {
+		return err
+	}
+
+	if err := node.Blockchain.AddTransactionToMempool(tx); err != nil {
+		var policyErr *blockchain.PolicyError
+		if errors.As(err, &policyErr) {
+			node.sendReject(session, hash, policyErr)
+			return nil
+		}
+
+		return err
+	}
+
+	if !node.seenTxs.add(hash) {
+		return nil
+	}
+
+	return node.relayTransaction(tx, session.PeerAddress())
+}
@@ -0,0 +1,94 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+// requestTimeout -> Longest a request waits for its response
+const requestTimeout = 60 * time.Second
+
+// pendingKey -> A request is identified by the peer it was sent to and its id
+type pendingKey struct {
+	peerAddress string
+	requestId   uint64
+}
+
+// pendingRequest -> A request waiting for a response of responseType
+type pendingRequest struct {
+	responseType string
+	responseCh   chan types.Payload
+}
+
+// request -> Sends a msgType request to the peer and waits for the matching response
+func (node *Node) request(ctx context.Context, peerAddress, msgType, responseType string, payload types.Payload) (types.Payload, error) {
+	key := pendingKey{
+		peerAddress: peerAddress,
+		requestId:   node.nextRequestId.Add(1),
+	}
+
+	pending := &pendingRequest{
+		responseType: responseType,
+		responseCh:   make(chan types.Payload, 1),
+	}
+
+	node.pendingMutex.Lock()
+	node.pending[key] = pending
+	node.pendingMutex.Unlock()
+
+	defer func() {
+		node.pendingMutex.Lock()
+		delete(node.pending, key)
+		node.pendingMutex.Unlock()
+	}()
+
+	msg := types.NewMessage(msgType, node.GetCurrentTcpAddress(), payload).WithRequestId(key.requestId)
+	if err := node.WriteMessage(ctx, peerAddress, msg.Marshal()); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(requestTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-pending.responseCh:
+		return response, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("request %d to %s timed out waiting for %s", key.requestId, peerAddress, responseType)
+	}
+}
+
+// deliverResponse -> Hands a response to the request waiting for it. Unsolicited,
+// late or duplicate responses are dropped.
+func (node *Node) deliverResponse(session *Session, msg *types.Message) {
+	key := pendingKey{
+		peerAddress: session.PeerAddress(),
+		requestId:   msg.RequestId,
+	}
+
+	node.pendingMutex.Lock()
+	pending, ok := node.pending[key]
+	if ok && pending.responseType == msg.Type {
+		// One response per request
+		delete(node.pending, key)
+	}
+	node.pendingMutex.Unlock()
+
+	if !ok {
+		log.Printf("Dropping unsolicited %s from %s (request %d)", msg.Type, key.peerAddress, msg.RequestId)
+		return
+	}
+
+	if pending.responseType != msg.Type {
+		log.Printf("Dropping %s from %s, request %d expects %s", msg.Type, key.peerAddress, msg.RequestId, pending.responseType)
+		return
+	}
+
+	pending.responseCh <- msg.Payload
+}
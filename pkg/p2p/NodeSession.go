@@ -0,0 +1,324 @@
+package p2p
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+const (
+	// sendQueueSize -> Frames queued for a peer before Send blocks
+	sendQueueSize = 256
+
+	// writeTimeout -> Longest a single frame may take to reach the peer
+	writeTimeout = 30 * time.Second
+
+	// DefaultReadTimeout -> Longest a single frame may take to arrive once its first byte did
+	DefaultReadTimeout = 30 * time.Second
+)
+
+var ErrSessionClosed = errors.New("peer session is closed")
+
+// Session -> One long-lived, bidirectional TLS connection to a peer. A read and a
+// write goroutine run for as long as it is open; frames to send are queued. Only
+// version and verack are accepted until the handshake is done.
+type Session struct {
+	conn     net.Conn
+	inbound  bool
+	sendCh   chan []byte
+	closeCh  chan struct{}
+	closeErr error
+
+	peerAddress    string                // TCP address the peer listens on, empty until known
+	version        *types.VersionPayload // What the peer advertised in its version message
+	versionSent    bool
+	verackReceived bool
+	mutex          sync.RWMutex
+
+	known *hashCache // Block and tx hashes the peer sent us or we relayed to it
+	score int        // Misbehavior on this session so far, banned at BanThreshold
+
+	handshakeCh   chan struct{} // Closed once version and verack went both ways
+	handshakeOnce sync.Once
+	closeOnce     sync.Once
+}
+
+func newSession(conn net.Conn, peerAddress string, inbound bool) *Session {
+	return &Session{
+		conn:        conn,
+		inbound:     inbound,
+		sendCh:      make(chan []byte, sendQueueSize),
+		closeCh:     make(chan struct{}),
+		handshakeCh: make(chan struct{}),
+		peerAddress: peerAddress,
+		known:       newHashCache(knownPerPeerSize),
+	}
+}
+
+// markKnown -> Records that the peer has hash, false if it was known already
+func (session *Session) markKnown(hash string) bool {
+	return session.known.add(hash)
+}
+
+// PeerAddress -> Address the peer listens on; for inbound sessions it is learned
+// from the first message
+func (session *Session) PeerAddress() string {
+	session.mutex.RLock()
+	defer session.mutex.RUnlock()
+
+	return session.peerAddress
+}
+
+func (session *Session) setPeerAddress(address string) {
+	session.mutex.Lock()
+	session.peerAddress = address
+	session.mutex.Unlock()
+}
+
+// Send -> Queues msg for the write goroutine, waiting while the queue is full
+func (session *Session) Send(ctx context.Context, msg []byte) error {
+	if len(msg) > types.MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", types.ErrFrameTooLarge, len(msg))
+	}
+
+	select {
+	case <-session.closeCh:
+		return ErrSessionClosed
+	default:
+	}
+
+	select {
+	case session.sendCh <- msg:
+		return nil
+	case <-session.closeCh:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close -> Closes the connection, which also stops both goroutines
+func (session *Session) Close() error {
+	session.closeOnce.Do(func() {
+		close(session.closeCh)
+		session.closeErr = session.conn.Close()
+	})
+
+	return session.closeErr
+}
+
+// Done -> Closed once the session is
+func (session *Session) Done() <-chan struct{} {
+	return session.closeCh
+}
+
+func (session *Session) writeLoop() {
+	defer session.Close()
+
+	for {
+		select {
+		case <-session.closeCh:
+			return
+		case msg := <-session.sendCh:
+			if err := session.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+				log.Printf("Failed to set write deadline for %s: %v", session.conn.RemoteAddr(), err)
+				return
+			}
+
+			if err := types.WriteFrame(session.conn, msg); err != nil {
+				log.Printf("Failed to write to %s: %v", session.conn.RemoteAddr(), err)
+				return
+			}
+		}
+	}
+}
+
+func (node *Node) readLoop(session *Session) {
+	defer node.closeSession(session)
+
+	for {
+		// Until the handshake is done only small version and verack frames are expected
+		var maxSize uint32 = types.MaxFrameSize
+		if !session.isHandshaked() {
+			maxSize = maxHandshakeFrameSize
+		}
+
+		// A quiet peer may wait as long as it likes before the next frame
+		if err := session.conn.SetReadDeadline(time.Time{}); err != nil {
+			log.Printf("Failed to clear read deadline for %s: %v", session.conn.RemoteAddr(), err)
+			return
+		}
+
+		reader := &frameReader{conn: session.conn, timeout: node.ReadTimeout}
+		data, err := types.ReadFrameLimit(reader, maxSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Failed to read from %s: %v", session.conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if err := node.parseMessage(session, data); err != nil {
+			log.Println("parsing message: ", err)
+			node.penalize(session, err)
+		}
+	}
+}
+
+// frameReader -> Reads one frame from conn, giving the peer timeout to deliver all of
+// it once the first byte arrived, so a peer that stops mid-frame does not hold the reader
+type frameReader struct {
+	conn    net.Conn
+	timeout time.Duration
+	started bool
+}
+
+func (reader *frameReader) Read(p []byte) (int, error) {
+	n, err := reader.conn.Read(p)
+	if n > 0 && !reader.started {
+		reader.started = true
+
+		if deadlineErr := reader.conn.SetReadDeadline(time.Now().Add(reader.timeout)); deadlineErr != nil && err == nil {
+			err = fmt.Errorf("set read deadline: %w", deadlineErr)
+		}
+	}
+
+	return n, err
+}
+
+// startSession -> Starts the read and write goroutines of a new session
+func (node *Node) startSession(session *Session) {
+	go session.writeLoop()
+	go node.readLoop(session)
+
+	// Outbound sessions are bounded by the dialer waiting for the handshake
+	if session.inbound {
+		go node.enforceHandshakeDeadline(session)
+	}
+}
+
+// getSession -> Open, handshaked session to the peer, dialing a new one if there is none
+func (node *Node) getSession(ctx context.Context, peerAddress string) (*Session, error) {
+	node.sessionsMutex.Lock()
+	session, ok := node.sessions[peerAddress]
+	node.sessionsMutex.Unlock()
+
+	if ok {
+		select {
+		case <-session.Done():
+		default:
+			if err := session.waitHandshake(ctx, node.HandshakeTimeout); err != nil {
+				return nil, fmt.Errorf("session with peer %s: %w", peerAddress, err)
+			}
+
+			return session, nil
+		}
+	}
+
+	if node.isBanned(peerAddress, "") {
+		return nil, fmt.Errorf("refusing to dial %s: %w", peerAddress, ErrPeerBanned)
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", peerAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial peer %s: %w", peerAddress, err)
+	}
+
+	tlsConn := tls.Client(conn, node.TLSConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to handshake with peer %s: %w", peerAddress, err)
+	}
+
+	session = newSession(tlsConn, peerAddress, false)
+
+	// Queued before anyone else can find the session, so the version goes out first
+	if err := node.sendVersion(session); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	node.sessionsMutex.Lock()
+	if existing, ok := node.sessions[peerAddress]; ok {
+		select {
+		case <-existing.Done():
+		default:
+			// Another goroutine dialed the peer first
+			node.sessionsMutex.Unlock()
+			session.Close()
+			return existing, existing.waitHandshake(ctx, node.HandshakeTimeout)
+		}
+	}
+	node.sessions[peerAddress] = session
+	node.sessionsMutex.Unlock()
+
+	node.startSession(session)
+
+	if err := session.waitHandshake(ctx, node.HandshakeTimeout); err != nil {
+		node.closeSession(session)
+		return nil, fmt.Errorf("version handshake with peer %s: %w", peerAddress, err)
+	}
+
+	return session, nil
+}
+
+// registerSession -> Makes an inbound session reachable under the address the peer
+// listens on. A session keeps the first address it is known by. False if a live,
+// handshaked session already holds the address, the new one could be anybody
+// claiming it.
+func (node *Node) registerSession(session *Session, peerAddress string) bool {
+	if session.PeerAddress() != "" {
+		return true
+	}
+
+	node.sessionsMutex.Lock()
+	defer node.sessionsMutex.Unlock()
+
+	if existing, ok := node.sessions[peerAddress]; ok {
+		select {
+		case <-existing.Done():
+		default:
+			if existing.isHandshaked() {
+				return false
+			}
+
+			// Both sides dialed at once, keep the session already in use; this one
+			// still serves its own requests
+			session.setPeerAddress(peerAddress)
+			return true
+		}
+	}
+
+	session.setPeerAddress(peerAddress)
+	node.sessions[peerAddress] = session
+
+	return true
+}
+
+func (node *Node) closeSession(session *Session) {
+	session.Close()
+
+	node.sessionsMutex.Lock()
+	defer node.sessionsMutex.Unlock()
+
+	address := session.PeerAddress()
+	if node.sessions[address] != session {
+		return
+	}
+
+	delete(node.sessions, address)
+
+	if session.isHandshaked() {
+		node.setPeerDisconnected(address)
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
@@ -18,15 +18,43 @@ import (
 	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
 )
 
+var ErrForkDetected = errors.New("peer chain forks from ours")
+
 type Node struct {
 	TcpAddress string
-	Peers      []string // Slice of TCP addresses
+	Peers      map[string]*Peer // Known peers keyed by TCP address
 
 	Blockchain *blockchain.Blockchain `json:"blockchain"`
-	payloadCh  chan types.Payload     // Communication channel
 
 	Mutex sync.RWMutex
 
+	sessions      map[string]*Session // Open sessions keyed by the peer's TCP address
+	sessionsMutex sync.Mutex
+
+	pending       map[pendingKey]*pendingRequest // Requests waiting for their response
+	pendingMutex  sync.Mutex
+	nextRequestId atomic.Uint64
+
+	HandshakeTimeout  time.Duration // A new session that has not exchanged version and verack by then is closed
+	ReadTimeout       time.Duration // A session whose peer stops sending mid-frame for this long is closed
+	BlockStallTimeout time.Duration // A block request unanswered for this long is sent to another peer
+	downloadMutex     sync.Mutex    // One block download at a time
+	syncStatus        SyncStatus
+	syncMutex         sync.Mutex
+
+	seenBlocks *hashCache // Relayed blocks already handled
+	seenTxs    *hashCache // Relayed txs already admitted
+
+	requestedTxs   map[string]time.Time // Txs asked for with get_data, until when to wait for them
+	requestedMutex sync.Mutex
+
+	orphans *orphanPool // Announced blocks whose parent we do not have yet
+
+	BanDuration time.Duration   // How long a misbehaving peer stays banned
+	BanIPs      bool            // Also refuse every connection from the IP of a banned peer
+	bans        map[string]*Ban // Bans keyed by the peer's TCP address
+	bansMutex   sync.Mutex
+
 	TLSConfig *tls.Config
 }
 
@@ -45,11 +73,26 @@ func SetupNode(address string, bc *blockchain.Blockchain, tlsConfig *tls.Config)
 
 	node := &Node{
 		TcpAddress: address,
-		Peers:      make([]string, 0),
+		Peers:      make(map[string]*Peer),
 
 		Blockchain: bc,
 
-		payloadCh: make(chan types.Payload),
+		sessions: make(map[string]*Session),
+		pending:  make(map[pendingKey]*pendingRequest),
+
+		HandshakeTimeout:  DefaultHandshakeTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		BlockStallTimeout: DefaultBlockStallTimeout,
+
+		seenBlocks: newHashCache(seenBlocksSize),
+		seenTxs:    newHashCache(seenTxsSize),
+
+		requestedTxs: make(map[string]time.Time),
+
+		orphans: newOrphanPool(),
+
+		BanDuration: DefaultBanDuration,
+		bans:        make(map[string]*Ban),
 
 		TLSConfig: tlsConfig,
 	}
@@ -79,125 +122,111 @@ func (node *Node) Bootstrap() {
 	}
 }
 
-func (node *Node) ConnectAndSync(ctx context.Context, peerAddress string) error {
-	msg := types.NewMessage(types.RequestHeadersMsg, node.GetCurrentTcpAddress(), types.Payload{})
-	if err := node.WriteMessage(ctx, peerAddress, msg.Marshal()); err != nil {
-		return err
-	}
+// Connect -> Opens a session to the peer and completes the version handshake
+func (node *Node) Connect(ctx context.Context, peerAddress string) error {
+	_, err := node.getSession(ctx, peerAddress)
+	return err
+}
 
-	// Receive headers
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case payload := <-node.payloadCh:
-		var headers []blockchain.BlockHeader
+// ConnectAndSync -> Downloads the blocks the peer has past our tip, fetching headers
+// in batches from the last block both chains share
+func (node *Node) ConnectAndSync(ctx context.Context, peerAddress string) error {
+	synced := 0
 
-		if err := payload.Unmarshal(&headers); err != nil {
+	for {
+		headers, err := node.requestHeaders(ctx, peerAddress)
+		if err != nil {
+			node.penalizePeer(peerAddress, err)
 			return err
 		}
 
-		if valid, err := node.Blockchain.VerifyHeaders(headers); err != nil {
+		if len(headers) == 0 {
+			break
+		}
+
+		if err := node.verifyPeerHeaders(peerAddress, headers); err != nil {
+			node.penalizePeer(peerAddress, err)
 			return err
-		} else if !valid {
-			return fmt.Errorf("peer %s send corrupted blockchain", peerAddress)
 		}
 
 		if err := node.DownloadMissingBlocks(ctx, peerAddress, headers); err != nil {
+			node.penalizePeer(peerAddress, err)
 			return err
 		}
 
-		fmt.Printf("Successfully synced %d blocks with peer: %s\n", len(headers), peerAddress)
-		return node.AddNewPeer(peerAddress)
-	case <-time.After(60 * time.Second):
-		return errors.New("ERROR get payload Timeout exceeded")
-	}
-}
-
-func (node *Node) DownloadMissingBlocks(ctx context.Context, peerAddress string, headers []blockchain.BlockHeader) error {
-	localBlocks, err := node.Blockchain.GetAllBlocks()
-	if err != nil {
-		return err
-	}
-
-	localBlockIds := make(map[int64]bool)
-	for _, block := range localBlocks {
-		localBlockIds[block.Id] = true
-	}
-
-	downloaded := 0
-
-	for _, header := range headers {
-		if localBlockIds[header.Id] {
-			continue
-		}
+		synced += len(headers)
 
-		if err := node.downloadBlock(ctx, peerAddress, header.Id); err != nil {
-			return fmt.Errorf("failed to download block %d: %w", header.Id, err)
+		if len(headers) < blockchain.MaxHeadersPerMsg {
+			break
 		}
-		downloaded++
 	}
 
-	return nil
+	fmt.Printf("Successfully synced %d blocks with peer: %s\n", synced, peerAddress)
+	return node.AddNewPeer(peerAddress)
 }
 
-func (node *Node) downloadBlock(ctx context.Context, peerAddress string, blockId int64) error {
-	payload, err := json.Marshal(blockId)
+// requestHeaders -> Headers the peer has after the last block of our locator it knows
+func (node *Node) requestHeaders(ctx context.Context, peerAddress string) ([]blockchain.BlockHeader, error) {
+	requestPayload, err := json.Marshal(types.GetHeadersPayload{
+		Locator: node.Blockchain.BlockLocator(),
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg := types.NewMessage(types.RequestBlockMsg, node.GetCurrentTcpAddress(), payload)
-	if err := node.WriteMessage(ctx, peerAddress, msg.Marshal()); err != nil {
-		return err
+	payload, err := node.request(ctx, peerAddress, types.RequestHeadersMsg, types.SendBlockHeadersMsg, requestPayload)
+	if err != nil {
+		return nil, err
 	}
 
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case payload := <-node.payloadCh:
-		var block blockchain.Block
-		if err := payload.Unmarshal(&block); err != nil {
-			return err
-		}
+	var headers []blockchain.BlockHeader
+	if err := payload.Unmarshal(&headers); err != nil {
+		return nil, err
+	}
 
-		if block.Id != blockId {
-			return fmt.Errorf("received block ID mismatch: expected %d, got %d", blockId, block.Id)
-		}
+	if len(headers) > blockchain.MaxHeadersPerMsg {
+		return nil, misbehavior(violationCorruptHeaders,
+			fmt.Errorf("peer %s sent %d headers, more than %d", peerAddress, len(headers), blockchain.MaxHeadersPerMsg))
+	}
 
-		valid, err := node.Blockchain.VerifyBlock(&block)
-		if err != nil {
-			return err
-		}
+	return headers, nil
+}
 
-		if !valid {
-			return fmt.Errorf("received invalid block %d", blockId)
-		}
+// verifyPeerHeaders -> Checks that a batch of headers is valid and extends our tip.
+// Headers branching off below the tip are a fork, which is reported, not followed.
+func (node *Node) verifyPeerHeaders(peerAddress string, headers []blockchain.BlockHeader) error {
+	first := headers[0]
 
-		if err := node.verifyBlockTransactions(&block); err != nil {
-			return fmt.Errorf("block %d contains invalid transactions: %w", blockId, err)
+	if first.Id == 0 {
+		if valid, err := node.Blockchain.VerifyHeaders(headers); err != nil {
+			return misbehavior(violationCorruptHeaders, err)
+		} else if !valid {
+			return misbehavior(violationCorruptHeaders, fmt.Errorf("peer %s send corrupted blockchain", peerAddress))
 		}
 
-		sqlTx, err := node.Blockchain.Database.BeginTx()
-		if err != nil {
-			return err
+		if node.Blockchain.GetBlockByHash(first.Hash) == nil {
+			return fmt.Errorf("peer %s is on a chain with another genesis block", peerAddress)
 		}
 
-		defer sqlTx.Rollback()
-
-		if err := node.Blockchain.AddBlock(sqlTx, &block); err != nil {
-			return err
-		}
+		return nil
+	}
 
-		if err := sqlTx.Commit(); err != nil {
-			return err
-		}
+	parent := node.Blockchain.GetBlockByHash(first.PrevHash)
+	if parent == nil {
+		return misbehavior(violationCorruptHeaders, fmt.Errorf("headers from peer %s do not connect to our chain", peerAddress))
+	}
 
-		node.Blockchain.AddBlockToMemory(&block)
+	if valid, err := node.Blockchain.VerifyHeadersAfter(parent.Id, parent.Hash, headers); err != nil {
+		return misbehavior(violationCorruptHeaders, err)
+	} else if !valid {
+		return misbehavior(violationCorruptHeaders, fmt.Errorf("peer %s send corrupted blockchain", peerAddress))
+	}
 
-		return nil
-	case <-time.After(60 * time.Second):
-		return errors.New("ERROR get block payload timeout exceeded")
+	if tip := node.Blockchain.GetHeight(); parent.Id < tip {
+		return fmt.Errorf("%w: peer %s branches off after block %d, our tip is %d", ErrForkDetected, peerAddress, parent.Id, tip)
 	}
+
+	return nil
 }
 
 func (node *Node) startListening(tcpListener net.Listener) error {
@@ -207,52 +236,30 @@ func (node *Node) startListening(tcpListener net.Listener) error {
 			return err
 		}
 
-		go node.handleListening(conn)
-	}
-}
-
-// handleListening -> Node must consistently listen (read) to the TCP TLS connection
-func (node *Node) handleListening(conn net.Conn) {
-	defer conn.Close()
-
-	if !isTLS(conn) {
-		log.Println("connection is not TLS")
-	}
+		if !isTLS(conn) {
+			log.Println("connection is not TLS")
+			conn.Close()
+			continue
+		}
 
-	data, err := io.ReadAll(conn)
-	if err != nil {
-		log.Println(err)
-	}
+		if ip := remoteIP(conn.RemoteAddr()); node.isBanned("", ip) {
+			log.Printf("refusing connection from banned %s", ip)
+			conn.Close()
+			continue
+		}
 
-	if err := node.parseMessage(data); err != nil {
-		log.Println("parsing message: ", err)
+		node.startSession(newSession(conn, "", true))
 	}
 }
 
-// WriteMessage -> Writes to TCP connection
+// WriteMessage -> Sends msg over the session to the peer, opening one if needed
 func (node *Node) WriteMessage(ctx context.Context, peerAddress string, msg []byte) error {
-	var dialer net.Dialer
-
-	conn, err := dialer.DialContext(ctx, "tcp", peerAddress)
+	session, err := node.getSession(ctx, peerAddress)
 	if err != nil {
-		return fmt.Errorf("failed to dial peer %s: %w", peerAddress, err)
-	}
-	defer conn.Close()
-
-	tlsConn := tls.Client(conn, node.TLSConfig)
-	defer tlsConn.Close()
-
-	if deadline, ok := ctx.Deadline(); ok {
-		if err := tlsConn.SetDeadline(deadline); err != nil {
-			return err
-		}
-	}
-
-	if _, err := tlsConn.Write(msg); err != nil {
 		return err
 	}
 
-	return nil
+	return session.Send(ctx, msg)
 }
 
 func (node *Node) CancelMining() error {
@@ -263,36 +270,10 @@ func (node *Node) CancelMining() error {
 	return nil
 }
 
-func (node *Node) BroadcastBlock(block *blockchain.Block) error {
-	payload, err := json.Marshal(block)
-	if err != nil {
-		return fmt.Errorf("failed to marshal block: %w", err)
-	}
-
-	newMessage := types.NewMessage(types.BlockBroadcastMsg, node.GetCurrentTcpAddress(), payload)
-
-	node.sendToAllPeers(newMessage.Marshal())
-
-	return nil
-}
-
-func (node *Node) BroadcastMempool(mempool *blockchain.Mempool) error {
-	var payload, err = json.Marshal(mempool)
-	if err != nil {
-		return fmt.Errorf("failed to marshal block: %w", err)
-	}
-
-	newMessage := types.NewMessage(types.MempoolBroadcastMsg, node.GetCurrentTcpAddress(), payload)
-
-	node.sendToAllPeers(newMessage.Marshal())
-
-	return nil
-}
-
 func (node *Node) sendToAllPeers(newMessage []byte) {
 	peersList := node.getPeersList()
 
-	fmt.Println("available peers: ", node.Peers)
+	fmt.Println("available peers: ", peersList)
 
 	for _, peerAddr := range peersList {
 		if peerAddr == node.GetCurrentTcpAddress() {
@@ -313,8 +294,10 @@ func (node *Node) sendToAllPeers(newMessage []byte) {
 func (node *Node) getPeersList() []string {
 	node.Mutex.RLock()
 
-	peersList := make([]string, len(node.Peers))
-	copy(peersList, node.Peers)
+	peersList := make([]string, 0, len(node.Peers))
+	for address := range node.Peers {
+		peersList = append(peersList, address)
+	}
 
 	node.Mutex.RUnlock()
 
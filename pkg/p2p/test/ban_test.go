@@ -0,0 +1,306 @@
+package test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// waitForBans -> Waits until node has count bans in effect
+func waitForBans(t *testing.T, node *p2p.Node, count int) []p2p.Ban {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		bans := node.GetBans()
+		if len(bans) == count {
+			return bans
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d bans, got %d", count, len(bans))
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestBan_MalformedMessagesAddUp(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9268")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	malformed := types.NewMessage(types.TxMsg, "127.0.0.1:1", types.Payload("{not json"))
+
+	// One short of the threshold only adds to the score
+	for range p2p.BanThreshold/10 - 1 {
+		writeMessage(t, sender, malformed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for node.GetPeers()[0].Score != p2p.BanThreshold-10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected score %d, got %d", p2p.BanThreshold-10, node.GetPeers()[0].Score)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if bans := node.GetBans(); len(bans) != 0 {
+		t.Fatalf("Peer should not be banned yet, got %d bans", len(bans))
+	}
+
+	writeMessage(t, sender, malformed)
+	assertClosed(t, sender)
+
+	bans := waitForBans(t, node, 1)
+	if bans[0].Address != "127.0.0.1:1" || bans[0].IP != "127.0.0.1" || bans[0].Reason != "malformed message" {
+		t.Errorf("Unexpected ban: %+v", bans[0])
+	}
+
+	if peers := node.GetPeers(); len(peers) != 0 {
+		t.Errorf("Banned peer should be forgotten, got %d peers", len(peers))
+	}
+
+	// Other peers behind the same IP are only refused when IP bans are enabled
+	dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:2"))
+	waitForConnected(t, node, 1)
+}
+
+func TestBan_InvalidBlockBansAtOnce(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9269")
+	node.BanIPs = true
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	block := &blockchain.Block{
+		Id:           5,
+		PrevHash:     bytes.Repeat([]byte{0xab}, 32),
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)},
+	}
+	if err := block.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", block))
+	assertClosed(t, sender)
+
+	if bans := waitForBans(t, node, 1); bans[0].Reason != "invalid block" {
+		t.Errorf("Expected an invalid block ban, got %q", bans[0].Reason)
+	}
+
+	// Neither its address nor its IP may come back until the ban is lifted
+	if conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig); err == nil {
+		t.Cleanup(func() { conn.Close() })
+		assertClosed(t, conn)
+	}
+
+	if err := node.Connect(t.Context(), "127.0.0.1:1"); !errors.Is(err, p2p.ErrPeerBanned) {
+		t.Errorf("Expected %v, got %v", p2p.ErrPeerBanned, err)
+	}
+
+	if err := node.Unban("127.0.0.1:1"); err != nil {
+		t.Fatalf("Failed to lift ban: %v", err)
+	}
+
+	if err := node.Unban("127.0.0.1:1"); !errors.Is(err, p2p.ErrPeerNotBanned) {
+		t.Errorf("Expected %v, got %v", p2p.ErrPeerNotBanned, err)
+	}
+
+	dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+}
+
+func TestBan_SurvivesRestart(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9270")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, txMessage(t, "127.0.0.1:1", blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)))
+	writeMessage(t, sender, invMessage(t, types.InvMsg, "127.0.0.1:1", make([]string, 1001)...))
+
+	for range 7 {
+		writeMessage(t, sender, types.NewMessage(types.TxMsg, "127.0.0.1:1", types.Payload("[")))
+	}
+
+	waitForBans(t, node, 1)
+
+	// A node started on the same database keeps the ban
+	restarted, err := p2p.SetupNode(":9271", node.Blockchain, tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to setup node: %v", err)
+	}
+
+	if err := restarted.LoadBans(); err != nil {
+		t.Fatalf("Failed to load bans: %v", err)
+	}
+
+	bans := restarted.GetBans()
+	if len(bans) != 1 || bans[0].Address != "127.0.0.1:1" || bans[0].Score != p2p.BanThreshold {
+		t.Fatalf("Expected the ban to be loaded, got %+v", bans)
+	}
+
+	if bans[0].ExpiresAt-bans[0].BannedAt != p2p.DefaultBanDuration.Milliseconds() {
+		t.Errorf("Expected the ban to last %v", p2p.DefaultBanDuration)
+	}
+}
+
+func TestBan_OversizedLocatorIsMalformed(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9272")
+
+	conn := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	for _, size := range []int{blockchain.MaxLocatorHashes + 1, blockchain.MaxLocatorHashes} {
+		locator := make([][]byte, size)
+		for idx := range locator {
+			locator[idx] = bytes.Repeat([]byte{0xab}, 32)
+		}
+
+		payload, err := json.Marshal(types.GetHeadersPayload{Locator: locator})
+		if err != nil {
+			t.Fatalf("Failed to marshal locator: %v", err)
+		}
+
+		writeMessage(t, conn, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", payload))
+	}
+
+	// Only the locator within the limit is answered
+	reply := readMessage(t, conn)
+	if reply.Type != types.SendBlockHeadersMsg {
+		t.Fatalf("Expected %s, got %s", types.SendBlockHeadersMsg, reply.Type)
+	}
+
+	var headers []blockchain.BlockHeader
+	if err := reply.Payload.Unmarshal(&headers); err != nil {
+		t.Fatalf("Failed to unmarshal headers: %v", err)
+	}
+
+	if len(headers) != 1 || headers[0].Id != 0 {
+		t.Errorf("Expected the genesis header, got %+v", headers)
+	}
+
+	if score := node.GetPeers()[0].Score; score != 10 {
+		t.Errorf("Expected a malformed message to score 10, got %d", score)
+	}
+}
+
+func TestBan_ImpostorCannotTakeOverAddress(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9274")
+
+	honest := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	// A second connection claiming the same address is refused, whatever it sends
+	impostor, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to dial node: %v", err)
+	}
+	defer impostor.Close()
+
+	messages := []*types.Message{
+		versionMessage(t, testVersion(node, "127.0.0.1:1")),
+		types.NewMessage(types.VerackMsg, "127.0.0.1:1", types.Payload{}),
+	}
+	for range p2p.BanThreshold / 10 {
+		messages = append(messages, types.NewMessage(types.TxMsg, "127.0.0.1:1", types.Payload("{not json")))
+	}
+
+	// Writes fail once the node hung up
+	for _, msg := range messages {
+		if err := types.WriteFrame(impostor, msg.Marshal()); err != nil {
+			break
+		}
+	}
+
+	assertClosed(t, impostor)
+
+	if bans := node.GetBans(); len(bans) != 0 {
+		t.Fatalf("The honest peer should not be banned, got %+v", bans)
+	}
+
+	peers := node.GetPeers()
+	if len(peers) != 1 || !peers[0].Connected || peers[0].Score != 0 {
+		t.Fatalf("Expected the honest peer connected with no score, got %+v", peers)
+	}
+
+	writeMessage(t, honest, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}))
+
+	if reply := readMessage(t, honest); reply.Type != types.SendBlockHeadersMsg {
+		t.Errorf("Expected %s, got %s", types.SendBlockHeadersMsg, reply.Type)
+	}
+}
+
+func TestBan_ValidBlockWithTransactionsNotPenalized(t *testing.T) {
+	source, tlsConfig := setupSessionNode(t, ":9277")
+	node := setupPeerNode(t, ":9278", source)
+
+	mined, _ := mineWithTx(t, source, node)
+
+	// As served from the database, where the tx is confirmed
+	block, err := source.Blockchain.GetBlockById(mined.Id)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", block))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for node.Blockchain.GetHeight() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the block to connect, height is %d", node.Blockchain.GetHeight())
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if score := node.GetPeers()[0].Score; score != 0 {
+		t.Errorf("A valid block should not raise the sender's score, got %d", score)
+	}
+}
+
+func TestBan_BlockRejectedByLocalStateNotBanned(t *testing.T) {
+	source, tlsConfig := setupSessionNode(t, ":9279")
+	node := setupPeerNode(t, ":9280", source)
+
+	// The tx sender is funded on the source only, so our state rejects the block
+	block, _ := mineWithTx(t, source)
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", block))
+
+	// Still answered, so the session survived the block
+	writeMessage(t, sender, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}))
+	if reply := readMessage(t, sender); reply.Type != types.SendBlockHeadersMsg {
+		t.Fatalf("Expected %s, got %s", types.SendBlockHeadersMsg, reply.Type)
+	}
+
+	if height := node.Blockchain.GetHeight(); height != 0 {
+		t.Errorf("Expected the block to be rejected, height is %d", height)
+	}
+
+	if score := node.GetPeers()[0].Score; score != 0 {
+		t.Errorf("A block with valid proof of work should not raise the sender's score, got %d", score)
+	}
+
+	if bans := node.GetBans(); len(bans) != 0 {
+		t.Errorf("Expected no bans, got %+v", bans)
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+// mineWithTx -> Mines a block on miner holding one tx that is valid on every node of
+// fundOn, returns the block and the tx
+func mineWithTx(t *testing.T, miner *p2p.Node, fundOn ...*p2p.Node) (*blockchain.Block, *blockchain.Transaction) {
+	t.Helper()
+
+	tx := signedTx(t, append(fundOn, miner)...)
+	if err := miner.Blockchain.AddTransactionToMempool(tx); err != nil {
+		t.Fatalf("Failed to add transaction to mempool: %v", err)
+	}
+
+	mineBlocks(t, miner, 1)
+
+	block := miner.Blockchain.GetLatestBlock()
+	if len(block.Transactions) != 2 {
+		t.Fatalf("Expected the block to hold the coinbase and the tx, got %d txs", len(block.Transactions))
+	}
+
+	return block, tx
+}
+
+func compactMessage(t *testing.T, sender string, block *blockchain.Block) *types.Message {
+	t.Helper()
+
+	compact, err := blockchain.NewCompactBlock(block)
+	if err != nil {
+		t.Fatalf("Failed to create compact block: %v", err)
+	}
+
+	payload, err := json.Marshal(compact)
+	if err != nil {
+		t.Fatalf("Failed to marshal compact block: %v", err)
+	}
+
+	return types.NewMessage(types.CompactBlockMsg, sender, payload)
+}
+
+func TestCompactBlock_RebuiltFromMempool(t *testing.T) {
+	source, _ := setupSessionNode(t, ":9257")
+	node := setupPeerNode(t, ":9258", source)
+
+	block, tx := mineWithTx(t, source, node)
+	if err := node.Blockchain.AddTransactionToMempool(tx); err != nil {
+		t.Fatalf("Failed to add transaction to mempool: %v", err)
+	}
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, compactMessage(t, "127.0.0.1:1", block))
+
+	waitForHeight(t, node, 1)
+
+	// Nothing was missing, so nothing was asked for
+	assertNoMessage(t, sender)
+
+	if node.Blockchain.Mempool.HasTransaction(tx.Hash().EncodeToString()) {
+		t.Error("Mined transaction should have left the mempool")
+	}
+}
+
+func TestCompactBlock_FetchesMissingTransactions(t *testing.T) {
+	miner, _ := setupSessionNode(t, ":9259")
+	node := setupPeerNode(t, ":9260", miner)
+
+	block, _ := mineWithTx(t, miner, node)
+
+	miner.LoadPeers([]string{node.GetCurrentTcpAddress()})
+	if err := miner.BroadcastBlock(block); err != nil {
+		t.Fatalf("Failed to broadcast block: %v", err)
+	}
+
+	waitForHeight(t, node, 1)
+
+	if !bytes.Equal(node.Blockchain.GetLatestBlock().Hash, block.Hash) {
+		t.Error("Rebuilt tip should be the miner's tip")
+	}
+}
+
+func TestCompactBlock_FallsBackToFullBlock(t *testing.T) {
+	source, _ := setupSessionNode(t, ":9261")
+	node := setupPeerNode(t, ":9262", source)
+
+	block, _ := mineWithTx(t, source, node)
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, compactMessage(t, "127.0.0.1:1", block))
+
+	msg := readMessage(t, sender)
+	if msg.Type != types.GetBlockTxsMsg {
+		t.Fatalf("Expected %s, got %s", types.GetBlockTxsMsg, msg.Type)
+	}
+
+	var request types.GetBlockTxsPayload
+	if err := msg.Payload.Unmarshal(&request); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+
+	if len(request.Indexes) != 1 || request.Indexes[0] != 0 {
+		t.Fatalf("Expected the tx after the coinbase to be asked for, got %v", request.Indexes)
+	}
+
+	// Another tx than the one in the block cannot complete it
+	wrong, err := json.Marshal([]blockchain.Transaction{*signedTx(t)})
+	if err != nil {
+		t.Fatalf("Failed to marshal transactions: %v", err)
+	}
+	writeMessage(t, sender, types.NewMessage(types.BlockTxsMsg, "127.0.0.1:1", wrong).WithRequestId(msg.RequestId))
+
+	msg = readMessage(t, sender)
+	if msg.Type != types.RequestBlockMsg {
+		t.Fatalf("Expected %s, got %s", types.RequestBlockMsg, msg.Type)
+	}
+
+	full, err := json.Marshal(block)
+	if err != nil {
+		t.Fatalf("Failed to marshal block: %v", err)
+	}
+	writeMessage(t, sender, types.NewMessage(types.SendBlockMsg, "127.0.0.1:1", full).WithRequestId(msg.RequestId))
+
+	waitForHeight(t, node, 1)
+
+	if !bytes.Equal(node.Blockchain.GetLatestBlock().Hash, block.Hash) {
+		t.Error("Tip should be the full block")
+	}
+}
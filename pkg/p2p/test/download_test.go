@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDownload_FromMultiplePeers(t *testing.T) {
+	peerA, _ := setupSessionNode(t, ":9240")
+	peerB := setupPeerNode(t, ":9241", peerA)
+	node := setupPeerNode(t, ":9242", peerA)
+
+	mineBlocks(t, peerA, 3)
+
+	syncWith(t, peerB, peerA)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// peerB advertises height 3 in its handshake, so blocks are requested from it too
+	if err := node.Connect(ctx, peerB.GetCurrentTcpAddress()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	if err := node.ConnectAndSync(ctx, peerA.GetCurrentTcpAddress()); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	status := node.GetSyncStatus()
+	if status.Syncing || status.StartHeight != 0 || status.CurrentHeight != 3 || status.TargetHeight != 3 {
+		t.Errorf("Unexpected sync status: %+v", status)
+	}
+
+	for _, peer := range []string{peerA.GetCurrentTcpAddress(), peerB.GetCurrentTcpAddress()} {
+		if !slices.Contains(status.Peers, peer) {
+			t.Errorf("Expected %s to serve blocks, got %v", peer, status.Peers)
+		}
+	}
+
+	if string(node.Blockchain.GetLatestBlock().Hash) != string(peerA.Blockchain.GetLatestBlock().Hash) {
+		t.Error("Synced tip should be the peer's tip")
+	}
+}
+
+func TestDownload_StalledPeerIsReplaced(t *testing.T) {
+	peer, _ := setupSessionNode(t, ":9243")
+	node := setupPeerNode(t, ":9244", peer)
+	node.BlockStallTimeout = 300 * time.Millisecond
+
+	mineBlocks(t, peer, 2)
+
+	// Claims to have the blocks but never answers a request
+	stalling := testVersion(node, "127.0.0.1:1")
+	stalling.BestHeight = 100
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+	dialPeer(t, node, tlsConfig, stalling)
+
+	syncWith(t, node, peer)
+
+	if height := node.Blockchain.GetHeight(); height != 2 {
+		t.Errorf("Expected height 2, got %d", height)
+	}
+
+	if status := node.GetSyncStatus(); !slices.Contains(status.Peers, stalling.Address) {
+		t.Errorf("Expected the stalling peer to have been asked, got %v", status.Peers)
+	}
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+// assertClosed -> Fails unless node closes conn without answering
+func assertClosed(t *testing.T, conn *tls.Conn) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	for {
+		data, err := types.ReadFrame(conn)
+		if err != nil {
+			return
+		}
+
+		var msg types.Message
+		if err := json.Unmarshal(data, &msg); err == nil && msg.Type != types.VersionMsg && msg.Type != types.VerackMsg {
+			t.Fatalf("Expected the session to be closed, got %s", msg.Type)
+		}
+	}
+}
+
+func TestHandshake_RecordsPeerVersion(t *testing.T) {
+	node, _ := setupSessionNode(t, ":9220")
+	peer := setupPeerNode(t, ":9221", node)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := node.ConnectAndSync(ctx, peer.GetCurrentTcpAddress()); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	peers := node.GetPeers()
+	if len(peers) != 1 || peers[0].Address != peer.GetCurrentTcpAddress() {
+		t.Fatalf("Expected only %s as peer, got %+v", peer.GetCurrentTcpAddress(), peers)
+	}
+
+	version := peers[0].Version
+	if version == nil || !peers[0].Connected || peers[0].Inbound {
+		t.Fatalf("Expected a connected outbound peer with its version, got %+v", peers[0])
+	}
+
+	if version.ProtocolVersion != p2p.ProtocolVersion || version.UserAgent != p2p.UserAgent || version.Services&p2p.ServiceFullNode == 0 {
+		t.Errorf("Unexpected peer version: %+v", version)
+	}
+}
+
+func TestHandshake_RejectsIncompatiblePeers(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9222")
+
+	otherChain := testVersion(node, "127.0.0.1:1")
+	otherChain.ChainId = "testnet"
+
+	otherGenesis := testVersion(node, "127.0.0.1:1")
+	otherGenesis.GenesisHash = bytes.Repeat([]byte{0xab}, 32)
+
+	oldProtocol := testVersion(node, "127.0.0.1:1")
+	oldProtocol.ProtocolVersion = p2p.MinProtocolVersion - 1
+
+	testCases := []struct {
+		name  string
+		first *types.Message
+	}{
+		{"message before handshake", types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{})},
+		{"verack before version", types.NewMessage(types.VerackMsg, "127.0.0.1:1", types.Payload{})},
+		{"other chain", versionMessage(t, otherChain)},
+		{"other genesis", versionMessage(t, otherGenesis)},
+		{"old protocol", versionMessage(t, oldProtocol)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+			if err != nil {
+				t.Fatalf("Failed to dial node: %v", err)
+			}
+			defer conn.Close()
+
+			writeMessage(t, conn, tc.first)
+			writeMessage(t, conn, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}))
+
+			assertClosed(t, conn)
+		})
+	}
+}
+
+// assertClosedBy -> Fails unless node closes conn on its own before deadline
+func assertClosedBy(t *testing.T, conn *tls.Conn, deadline time.Duration) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(deadline)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	for {
+		if _, err := types.ReadFrame(conn); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Fatal("Node kept the session open")
+			}
+			return
+		}
+	}
+}
+
+func TestHandshake_IdleInboundSessionClosed(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9281")
+	node.HandshakeTimeout = 200 * time.Millisecond
+
+	conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to dial node: %v", err)
+	}
+	defer conn.Close()
+
+	assertClosedBy(t, conn, 3*time.Second)
+}
+
+func TestHandshake_LargeFrameBeforeHandshakeClosesConnection(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9282")
+
+	conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to dial node: %v", err)
+	}
+	defer conn.Close()
+
+	// Well below MaxFrameSize but far more than a version message needs
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, 1<<20)
+	if _, err := conn.Write(header); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+
+	assertClosedBy(t, conn, 3*time.Second)
+}
+
+// versionMessage -> Version message advertising version
+func versionMessage(t *testing.T, version types.VersionPayload) *types.Message {
+	t.Helper()
+
+	payload, err := json.Marshal(version)
+	if err != nil {
+		t.Fatalf("Failed to marshal version: %v", err)
+	}
+
+	return types.NewMessage(types.VersionMsg, version.Address, payload)
+}
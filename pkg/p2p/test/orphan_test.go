@@ -0,0 +1,108 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// waitForOrphans -> Waits until node has count orphans waiting
+func waitForOrphans(t *testing.T, node *p2p.Node, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for node.GetSyncStatus().Orphans != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d orphans, got %d", count, node.GetSyncStatus().Orphans)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestOrphan_ConnectsWhenParentArrives(t *testing.T) {
+	source, _ := setupSessionNode(t, ":9263")
+	node := setupPeerNode(t, ":9264", source)
+
+	mineBlocks(t, source, 2)
+
+	parent, err := source.Blockchain.GetBlockById(1)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+	child := source.Blockchain.GetLatestBlock()
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", child))
+	waitForOrphans(t, node, 1)
+
+	if height := node.Blockchain.GetHeight(); height != 0 {
+		t.Fatalf("Orphan should not be connected, got height %d", height)
+	}
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", parent))
+
+	waitForHeight(t, node, 2)
+	waitForOrphans(t, node, 0)
+
+	if !bytes.Equal(node.Blockchain.GetLatestBlock().Hash, child.Hash) {
+		t.Error("Tip should be the orphan")
+	}
+}
+
+func TestOrphan_FetchesAncestorsFromAnnouncingPeer(t *testing.T) {
+	source, _ := setupSessionNode(t, ":9265")
+	node := setupPeerNode(t, ":9266", source)
+
+	mineBlocks(t, source, 2)
+
+	// Only the tip is announced, its parent has to be fetched
+	source.LoadPeers([]string{node.GetCurrentTcpAddress()})
+	if err := source.BroadcastBlock(source.Blockchain.GetLatestBlock()); err != nil {
+		t.Fatalf("Failed to broadcast block: %v", err)
+	}
+
+	waitForHeight(t, node, 2)
+	waitForOrphans(t, node, 0)
+
+	if !bytes.Equal(node.Blockchain.GetLatestBlock().Hash, source.Blockchain.GetLatestBlock().Hash) {
+		t.Error("Tip should be the announced block")
+	}
+}
+
+func TestOrphan_WithoutProofOfWorkIsDropped(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9267")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	waitForConnected(t, node, 1)
+
+	block := &blockchain.Block{
+		Id:           5,
+		PrevHash:     bytes.Repeat([]byte{0xab}, 32),
+		Timestamp:    utils.GetTimestamp(),
+		Transactions: []blockchain.Transaction{*blockchain.CreateCoinbaseTx("miner", blockchain.MiningReward)},
+	}
+	if err := block.HashBlock(); err != nil {
+		t.Fatalf("Failed to hash block: %v", err)
+	}
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", block))
+
+	// Neither kept nor worth asking the peer for its ancestors
+	assertNoMessage(t, sender)
+
+	if orphans := node.GetSyncStatus().Orphans; orphans != 0 {
+		t.Errorf("Expected no orphans, got %d", orphans)
+	}
+}
@@ -0,0 +1,339 @@
+package test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+	"github.com/Nikolat27/simple_blockchain/pkg/utils"
+)
+
+// assertNoMessage -> Fails if conn receives anything for a second
+func assertNoMessage(t *testing.T, conn *tls.Conn) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	if data, err := types.ReadFrame(conn); err == nil {
+		t.Fatalf("Expected no message, got %s", data)
+	}
+}
+
+// waitForHeight -> Waits until node's chain reaches height
+func waitForHeight(t *testing.T, node *p2p.Node, height int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for node.Blockchain.GetHeight() < height {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected height %d, got %d", height, node.Blockchain.GetHeight())
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForConnected -> Waits until node finished the handshake with count peers
+func waitForConnected(t *testing.T, node *p2p.Node, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		connected := 0
+		for _, peer := range node.GetPeers() {
+			if peer.Connected {
+				connected++
+			}
+		}
+
+		if connected >= count {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connected peers, got %d", count, connected)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func blockMessage(t *testing.T, sender string, block *blockchain.Block) *types.Message {
+	t.Helper()
+
+	payload, err := json.Marshal(block)
+	if err != nil {
+		t.Fatalf("Failed to marshal block: %v", err)
+	}
+
+	return types.NewMessage(types.BlockBroadcastMsg, sender, payload)
+}
+
+// signedTx -> Tx signed by a new key pair whose address is funded on every node of fundOn
+func signedTx(t *testing.T, fundOn ...*p2p.Node) *blockchain.Transaction {
+	t.Helper()
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	for _, node := range fundOn {
+		db := node.Blockchain.Database
+
+		sqlTx, err := db.BeginTx()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+
+		if err := db.IncreaseUserBalance(sqlTx, keyPair.Address, 100_000); err != nil {
+			sqlTx.Rollback()
+			t.Fatalf("Failed to fund %s: %v", keyPair.Address, err)
+		}
+
+		if err := sqlTx.Commit(); err != nil {
+			t.Fatalf("Failed to commit transaction: %v", err)
+		}
+	}
+
+	tx := blockchain.NewTransaction(keyPair.Address, "bob", 1_000, utils.GetTimestamp())
+	tx.Fee = 10_000 // Well above the minimum relay fee rate
+
+	if err := tx.Sign(keyPair, blockchain.DefaultChainId); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	return tx
+}
+
+func invMessage(t *testing.T, msgType, sender string, hashes ...string) *types.Message {
+	t.Helper()
+
+	payload, err := json.Marshal(types.InvPayload{TxHashes: hashes})
+	if err != nil {
+		t.Fatalf("Failed to marshal inv: %v", err)
+	}
+
+	return types.NewMessage(msgType, sender, payload)
+}
+
+func txMessage(t *testing.T, sender string, tx *blockchain.Transaction) *types.Message {
+	t.Helper()
+
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("Failed to marshal transaction: %v", err)
+	}
+
+	return types.NewMessage(types.TxMsg, sender, payload)
+}
+
+// expectInv -> Reads a message of msgType from conn carrying exactly hash
+func expectInv(t *testing.T, conn *tls.Conn, msgType, hash string) {
+	t.Helper()
+
+	msg := readMessage(t, conn)
+	if msg.Type != msgType {
+		t.Fatalf("Expected %s, got %s", msgType, msg.Type)
+	}
+
+	var inv types.InvPayload
+	if err := msg.Payload.Unmarshal(&inv); err != nil {
+		t.Fatalf("Failed to unmarshal inv: %v", err)
+	}
+
+	if len(inv.TxHashes) != 1 || inv.TxHashes[0] != hash {
+		t.Fatalf("Expected %s for %s, got %v", msgType, hash, inv.TxHashes)
+	}
+}
+
+func TestRelay_BlockCrossesHops(t *testing.T) {
+	miner, _ := setupSessionNode(t, ":9250")
+	relay := setupPeerNode(t, ":9251", miner)
+	last := setupPeerNode(t, ":9252", miner)
+
+	// A line, the miner cannot reach the last node itself
+	miner.LoadPeers([]string{relay.GetCurrentTcpAddress()})
+	relay.LoadPeers([]string{last.GetCurrentTcpAddress()})
+
+	mineBlocks(t, miner, 1)
+
+	if err := miner.BroadcastBlock(miner.Blockchain.GetLatestBlock()); err != nil {
+		t.Fatalf("Failed to broadcast block: %v", err)
+	}
+
+	waitForHeight(t, last, 1)
+
+	if !bytes.Equal(last.Blockchain.GetLatestBlock().Hash, miner.Blockchain.GetLatestBlock().Hash) {
+		t.Error("Relayed tip should be the miner's tip")
+	}
+}
+
+func TestRelay_SkipsSourceAndSeenBlocks(t *testing.T) {
+	source, _ := setupSessionNode(t, ":9253")
+	node := setupPeerNode(t, ":9254", source)
+
+	mineBlocks(t, source, 1)
+	block := source.Blockchain.GetLatestBlock()
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	other := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:2"))
+	waitForConnected(t, node, 2)
+
+	writeMessage(t, sender, blockMessage(t, "127.0.0.1:1", block))
+
+	msg := readMessage(t, other)
+	if msg.Type != types.CompactBlockMsg {
+		t.Fatalf("Expected %s, got %s", types.CompactBlockMsg, msg.Type)
+	}
+
+	var relayed blockchain.CompactBlock
+	if err := msg.Payload.Unmarshal(&relayed); err != nil {
+		t.Fatalf("Failed to unmarshal relayed block: %v", err)
+	}
+
+	if !bytes.Equal(relayed.Header.Hash, block.Hash) {
+		t.Error("Relayed block should be the one sent")
+	}
+
+	// Already seen, so it goes nowhere, and never back to the sender
+	writeMessage(t, other, blockMessage(t, "127.0.0.1:2", block))
+
+	assertNoMessage(t, sender)
+	assertNoMessage(t, other)
+
+	if height := node.Blockchain.GetHeight(); height != 1 {
+		t.Errorf("Expected height 1, got %d", height)
+	}
+}
+
+func TestRelay_TransactionInventory(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9255")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	other := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:2"))
+	announcer := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:3"))
+	waitForConnected(t, node, 3)
+
+	tx := signedTx(t, node)
+	hash := tx.Hash().EncodeToString()
+
+	// The announced tx is asked for once, not again from a second peer
+	writeMessage(t, sender, invMessage(t, types.InvMsg, "127.0.0.1:1", hash))
+	expectInv(t, sender, types.GetDataMsg, hash)
+
+	writeMessage(t, announcer, invMessage(t, types.InvMsg, "127.0.0.1:3", hash))
+	assertNoMessage(t, announcer)
+
+	// Once admitted it is announced only to the peer that does not have it
+	writeMessage(t, sender, txMessage(t, "127.0.0.1:1", tx))
+	expectInv(t, other, types.InvMsg, hash)
+	assertNoMessage(t, sender)
+	assertNoMessage(t, announcer)
+
+	if !node.Blockchain.Mempool.HasTransaction(hash) {
+		t.Fatal("Transaction should be in the mempool")
+	}
+
+	writeMessage(t, other, invMessage(t, types.GetDataMsg, "127.0.0.1:2", hash))
+
+	msg := readMessage(t, other)
+	if msg.Type != types.TxMsg {
+		t.Fatalf("Expected %s, got %s", types.TxMsg, msg.Type)
+	}
+
+	var received blockchain.Transaction
+	if err := msg.Payload.Unmarshal(&received); err != nil {
+		t.Fatalf("Failed to unmarshal transaction: %v", err)
+	}
+
+	if received.Hash().EncodeToString() != hash {
+		t.Error("Expected the asked for transaction")
+	}
+}
+
+func TestRelay_InvalidTransactionDropped(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9256")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	other := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:2"))
+	waitForConnected(t, node, 2)
+
+	unfunded := signedTx(t)
+
+	tampered := signedTx(t, node)
+	tampered.Amount++
+
+	coinbase := blockchain.CreateCoinbaseTx("127.0.0.1:1", blockchain.MiningReward)
+
+	for _, tx := range []*blockchain.Transaction{unfunded, tampered, coinbase} {
+		writeMessage(t, sender, txMessage(t, "127.0.0.1:1", tx))
+	}
+
+	assertNoMessage(t, other)
+
+	for _, tx := range []*blockchain.Transaction{unfunded, tampered, coinbase} {
+		if node.Blockchain.Mempool.HasTransaction(tx.Hash().EncodeToString()) {
+			t.Errorf("Invalid transaction %s should not be in the mempool", tx.Hash().EncodeToString())
+		}
+	}
+}
+
+func TestRelay_RejectedTransactionRequestedAgain(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9273")
+
+	sender := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+	other := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:2"))
+	watcher := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:3"))
+	waitForConnected(t, node, 3)
+
+	// The sender is not funded on our chain yet, as if we were behind
+	tx := signedTx(t)
+	hash := tx.Hash().EncodeToString()
+
+	writeMessage(t, sender, invMessage(t, types.InvMsg, "127.0.0.1:1", hash))
+	expectInv(t, sender, types.GetDataMsg, hash)
+
+	writeMessage(t, sender, txMessage(t, "127.0.0.1:1", tx))
+	assertNoMessage(t, watcher)
+
+	db := node.Blockchain.Database
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	if err := db.IncreaseUserBalance(sqlTx, tx.From, 100_000); err != nil {
+		sqlTx.Rollback()
+		t.Fatalf("Failed to fund %s: %v", tx.From, err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	// Once we caught up the rejected tx is asked for and admitted
+	writeMessage(t, other, invMessage(t, types.InvMsg, "127.0.0.1:2", hash))
+	expectInv(t, other, types.GetDataMsg, hash)
+
+	writeMessage(t, other, txMessage(t, "127.0.0.1:2", tx))
+	expectInv(t, watcher, types.InvMsg, hash)
+
+	if !node.Blockchain.Mempool.HasTransaction(hash) {
+		t.Error("Transaction should be in the mempool")
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+func TestRequests_ConcurrentSyncs(t *testing.T) {
+	node, _ := setupSessionNode(t, ":9210")
+	peerA := setupPeerNode(t, ":9211", node)
+	peerB := setupPeerNode(t, ":9212", node)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Each response must reach the request that asked for it
+	var wg sync.WaitGroup
+	errCh := make(chan error, 6)
+	for range 3 {
+		for _, peer := range []string{peerA.GetCurrentTcpAddress(), peerB.GetCurrentTcpAddress()} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errCh <- node.ConnectAndSync(ctx, peer)
+			}()
+		}
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			t.Errorf("Sync failed: %v", err)
+		}
+	}
+}
+
+func TestRequests_UnsolicitedResponseDropped(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9213")
+
+	conn := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+
+	// No request 99 is pending, the node must drop it without stalling the session
+	writeMessage(t, conn, types.NewMessage(types.SendBlockMsg, "127.0.0.1:1", types.Payload(`{}`)).WithRequestId(99))
+	writeMessage(t, conn, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}).WithRequestId(7))
+
+	reply := readMessage(t, conn)
+	if reply.Type != types.SendBlockHeadersMsg || reply.RequestId != 7 {
+		t.Errorf("Expected %s for request 7, got %s for request %d", types.SendBlockHeadersMsg, reply.Type, reply.RequestId)
+	}
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p/types"
+)
+
+// setupSessionNode -> Node listening on port with a fresh chain
+func setupSessionNode(t *testing.T, port string) (*p2p.Node, *tls.Config) {
+	t.Helper()
+
+	db, cleanup := setupTestDatabase(t)
+	t.Cleanup(cleanup)
+
+	bc, err := blockchain.NewBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	node, err := p2p.SetupNode(port, bc, tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to setup node: %v", err)
+	}
+
+	return node, tlsConfig
+}
+
+// readMessage -> Next framed message on conn
+func readMessage(t *testing.T, conn *tls.Conn) *types.Message {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	data, err := types.ReadFrame(conn)
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+
+	var msg types.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+
+	return &msg
+}
+
+// writeMessage -> Frames msg onto conn
+func writeMessage(t *testing.T, conn *tls.Conn, msg *types.Message) {
+	t.Helper()
+
+	if err := types.WriteFrame(conn, msg.Marshal()); err != nil {
+		t.Fatalf("Failed to write %s: %v", msg.Type, err)
+	}
+}
+
+// dialPeer -> Raw connection to node that completed the handshake as version
+func dialPeer(t *testing.T, node *p2p.Node, tlsConfig *tls.Config, version types.VersionPayload) *tls.Conn {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to dial node: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	payload, err := json.Marshal(version)
+	if err != nil {
+		t.Fatalf("Failed to marshal version: %v", err)
+	}
+
+	writeMessage(t, conn, types.NewMessage(types.VersionMsg, version.Address, payload))
+
+	if msg := readMessage(t, conn); msg.Type != types.VersionMsg {
+		t.Fatalf("Expected %s, got %s", types.VersionMsg, msg.Type)
+	}
+
+	if msg := readMessage(t, conn); msg.Type != types.VerackMsg {
+		t.Fatalf("Expected %s, got %s", types.VerackMsg, msg.Type)
+	}
+
+	writeMessage(t, conn, types.NewMessage(types.VerackMsg, version.Address, types.Payload{}))
+
+	return conn
+}
+
+// testVersion -> Version of a peer compatible with node, listening on address
+func testVersion(node *p2p.Node, address string) types.VersionPayload {
+	return types.VersionPayload{
+		ProtocolVersion: p2p.ProtocolVersion,
+		ChainId:         blockchain.DefaultChainId,
+		GenesisHash:     node.Blockchain.GenesisHash(),
+		Address:         address,
+		Services:        p2p.ServiceFullNode,
+		UserAgent:       "test",
+	}
+}
+
+func TestFrame_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	for _, data := range [][]byte{[]byte(`{"type":"a"}`), {}, []byte(`{"type":"b"}`)} {
+		if err := types.WriteFrame(&buf, data); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+	}
+
+	for _, expected := range []string{`{"type":"a"}`, ``, `{"type":"b"}`} {
+		data, err := types.ReadFrame(&buf)
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+
+		if string(data) != expected {
+			t.Errorf("Expected frame %q, got %q", expected, data)
+		}
+	}
+}
+
+func TestFrame_TooLarge(t *testing.T) {
+	if err := types.WriteFrame(&bytes.Buffer{}, make([]byte, types.MaxFrameSize+1)); !errors.Is(err, types.ErrFrameTooLarge) {
+		t.Errorf("Expected ErrFrameTooLarge on write, got %v", err)
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, types.MaxFrameSize+1)
+	if _, err := types.ReadFrame(bytes.NewReader(header)); !errors.Is(err, types.ErrFrameTooLarge) {
+		t.Errorf("Expected ErrFrameTooLarge on read, got %v", err)
+	}
+}
+
+func TestSession_RepliesOnSameConnection(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9200")
+
+	// Nothing listens on the advertised address, so replies can only come back on conn
+	conn := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+
+	for range 2 {
+		writeMessage(t, conn, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}))
+
+		reply := readMessage(t, conn)
+		if reply.Type != types.SendBlockHeadersMsg {
+			t.Fatalf("Expected %s, got %s", types.SendBlockHeadersMsg, reply.Type)
+		}
+
+		var headers []blockchain.BlockHeader
+		if err := reply.Payload.Unmarshal(&headers); err != nil {
+			t.Fatalf("Failed to unmarshal headers: %v", err)
+		}
+
+		if len(headers) != 1 || headers[0].Id != 0 {
+			t.Errorf("Expected the genesis header, got %+v", headers)
+		}
+	}
+}
+
+func TestSession_OversizedFrameClosesConnection(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9201")
+
+	conn, err := tls.Dial("tcp", node.GetCurrentTcpAddress(), tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to dial node: %v", err)
+	}
+	defer conn.Close()
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, types.MaxFrameSize+1)
+	if _, err := conn.Write(header); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	if _, err := types.ReadFrame(conn); err == nil {
+		t.Error("Node should close the session after an oversized frame")
+	}
+}
+
+func TestSession_StalledFrameClosesConnection(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9283")
+	node.ReadTimeout = 200 * time.Millisecond
+
+	conn := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+
+	// Announce 100 bytes but stop after 10
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, 100)
+	if _, err := conn.Write(append(header, make([]byte, 10)...)); err != nil {
+		t.Fatalf("Failed to write partial frame: %v", err)
+	}
+
+	assertClosedBy(t, conn, 3*time.Second)
+}
+
+func TestSession_QuietPeerStaysConnected(t *testing.T) {
+	node, tlsConfig := setupSessionNode(t, ":9284")
+	node.ReadTimeout = 200 * time.Millisecond
+
+	conn := dialPeer(t, node, tlsConfig, testVersion(node, "127.0.0.1:1"))
+
+	// Waiting between frames is not a stall
+	time.Sleep(3 * node.ReadTimeout)
+
+	writeMessage(t, conn, types.NewMessage(types.RequestHeadersMsg, "127.0.0.1:1", types.Payload{}))
+
+	if reply := readMessage(t, conn); reply.Type != types.SendBlockHeadersMsg {
+		t.Fatalf("Expected %s, got %s", types.SendBlockHeadersMsg, reply.Type)
+	}
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/blockchain"
+	"github.com/Nikolat27/simple_blockchain/pkg/p2p"
+)
+
+// setupPeerNode -> Node listening on port whose chain starts at the genesis block of source
+func setupPeerNode(t *testing.T, port string, source *p2p.Node) *p2p.Node {
+	t.Helper()
+
+	db, cleanup := setupTestDatabase(t)
+	t.Cleanup(cleanup)
+
+	bc, err := blockchain.LoadBlockchain(db, blockchain.NewMempool(1048576), blockchain.DefaultChainId)
+	if err != nil {
+		t.Fatalf("Failed to load blockchain: %v", err)
+	}
+
+	genesis, err := source.Blockchain.GetBlockById(0)
+	if err != nil {
+		t.Fatalf("Failed to get genesis block: %v", err)
+	}
+
+	sqlTx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := bc.AddBlock(sqlTx, genesis); err != nil {
+		t.Fatalf("Failed to add genesis block: %v", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	bc.AddBlockToMemory(genesis)
+
+	tlsConfig, err := initTls()
+	if err != nil {
+		t.Fatalf("Failed to init tls: %v", err)
+	}
+
+	node, err := p2p.SetupNode(port, bc, tlsConfig)
+	if err != nil {
+		t.Fatalf("Failed to setup node: %v", err)
+	}
+
+	return node
+}
+
+// mineBlocks -> Mines count empty blocks on top of node's chain
+func mineBlocks(t *testing.T, node *p2p.Node, count int) {
+	t.Helper()
+
+	for range count {
+		block, err := node.Blockchain.MineBlock(context.Background(), node.Blockchain.Mempool, "miner")
+		if err != nil || block == nil {
+			t.Fatalf("Failed to mine block: %v", err)
+		}
+	}
+}
+
+// syncWith -> Syncs node with peer, failing the test on error
+func syncWith(t *testing.T, node, peer *p2p.Node) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := node.ConnectAndSync(ctx, peer.GetCurrentTcpAddress()); err != nil {
+		t.Fatalf("Sync with %s failed: %v", peer.GetCurrentTcpAddress(), err)
+	}
+}
+
+func TestSync_DownloadsOnlyNewBlocks(t *testing.T) {
+	peer, _ := setupSessionNode(t, ":9230")
+	node := setupPeerNode(t, ":9231", peer)
+
+	mineBlocks(t, peer, 2)
+
+	syncWith(t, node, peer)
+
+	if height := node.Blockchain.GetHeight(); height != 2 {
+		t.Fatalf("Expected height 2 after the first sync, got %d", height)
+	}
+
+	// The second sync starts from the shared tip
+	mineBlocks(t, peer, 1)
+
+	syncWith(t, node, peer)
+
+	if height := node.Blockchain.GetHeight(); height != 3 {
+		t.Errorf("Expected height 3 after the second sync, got %d", height)
+	}
+
+	tip := node.Blockchain.GetLatestBlock()
+	if string(tip.Hash) != string(peer.Blockchain.GetLatestBlock().Hash) {
+		t.Error("Synced tip should be the peer's tip")
+	}
+}
+
+func TestSync_BlockWithTransactions(t *testing.T) {
+	peer, _ := setupSessionNode(t, ":9275")
+	node := setupPeerNode(t, ":9276", peer)
+
+	// The peer serves the block from its database, where the tx is confirmed
+	block, tx := mineWithTx(t, peer, node)
+
+	syncWith(t, node, peer)
+
+	if height := node.Blockchain.GetHeight(); height != 1 {
+		t.Fatalf("Expected height 1, got %d", height)
+	}
+
+	synced := node.Blockchain.GetLatestBlock()
+	if !bytes.Equal(synced.Hash, block.Hash) || len(synced.Transactions) != 2 {
+		t.Fatalf("Expected the peer's block with its tx, got %+v", synced)
+	}
+
+	if synced.Transactions[1].LedgerId() != tx.LedgerId() {
+		t.Error("Synced block should hold the peer's tx")
+	}
+}
+
+func TestSync_RejectsOtherGenesis(t *testing.T) {
+	node, _ := setupSessionNode(t, ":9232")
+	peer, _ := setupSessionNode(t, ":9233")
+
+	// Genesis blocks are timestamped in milliseconds, make sure they differ
+	if string(node.Blockchain.GetLatestBlock().Hash) == string(peer.Blockchain.GetLatestBlock().Hash) {
+		t.Skip("Both nodes created the same genesis block")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := node.ConnectAndSync(ctx, peer.GetCurrentTcpAddress()); err == nil {
+		t.Error("Syncing with a chain on another genesis block should fail")
+	}
+}
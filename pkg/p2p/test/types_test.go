@@ -144,8 +144,10 @@ func TestMessageTypes_Constants(t *testing.T) {
 		"SendBlockHeadersMsg": types.SendBlockHeadersMsg,
 		"RequestBlockMsg":     types.RequestBlockMsg,
 		"SendBlockMsg":        types.SendBlockMsg,
-		"MempoolBroadcastMsg": types.MempoolBroadcastMsg,
 		"BlockBroadcastMsg":   types.BlockBroadcastMsg,
+		"InvMsg":              types.InvMsg,
+		"GetDataMsg":          types.GetDataMsg,
+		"TxMsg":               types.TxMsg,
 		"CancelMiningMsg":     types.CancelMiningMsg,
 	}
 
@@ -172,8 +174,10 @@ func TestMessage_WithDifferentTypes(t *testing.T) {
 		types.SendBlockHeadersMsg,
 		types.RequestBlockMsg,
 		types.SendBlockMsg,
-		types.MempoolBroadcastMsg,
 		types.BlockBroadcastMsg,
+		types.InvMsg,
+		types.GetDataMsg,
+		types.TxMsg,
 		types.CancelMiningMsg,
 	}
 
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxFrameSize -> Largest message a peer may send in one frame, in bytes
+const MaxFrameSize = 32 << 20
+
+// frameHeaderSize -> Every frame starts with its length as a big-endian uint32
+const frameHeaderSize = 4
+
+var ErrFrameTooLarge = errors.New("frame exceeds max frame size")
+
+// WriteFrame -> Writes data as one length-prefixed frame
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(data))
+	}
+
+	frame := make([]byte, frameHeaderSize+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[frameHeaderSize:], data)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame -> Reads the next length-prefixed frame, refusing frames above MaxFrameSize
+// before allocating them
+func ReadFrame(r io.Reader) ([]byte, error) {
+	return ReadFrameLimit(r, MaxFrameSize)
+}
+
+// ReadFrameLimit -> Reads the next length-prefixed frame, refusing frames above maxSize
+// before allocating them
+func ReadFrameLimit(r io.Reader, maxSize uint32) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
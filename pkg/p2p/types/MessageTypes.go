@@ -6,17 +6,28 @@ import (
 )
 
 const (
+	VersionMsg = "version_msg"
+	VerackMsg  = "verack_msg"
+
 	RequestHeadersMsg   = "get_headers_msg"
 	SendBlockHeadersMsg = "send_headers_msg"
 
 	RequestBlockMsg = "get_block_msg"
 	SendBlockMsg    = "send_block_msg"
 
-	MempoolBroadcastMsg = "mempool_broadcast_msg"
-
 	BlockBroadcastMsg = "block_broadcast_msg"
 
+	CompactBlockMsg = "compact_block_msg" // Header, coinbase and short tx ids of a new block
+	GetBlockTxsMsg  = "get_block_txs_msg" // Asks for the txs of a compact block missing from our mempool
+	BlockTxsMsg     = "block_txs_msg"
+
+	InvMsg     = "inv_msg"      // Hashes of transactions the sender has
+	GetDataMsg = "get_data_msg" // Asks for transactions announced in an inv
+	TxMsg      = "tx_msg"       // A single transaction
+
 	CancelMiningMsg = "cancel_mining_msg"
+
+	RejectMsg = "reject_msg"
 )
 
 type Payload []byte
@@ -28,9 +39,44 @@ func (payload *Payload) Unmarshal(v any) error {
 type Message struct {
 	Type          string  `json:"type"`
 	SenderAddress string  `json:"sender_address"`
+	RequestId     uint64  `json:"request_id,omitempty"` // Set on requests and copied to their response
 	Payload       Payload `json:"payload"`
 }
 
+// VersionPayload -> What a node tells a peer about itself when a session opens
+type VersionPayload struct {
+	ProtocolVersion uint32 `json:"protocol_version"`
+	ChainId         string `json:"chain_id"`     // Network magic, peers on another chain are disconnected
+	GenesisHash     []byte `json:"genesis_hash"` // Peers whose chain starts at another block are disconnected too
+	Address         string `json:"address"`      // TCP address the node listens on
+	Services        uint64 `json:"services"`
+	BestHeight      int64  `json:"best_height"`
+	UserAgent       string `json:"user_agent"`
+}
+
+// GetHeadersPayload -> Asks for the headers after the last locator hash the peer shares
+type GetHeadersPayload struct {
+	Locator [][]byte `json:"locator"` // Block hashes from our tip back to genesis
+}
+
+// InvPayload -> Transaction hashes, announced with inv and asked for with get_data
+type InvPayload struct {
+	TxHashes []string `json:"tx_hashes"`
+}
+
+// GetBlockTxsPayload -> Txs of a compact block by their index after the coinbase
+type GetBlockTxsPayload struct {
+	BlockHash []byte `json:"block_hash"`
+	Indexes   []int  `json:"indexes"`
+}
+
+// RejectPayload -> Tells a peer why one of its transactions was not accepted
+type RejectPayload struct {
+	TxHash  string `json:"tx_hash"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
 func NewMessage(typ, senderAddr string, payload Payload) *Message {
 	return &Message{
 		Type:          typ,
@@ -39,6 +85,12 @@ func NewMessage(typ, senderAddr string, payload Payload) *Message {
 	}
 }
 
+// WithRequestId -> Ties msg to the request with the given id
+func (msg *Message) WithRequestId(requestId uint64) *Message {
+	msg.RequestId = requestId
+	return msg
+}
+
 func (msg *Message) Marshal() []byte {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -0,0 +1,54 @@
+package script
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Disassemble -> Human readable form of the script: opcode names, pushed data as hex.
+// Assemble turns the output back into the same script.
+func Disassemble(s Script) (string, error) {
+	instructions, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	tokens := make([]string, len(instructions))
+	for idx, instruction := range instructions {
+		if instruction.Op.isPush() && instruction.Op != OpFalse && instruction.Op != Op1Negate && !instruction.Op.isSmallInt() {
+			tokens[idx] = hex.EncodeToString(instruction.Data)
+			continue
+		}
+
+		tokens[idx] = instruction.Op.String()
+	}
+
+	return strings.Join(tokens, " "), nil
+}
+
+// Assemble -> Script of whitespace separated opcode names (OP_CHECKSIG) and hex data pushes
+func Assemble(asm string) (Script, error) {
+	builder := NewBuilder()
+
+	for _, token := range strings.Fields(asm) {
+		if strings.HasPrefix(token, "OP_") {
+			op, exists := opcodesByName[token]
+			if !exists || op == OpPushData1 || op == OpPushData2 {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownOpcode, token)
+			}
+
+			builder.AddOp(op)
+			continue
+		}
+
+		data, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %q: %w", token, err)
+		}
+
+		builder.AddData(data)
+	}
+
+	return builder.Script()
+}
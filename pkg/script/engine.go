@@ -0,0 +1,525 @@
+package script
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"math"
+)
+
+// Context -> What a script can observe about the spending tx and its block
+type Context struct {
+	SigHash []byte // message OP_CHECKSIG verifies, the hash of the spending tx
+	Height  int64  // height of the block the spend is in
+	Time    int64  // median time past of that block
+}
+
+// Execute -> Runs s on top of the witness items. The spend is valid if execution
+// finishes without error and leaves exactly one true item on the stack.
+func Execute(s Script, witness [][]byte, ctx *Context) error {
+	instructions, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if len(witness) > MaxStackSize {
+		return fmt.Errorf("%w: %d witness items", ErrStackOverflow, len(witness))
+	}
+
+	if ctx == nil {
+		ctx = &Context{}
+	}
+
+	engine := &engine{ctx: ctx}
+	for _, item := range witness {
+		if len(item) > MaxElementSize {
+			return fmt.Errorf("%w: witness item of %d bytes", ErrElementTooLarge, len(item))
+		}
+
+		engine.stack = append(engine.stack, item)
+	}
+
+	for pos, instruction := range instructions {
+		if err := engine.step(instruction); err != nil {
+			return fmt.Errorf("%s at instruction %d: %w", instruction.Op, pos, err)
+		}
+	}
+
+	if len(engine.conditions) != 0 {
+		return fmt.Errorf("%w: missing OP_ENDIF", ErrUnbalancedConditional)
+	}
+
+	if len(engine.stack) != 1 {
+		return fmt.Errorf("%w: %d items left", ErrCleanStack, len(engine.stack))
+	}
+
+	if !castToBool(engine.stack[0]) {
+		return ErrEvalFalse
+	}
+
+	return nil
+}
+
+type engine struct {
+	ctx        *Context
+	stack      [][]byte
+	conditions []bool // one entry per open OP_IF, whether its current branch runs
+	opCount    int
+}
+
+// executing -> Whether every enclosing branch runs
+func (engine *engine) executing() bool {
+	for _, condition := range engine.conditions {
+		if !condition {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (engine *engine) step(instruction Instruction) error {
+	op := instruction.Op
+
+	if !op.isPush() {
+		engine.opCount++
+		if engine.opCount > MaxOps {
+			return ErrOpLimit
+		}
+	}
+
+	// Conditionals are tracked even inside branches that do not run
+	switch op {
+	case OpIf, OpNotIf:
+		condition := false
+		if engine.executing() {
+			item, err := engine.pop()
+			if err != nil {
+				return err
+			}
+
+			condition = castToBool(item) == (op == OpIf)
+		}
+
+		engine.conditions = append(engine.conditions, condition)
+		return nil
+	case OpElse:
+		if len(engine.conditions) == 0 {
+			return ErrUnbalancedConditional
+		}
+
+		last := len(engine.conditions) - 1
+		engine.conditions[last] = !engine.conditions[last]
+		return nil
+	case OpEndIf:
+		if len(engine.conditions) == 0 {
+			return ErrUnbalancedConditional
+		}
+
+		engine.conditions = engine.conditions[:len(engine.conditions)-1]
+		return nil
+	}
+
+	if !engine.executing() {
+		return nil
+	}
+
+	switch {
+	case op == OpFalse:
+		return engine.push(nil)
+	case op == Op1Negate:
+		return engine.pushNum(-1)
+	case op.isSmallInt():
+		return engine.pushNum(int64(op-OpTrue) + 1)
+	case op.isPush():
+		return engine.push(instruction.Data)
+	}
+
+	switch op {
+	case OpNop:
+		return nil
+	case OpVerify:
+		return engine.verify()
+	case OpReturn:
+		return ErrEarlyReturn
+
+	case OpDrop:
+		_, err := engine.pop()
+		return err
+	case OpDup:
+		item, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+
+		return engine.push(item)
+	case OpOver:
+		item, err := engine.peek(1)
+		if err != nil {
+			return err
+		}
+
+		return engine.push(item)
+	case OpSwap:
+		if len(engine.stack) < 2 {
+			return ErrStackUnderflow
+		}
+
+		top := len(engine.stack) - 1
+		engine.stack[top], engine.stack[top-1] = engine.stack[top-1], engine.stack[top]
+		return nil
+	case OpSize:
+		item, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+
+		return engine.pushNum(int64(len(item)))
+
+	case OpEqual, OpEqualVerify:
+		b, err := engine.pop()
+		if err != nil {
+			return err
+		}
+
+		a, err := engine.pop()
+		if err != nil {
+			return err
+		}
+
+		if err := engine.pushBool(bytes.Equal(a, b)); err != nil {
+			return err
+		}
+
+		if op == OpEqualVerify {
+			return engine.verify()
+		}
+
+		return nil
+
+	case OpNot:
+		n, err := engine.popNum()
+		if err != nil {
+			return err
+		}
+
+		return engine.pushBool(n == 0)
+	case OpAdd, OpSub, OpBoolAnd, OpBoolOr, OpNumEqual, OpNumEqualVerify, OpLessThan, OpGreaterThan,
+		OpLessThanOrEqual, OpGreaterThanOrEqual, OpMin, OpMax:
+		return engine.binaryNum(op)
+	case OpWithin:
+		upper, err := engine.popNum()
+		if err != nil {
+			return err
+		}
+
+		lower, err := engine.popNum()
+		if err != nil {
+			return err
+		}
+
+		n, err := engine.popNum()
+		if err != nil {
+			return err
+		}
+
+		return engine.pushBool(lower <= n && n < upper)
+
+	case OpSHA256, OpAddrHash:
+		item, err := engine.pop()
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.Sum256(item)
+		if op == OpAddrHash {
+			return engine.push(hash[:20])
+		}
+
+		return engine.push(hash[:])
+	case OpCheckSig, OpCheckSigVerify:
+		if err := engine.checkSig(); err != nil {
+			return err
+		}
+
+		if op == OpCheckSigVerify {
+			return engine.verify()
+		}
+
+		return nil
+	case OpCheckMultisig, OpCheckMultisigVerify:
+		if err := engine.checkMultisig(); err != nil {
+			return err
+		}
+
+		if op == OpCheckMultisigVerify {
+			return engine.verify()
+		}
+
+		return nil
+
+	case OpCheckHeightVerify, OpCheckTimeVerify:
+		// The lock stays on the stack, as with OP_CHECKLOCKTIMEVERIFY in bitcoin
+		item, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+
+		lock, err := DecodeNum(item)
+		if err != nil {
+			return err
+		}
+
+		if lock < 0 {
+			return ErrNegativeLock
+		}
+
+		current := engine.ctx.Height
+		if op == OpCheckTimeVerify {
+			current = engine.ctx.Time
+		}
+
+		if current < lock {
+			return fmt.Errorf("%w: %d, currently %d", ErrLockNotReached, lock, current)
+		}
+
+		return nil
+	case OpHeight:
+		return engine.pushNum(engine.ctx.Height)
+	case OpTime:
+		return engine.pushNum(engine.ctx.Time)
+	}
+
+	return fmt.Errorf("%w: 0x%02x", ErrUnknownOpcode, byte(op))
+}
+
+func (engine *engine) binaryNum(op Opcode) error {
+	b, err := engine.popNum()
+	if err != nil {
+		return err
+	}
+
+	a, err := engine.popNum()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case OpAdd:
+		sum := a + b
+		if (b > 0 && sum < a) || (b < 0 && sum > a) || sum == math.MinInt64 {
+			return ErrNumberOverflow
+		}
+
+		return engine.pushNum(sum)
+	case OpSub:
+		diff := a - b
+		if (b < 0 && diff < a) || (b > 0 && diff > a) || diff == math.MinInt64 {
+			return ErrNumberOverflow
+		}
+
+		return engine.pushNum(diff)
+	case OpBoolAnd:
+		return engine.pushBool(a != 0 && b != 0)
+	case OpBoolOr:
+		return engine.pushBool(a != 0 || b != 0)
+	case OpNumEqual:
+		return engine.pushBool(a == b)
+	case OpNumEqualVerify:
+		if err := engine.pushBool(a == b); err != nil {
+			return err
+		}
+
+		return engine.verify()
+	case OpLessThan:
+		return engine.pushBool(a < b)
+	case OpGreaterThan:
+		return engine.pushBool(a > b)
+	case OpLessThanOrEqual:
+		return engine.pushBool(a <= b)
+	case OpGreaterThanOrEqual:
+		return engine.pushBool(a >= b)
+	case OpMin:
+		return engine.pushNum(min(a, b))
+	default: // OpMax
+		return engine.pushNum(max(a, b))
+	}
+}
+
+// checkSig -> Pops <sig> <pubkey>, pushes whether sig signs the context's SigHash.
+// An empty sig pushes false, any other failing sig is an error so that a failed
+// check cannot be satisfied by arbitrary witness data.
+func (engine *engine) checkSig() error {
+	pubKey, err := engine.pop()
+	if err != nil {
+		return err
+	}
+
+	sig, err := engine.pop()
+	if err != nil {
+		return err
+	}
+
+	if len(sig) == 0 {
+		return engine.pushBool(false)
+	}
+
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidPublicKey, len(pubKey))
+	}
+
+	if !ed25519.Verify(pubKey, engine.ctx.SigHash, sig) {
+		return ErrInvalidSignature
+	}
+
+	return engine.pushBool(true)
+}
+
+// checkMultisig -> Pops <sig1>..<sigM> <M> <pubkey1>..<pubkeyN> <N>. Signatures must
+// appear in the order of their keys, every key is tried at most once.
+func (engine *engine) checkMultisig() error {
+	keyCount, err := engine.popNum()
+	if err != nil {
+		return err
+	}
+
+	if keyCount < 0 || keyCount > MaxMultisigKeys {
+		return fmt.Errorf("%w: %d keys", ErrInvalidKeyCount, keyCount)
+	}
+
+	engine.opCount += int(keyCount)
+	if engine.opCount > MaxOps {
+		return ErrOpLimit
+	}
+
+	pubKeys := make([][]byte, keyCount)
+	for idx := keyCount - 1; idx >= 0; idx-- {
+		if pubKeys[idx], err = engine.pop(); err != nil {
+			return err
+		}
+	}
+
+	sigCount, err := engine.popNum()
+	if err != nil {
+		return err
+	}
+
+	if sigCount < 0 || sigCount > keyCount {
+		return fmt.Errorf("%w: %d of %d signatures", ErrInvalidKeyCount, sigCount, keyCount)
+	}
+
+	sigs := make([][]byte, sigCount)
+	for idx := sigCount - 1; idx >= 0; idx-- {
+		if sigs[idx], err = engine.pop(); err != nil {
+			return err
+		}
+	}
+
+	allEmpty := true
+	for _, sig := range sigs {
+		if len(sig) != 0 {
+			allEmpty = false
+		}
+	}
+
+	keyIdx := 0
+	for _, sig := range sigs {
+		matched := false
+		for ; keyIdx < len(pubKeys) && !matched; keyIdx++ {
+			pubKey := pubKeys[keyIdx]
+			if len(pubKey) != ed25519.PublicKeySize {
+				return fmt.Errorf("%w: %d bytes", ErrInvalidPublicKey, len(pubKey))
+			}
+
+			matched = len(sig) != 0 && ed25519.Verify(pubKey, engine.ctx.SigHash, sig)
+		}
+
+		if !matched {
+			if allEmpty {
+				return engine.pushBool(false)
+			}
+
+			return ErrInvalidSignature
+		}
+	}
+
+	return engine.pushBool(true)
+}
+
+func (engine *engine) verify() error {
+	item, err := engine.pop()
+	if err != nil {
+		return err
+	}
+
+	if !castToBool(item) {
+		return ErrVerifyFailed
+	}
+
+	return nil
+}
+
+func (engine *engine) push(item []byte) error {
+	if len(item) > MaxElementSize {
+		return fmt.Errorf("%w: %d bytes", ErrElementTooLarge, len(item))
+	}
+
+	if len(engine.stack) >= MaxStackSize {
+		return ErrStackOverflow
+	}
+
+	engine.stack = append(engine.stack, item)
+	return nil
+}
+
+func (engine *engine) pushNum(n int64) error {
+	return engine.push(EncodeNum(n))
+}
+
+func (engine *engine) pushBool(value bool) error {
+	if value {
+		return engine.pushNum(1)
+	}
+
+	return engine.push(nil)
+}
+
+func (engine *engine) pop() ([]byte, error) {
+	if len(engine.stack) == 0 {
+		return nil, ErrStackUnderflow
+	}
+
+	item := engine.stack[len(engine.stack)-1]
+	engine.stack = engine.stack[:len(engine.stack)-1]
+	return item, nil
+}
+
+func (engine *engine) popNum() (int64, error) {
+	item, err := engine.pop()
+	if err != nil {
+		return 0, err
+	}
+
+	return DecodeNum(item)
+}
+
+// peek -> Item depth places below the top of the stack
+func (engine *engine) peek(depth int) ([]byte, error) {
+	if len(engine.stack) <= depth {
+		return nil, ErrStackUnderflow
+	}
+
+	return engine.stack[len(engine.stack)-1-depth], nil
+}
+
+// castToBool -> Any non-zero byte is true, except a lone sign bit (negative zero)
+func castToBool(item []byte) bool {
+	for idx, b := range item {
+		if b != 0 {
+			return idx != len(item)-1 || b != 0x80
+		}
+	}
+
+	return false
+}
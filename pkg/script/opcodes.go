@@ -0,0 +1,161 @@
+package script
+
+import "fmt"
+
+// Opcode -> A single script instruction. Bytes 0x01-0x4b push that many bytes of data.
+type Opcode byte
+
+const (
+	OpFalse     Opcode = 0x00 // pushes an empty item, alias OP_0
+	OpPushData1 Opcode = 0x4c // next byte is the length of the pushed item
+	OpPushData2 Opcode = 0x4d // next 2 bytes (little endian) are the length of the pushed item
+	Op1Negate   Opcode = 0x4f
+	OpTrue      Opcode = 0x51 // pushes 1, alias OP_1
+	Op16        Opcode = 0x60 // OP_2 to OP_16 sit between OP_1 and OP_16
+	OpNop       Opcode = 0x61
+
+	// Flow control
+	OpIf     Opcode = 0x63
+	OpNotIf  Opcode = 0x64
+	OpElse   Opcode = 0x67
+	OpEndIf  Opcode = 0x68
+	OpVerify Opcode = 0x69
+	OpReturn Opcode = 0x6a
+
+	// Stack
+	OpDrop Opcode = 0x75
+	OpDup  Opcode = 0x76
+	OpOver Opcode = 0x78
+	OpSwap Opcode = 0x7c
+	OpSize Opcode = 0x82
+
+	// Comparison and boolean logic
+	OpEqual              Opcode = 0x87
+	OpEqualVerify        Opcode = 0x88
+	OpNot                Opcode = 0x91
+	OpAdd                Opcode = 0x93
+	OpSub                Opcode = 0x94
+	OpBoolAnd            Opcode = 0x9a
+	OpBoolOr             Opcode = 0x9b
+	OpNumEqual           Opcode = 0x9c
+	OpNumEqualVerify     Opcode = 0x9d
+	OpLessThan           Opcode = 0x9f
+	OpGreaterThan        Opcode = 0xa0
+	OpLessThanOrEqual    Opcode = 0xa1
+	OpGreaterThanOrEqual Opcode = 0xa2
+	OpMin                Opcode = 0xa3
+	OpMax                Opcode = 0xa4
+	OpWithin             Opcode = 0xa5
+
+	// Hashes and signatures
+	OpSHA256              Opcode = 0xa8
+	OpAddrHash            Opcode = 0xa9 // first 20 bytes of sha256, as in key addresses
+	OpCheckSig            Opcode = 0xac
+	OpCheckSigVerify      Opcode = 0xad
+	OpCheckMultisig       Opcode = 0xae
+	OpCheckMultisigVerify Opcode = 0xaf
+
+	// Chain height and time of the block the spend is in
+	OpCheckHeightVerify Opcode = 0xb1
+	OpCheckTimeVerify   Opcode = 0xb2
+	OpHeight            Opcode = 0xb3
+	OpTime              Opcode = 0xb4
+)
+
+var opcodeNames = map[Opcode]string{
+	OpFalse:     "OP_0",
+	OpPushData1: "OP_PUSHDATA1",
+	OpPushData2: "OP_PUSHDATA2",
+	Op1Negate:   "OP_1NEGATE",
+	OpNop:       "OP_NOP",
+
+	OpIf:     "OP_IF",
+	OpNotIf:  "OP_NOTIF",
+	OpElse:   "OP_ELSE",
+	OpEndIf:  "OP_ENDIF",
+	OpVerify: "OP_VERIFY",
+	OpReturn: "OP_RETURN",
+
+	OpDrop: "OP_DROP",
+	OpDup:  "OP_DUP",
+	OpOver: "OP_OVER",
+	OpSwap: "OP_SWAP",
+	OpSize: "OP_SIZE",
+
+	OpEqual:              "OP_EQUAL",
+	OpEqualVerify:        "OP_EQUALVERIFY",
+	OpNot:                "OP_NOT",
+	OpAdd:                "OP_ADD",
+	OpSub:                "OP_SUB",
+	OpBoolAnd:            "OP_BOOLAND",
+	OpBoolOr:             "OP_BOOLOR",
+	OpNumEqual:           "OP_NUMEQUAL",
+	OpNumEqualVerify:     "OP_NUMEQUALVERIFY",
+	OpLessThan:           "OP_LESSTHAN",
+	OpGreaterThan:        "OP_GREATERTHAN",
+	OpLessThanOrEqual:    "OP_LESSTHANOREQUAL",
+	OpGreaterThanOrEqual: "OP_GREATERTHANOREQUAL",
+	OpMin:                "OP_MIN",
+	OpMax:                "OP_MAX",
+	OpWithin:             "OP_WITHIN",
+
+	OpSHA256:              "OP_SHA256",
+	OpAddrHash:            "OP_ADDRHASH",
+	OpCheckSig:            "OP_CHECKSIG",
+	OpCheckSigVerify:      "OP_CHECKSIGVERIFY",
+	OpCheckMultisig:       "OP_CHECKMULTISIG",
+	OpCheckMultisigVerify: "OP_CHECKMULTISIGVERIFY",
+
+	OpCheckHeightVerify: "OP_CHECKHEIGHTVERIFY",
+	OpCheckTimeVerify:   "OP_CHECKTIMEVERIFY",
+	OpHeight:            "OP_HEIGHT",
+	OpTime:              "OP_TIME",
+}
+
+// opcodesByName -> Reverse of opcodeNames, with the OP_1 to OP_16 and OP_FALSE/OP_TRUE aliases
+var opcodesByName = func() map[string]Opcode {
+	byName := make(map[string]Opcode, len(opcodeNames)+18)
+	for op, name := range opcodeNames {
+		byName[name] = op
+	}
+
+	for n := OpTrue; n <= Op16; n++ {
+		byName[n.String()] = n
+	}
+
+	byName["OP_FALSE"] = OpFalse
+	byName["OP_TRUE"] = OpTrue
+
+	return byName
+}()
+
+// String -> Name of the opcode, OP_DATA_<n> for direct pushes and OP_UNKNOWN_<hex> otherwise
+func (op Opcode) String() string {
+	if name, exists := opcodeNames[op]; exists {
+		return name
+	}
+
+	if op.isSmallInt() {
+		return fmt.Sprintf("OP_%d", op-OpTrue+1)
+	}
+
+	if op > OpFalse && op < OpPushData1 {
+		return fmt.Sprintf("OP_DATA_%d", op)
+	}
+
+	return fmt.Sprintf("OP_UNKNOWN_%02x", byte(op))
+}
+
+// isPush -> Whether the opcode only pushes data, push opcodes do not count towards MaxOps
+func (op Opcode) isPush() bool {
+	return op <= Op16
+}
+
+func (op Opcode) isSmallInt() bool {
+	return op >= OpTrue && op <= Op16
+}
+
+func (op Opcode) isKnown() bool {
+	_, exists := opcodeNames[op]
+	return exists || op.isSmallInt() || (op > OpFalse && op < OpPushData1)
+}
@@ -0,0 +1,264 @@
+package script
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxScriptSize -> Upper bound (in bytes) of a script
+	MaxScriptSize = 10_000
+
+	// MaxElementSize -> Upper bound (in bytes) of a single stack item
+	MaxElementSize = 520
+
+	// MaxStackSize -> Upper bound of items on the stack, witness included
+	MaxStackSize = 1000
+
+	// MaxOps -> Upper bound of executed non-push opcodes, OP_CHECKMULTISIG also counts its keys
+	MaxOps = 201
+
+	// MaxMultisigKeys -> Upper bound of keys checked by one OP_CHECKMULTISIG
+	MaxMultisigKeys = 20
+
+	// maxNumSize -> Upper bound (in bytes) of a number operand
+	maxNumSize = 8
+)
+
+// Script -> Serialized opcodes and pushed data
+type Script []byte
+
+// Instruction -> One parsed opcode, Data is set for pushes
+type Instruction struct {
+	Op   Opcode
+	Data []byte
+}
+
+// Decode -> Script from its hex encoding
+func Decode(scriptHex string) (Script, error) {
+	raw, err := hex.DecodeString(scriptHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script hex: %w", err)
+	}
+
+	return Script(raw), nil
+}
+
+func (s Script) String() string {
+	return hex.EncodeToString(s)
+}
+
+// Hash -> sha256 of the script, the address commits to it
+func (s Script) Hash() []byte {
+	hash := sha256.Sum256(s)
+	return hash[:]
+}
+
+// Address -> Address whose funds are spent by running the script
+func (s Script) Address() string {
+	record := fmt.Sprintf("script-%x", s.Hash())
+
+	hash := sha256.Sum256([]byte(record))
+	return hex.EncodeToString(hash[:20])
+}
+
+// Parse -> Splits the script into instructions, rejecting unknown opcodes and truncated pushes
+func Parse(s Script) ([]Instruction, error) {
+	if len(s) > MaxScriptSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrScriptTooLarge, len(s))
+	}
+
+	var instructions []Instruction
+	for pos := 0; pos < len(s); {
+		op := Opcode(s[pos])
+		pos++
+
+		if !op.isKnown() {
+			return nil, fmt.Errorf("%w: 0x%02x at offset %d", ErrUnknownOpcode, byte(op), pos-1)
+		}
+
+		var size int
+		switch {
+		case op > OpFalse && op < OpPushData1:
+			size = int(op)
+		case op == OpPushData1:
+			if pos+1 > len(s) {
+				return nil, fmt.Errorf("%w: missing OP_PUSHDATA1 length", ErrMalformedPush)
+			}
+
+			size = int(s[pos])
+			pos++
+		case op == OpPushData2:
+			if pos+2 > len(s) {
+				return nil, fmt.Errorf("%w: missing OP_PUSHDATA2 length", ErrMalformedPush)
+			}
+
+			size = int(binary.LittleEndian.Uint16(s[pos:]))
+			pos += 2
+		default:
+			instructions = append(instructions, Instruction{Op: op})
+			continue
+		}
+
+		if pos+size > len(s) {
+			return nil, fmt.Errorf("%w: push of %d bytes past the end of the script", ErrMalformedPush, size)
+		}
+
+		instructions = append(instructions, Instruction{Op: op, Data: s[pos : pos+size]})
+		pos += size
+	}
+
+	return instructions, nil
+}
+
+// Builder -> Assembles a script, the first error is kept and returned by Script
+type Builder struct {
+	script Script
+	err    error
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (builder *Builder) AddOp(op Opcode) *Builder {
+	builder.script = append(builder.script, byte(op))
+	return builder
+}
+
+// AddData -> Pushes data with the smallest push opcode
+func (builder *Builder) AddData(data []byte) *Builder {
+	switch size := len(data); {
+	case size == 0:
+		builder.script = append(builder.script, byte(OpFalse))
+	case size < int(OpPushData1):
+		builder.script = append(builder.script, byte(size))
+	case size <= 0xff:
+		builder.script = append(builder.script, byte(OpPushData1), byte(size))
+	case size <= MaxElementSize:
+		builder.script = append(builder.script, byte(OpPushData2))
+		builder.script = binary.LittleEndian.AppendUint16(builder.script, uint16(size))
+	default:
+		if builder.err == nil {
+			builder.err = fmt.Errorf("%w: push of %d bytes", ErrElementTooLarge, size)
+		}
+		return builder
+	}
+
+	builder.script = append(builder.script, data...)
+	return builder
+}
+
+// AddInt -> Pushes n, as OP_0, OP_1NEGATE or OP_1 to OP_16 where possible
+func (builder *Builder) AddInt(n int64) *Builder {
+	switch {
+	case n == 0:
+		return builder.AddOp(OpFalse)
+	case n == -1:
+		return builder.AddOp(Op1Negate)
+	case n >= 1 && n <= 16:
+		return builder.AddOp(OpTrue + Opcode(n-1))
+	default:
+		return builder.AddData(EncodeNum(n))
+	}
+}
+
+func (builder *Builder) Script() (Script, error) {
+	if builder.err != nil {
+		return nil, builder.err
+	}
+
+	if len(builder.script) > MaxScriptSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrScriptTooLarge, len(builder.script))
+	}
+
+	return builder.script, nil
+}
+
+// EncodeNum -> Minimal little endian sign-magnitude encoding of n, empty for zero
+func EncodeNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	negative := n < 0
+	magnitude := uint64(n)
+	if negative {
+		magnitude = uint64(-n)
+	}
+
+	var encoded []byte
+	for magnitude > 0 {
+		encoded = append(encoded, byte(magnitude&0xff))
+		magnitude >>= 8
+	}
+
+	// The top bit of the last byte is the sign, add a byte if the magnitude uses it
+	if encoded[len(encoded)-1]&0x80 != 0 {
+		encoded = append(encoded, 0)
+	}
+
+	if negative {
+		encoded[len(encoded)-1] |= 0x80
+	}
+
+	return encoded
+}
+
+// DecodeNum -> Number of a minimally encoded operand of at most 8 bytes
+func DecodeNum(encoded []byte) (int64, error) {
+	if len(encoded) > maxNumSize {
+		return 0, fmt.Errorf("%w: %d bytes", ErrInvalidNumber, len(encoded))
+	}
+
+	if len(encoded) == 0 {
+		return 0, nil
+	}
+
+	// A zero last byte is only allowed to make room for the sign bit
+	last := encoded[len(encoded)-1]
+	if last&0x7f == 0 && (len(encoded) == 1 || encoded[len(encoded)-2]&0x80 == 0) {
+		return 0, fmt.Errorf("%w: not minimally encoded", ErrInvalidNumber)
+	}
+
+	var magnitude uint64
+	for idx := len(encoded) - 1; idx >= 0; idx-- {
+		b := encoded[idx]
+		if idx == len(encoded)-1 {
+			b &= 0x7f
+		}
+
+		magnitude = magnitude<<8 | uint64(b)
+	}
+
+	if last&0x80 != 0 {
+		return -int64(magnitude), nil
+	}
+
+	return int64(magnitude), nil
+}
+
+var (
+	ErrScriptTooLarge        = errors.New("script too large")
+	ErrUnknownOpcode         = errors.New("unknown opcode")
+	ErrMalformedPush         = errors.New("malformed push")
+	ErrElementTooLarge       = errors.New("stack item too large")
+	ErrStackOverflow         = errors.New("stack size limit exceeded")
+	ErrStackUnderflow        = errors.New("not enough items on the stack")
+	ErrOpLimit               = errors.New("opcode limit exceeded")
+	ErrInvalidNumber         = errors.New("invalid number")
+	ErrNumberOverflow        = errors.New("number overflow")
+	ErrUnbalancedConditional = errors.New("unbalanced conditional")
+	ErrVerifyFailed          = errors.New("verify failed")
+	ErrEarlyReturn           = errors.New("script returned early")
+	ErrInvalidSignature      = errors.New("invalid signature")
+	ErrInvalidPublicKey      = errors.New("invalid public key")
+	ErrInvalidKeyCount       = errors.New("invalid key count")
+	ErrLockNotReached        = errors.New("lock not reached")
+	ErrNegativeLock          = errors.New("negative lock")
+	ErrEvalFalse             = errors.New("script evaluated to false")
+	ErrCleanStack            = errors.New("stack must hold exactly one item after execution")
+)
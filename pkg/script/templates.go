@@ -0,0 +1,78 @@
+package script
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// SingleKeyScript -> <pubkey> OP_CHECKSIG, spent with witness [<sig>]
+func SingleKeyScript(pubKeyHex string) (Script, error) {
+	pubKey, err := decodePublicKey(pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBuilder().AddData(pubKey).AddOp(OpCheckSig).Script()
+}
+
+// MultisigScript -> <M> <pubkey1>..<pubkeyN> <N> OP_CHECKMULTISIG, spent with
+// witness [<sig1>..<sigM>], the signatures in the order of their keys
+func MultisigScript(threshold int, pubKeysHex []string) (Script, error) {
+	if len(pubKeysHex) == 0 || len(pubKeysHex) > MaxMultisigKeys {
+		return nil, fmt.Errorf("%w: %d keys", ErrInvalidKeyCount, len(pubKeysHex))
+	}
+
+	if threshold < 1 || threshold > len(pubKeysHex) {
+		return nil, fmt.Errorf("threshold must be between 1 and %d", len(pubKeysHex))
+	}
+
+	builder := NewBuilder().AddInt(int64(threshold))
+	for _, pubKeyHex := range pubKeysHex {
+		pubKey, err := decodePublicKey(pubKeyHex)
+		if err != nil {
+			return nil, err
+		}
+
+		builder.AddData(pubKey)
+	}
+
+	return builder.AddInt(int64(len(pubKeysHex))).AddOp(OpCheckMultisig).Script()
+}
+
+// HashLockScript -> OP_SHA256 <hash> OP_EQUALVERIFY <pubkey> OP_CHECKSIG, spent with
+// witness [<sig> <preimage>]
+func HashLockScript(hashLockHex, pubKeyHex string) (Script, error) {
+	hashLock, err := hex.DecodeString(hashLockHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash lock hex: %w", err)
+	}
+
+	pubKey, err := decodePublicKey(pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBuilder().AddOp(OpSHA256).AddData(hashLock).AddOp(OpEqualVerify).
+		AddData(pubKey).AddOp(OpCheckSig).Script()
+}
+
+// HeightLockScript -> <height> OP_CHECKHEIGHTVERIFY OP_DROP <pubkey> OP_CHECKSIG,
+// spendable with witness [<sig>] from block height on
+func HeightLockScript(height int64, pubKeyHex string) (Script, error) {
+	pubKey, err := decodePublicKey(pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBuilder().AddInt(height).AddOp(OpCheckHeightVerify).AddOp(OpDrop).
+		AddData(pubKey).AddOp(OpCheckSig).Script()
+}
+
+func decodePublicKey(pubKeyHex string) ([]byte, error) {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key hex: %w", err)
+	}
+
+	return pubKey, nil
+}
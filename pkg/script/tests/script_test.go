@@ -0,0 +1,480 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Nikolat27/simple_blockchain/pkg/CryptoGraphy"
+	"github.com/Nikolat27/simple_blockchain/pkg/script"
+)
+
+var sigHash = sha256.Sum256([]byte("spending tx"))
+
+func newContext() *script.Context {
+	return &script.Context{SigHash: sigHash[:], Height: 100, Time: 1_700_000_000_000}
+}
+
+func generateKeyPair(t *testing.T) *CryptoGraphy.KeyPair {
+	t.Helper()
+
+	keyPair, err := CryptoGraphy.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	return keyPair
+}
+
+func assemble(t *testing.T, asm string) script.Script {
+	t.Helper()
+
+	s, err := script.Assemble(asm)
+	if err != nil {
+		t.Fatalf("Failed to assemble %q: %v", asm, err)
+	}
+
+	return s
+}
+
+// checkResult -> err must be nil if expected is nil, or wrap expected
+func checkResult(t *testing.T, err, expected error) {
+	t.Helper()
+
+	if expected == nil {
+		if err != nil {
+			t.Errorf("Expected success, got %v", err)
+		}
+		return
+	}
+
+	if !errors.Is(err, expected) {
+		t.Errorf("Expected %v, got %v", expected, err)
+	}
+}
+
+func TestExecute_Opcodes(t *testing.T) {
+	maxNum := hex.EncodeToString(script.EncodeNum(math.MaxInt64))
+	minNum := hex.EncodeToString(script.EncodeNum(-math.MaxInt64))
+
+	tests := []struct {
+		name     string
+		asm      string
+		expected error
+	}{
+		// Constants and the final stack
+		{"True", "OP_1", nil},
+		{"False", "OP_0", script.ErrEvalFalse},
+		{"Empty script", "", script.ErrCleanStack},
+		{"Two items left", "OP_1 OP_1", script.ErrCleanStack},
+		{"Negative zero is false", "80", script.ErrEvalFalse},
+		{"Non-zero data is true", "0001", nil},
+		{"OP_16", "OP_16 10 OP_NUMEQUAL", nil},
+		{"OP_1NEGATE", "OP_1NEGATE 81 OP_EQUAL", nil},
+		{"OP_NOP", "OP_NOP OP_1", nil},
+
+		// Flow control
+		{"If taken", "OP_1 OP_IF OP_2 OP_ELSE OP_3 OP_ENDIF OP_2 OP_NUMEQUAL", nil},
+		{"Else taken", "OP_0 OP_IF OP_2 OP_ELSE OP_3 OP_ENDIF OP_3 OP_NUMEQUAL", nil},
+		{"NotIf", "OP_0 OP_NOTIF OP_1 OP_ELSE OP_0 OP_ENDIF", nil},
+		{"Nested", "OP_1 OP_IF OP_0 OP_IF OP_RETURN OP_ELSE OP_1 OP_ENDIF OP_ENDIF", nil},
+		{"Skipped branch is not executed", "OP_0 OP_IF OP_RETURN OP_DROP OP_ENDIF OP_1", nil},
+		{"If on empty stack", "OP_IF OP_ENDIF OP_1", script.ErrStackUnderflow},
+		{"Missing endif", "OP_1 OP_IF OP_1", script.ErrUnbalancedConditional},
+		{"Endif without if", "OP_ENDIF OP_1", script.ErrUnbalancedConditional},
+		{"Else without if", "OP_1 OP_ELSE", script.ErrUnbalancedConditional},
+		{"Verify true", "OP_1 OP_VERIFY OP_1", nil},
+		{"Verify false", "OP_0 OP_VERIFY OP_1", script.ErrVerifyFailed},
+		{"Return", "OP_1 OP_RETURN", script.ErrEarlyReturn},
+
+		// Stack
+		{"Dup", "OP_2 OP_DUP OP_NUMEQUAL", nil},
+		{"Dup on empty stack", "OP_DUP", script.ErrStackUnderflow},
+		{"Drop", "OP_0 OP_1 OP_SWAP OP_DROP", nil},
+		{"Drop on empty stack", "OP_DROP OP_1", script.ErrStackUnderflow},
+		{"Over", "OP_1 OP_2 OP_OVER OP_1 OP_NUMEQUALVERIFY OP_2 OP_NUMEQUALVERIFY OP_1 OP_NUMEQUAL", nil},
+		{"Over with one item", "OP_1 OP_OVER", script.ErrStackUnderflow},
+		{"Swap", "OP_1 OP_2 OP_SWAP OP_1 OP_NUMEQUALVERIFY OP_2 OP_NUMEQUAL", nil},
+		{"Swap with one item", "OP_1 OP_SWAP", script.ErrStackUnderflow},
+		{"Size keeps the item", "0a0b0c OP_SIZE OP_3 OP_NUMEQUALVERIFY 0a0b0c OP_EQUAL", nil},
+		{"Size of empty item", "OP_0 OP_SIZE OP_0 OP_NUMEQUALVERIFY OP_SIZE OP_NOT OP_VERIFY OP_DROP OP_1", nil},
+
+		// Comparison
+		{"Equal", "0102 0102 OP_EQUAL", nil},
+		{"Not equal", "0102 0103 OP_EQUAL", script.ErrEvalFalse},
+		{"EqualVerify", "0102 0102 OP_EQUALVERIFY OP_1", nil},
+		{"EqualVerify fails", "0102 0103 OP_EQUALVERIFY OP_1", script.ErrVerifyFailed},
+		{"Equal is bytewise", "OP_0 00 OP_EQUAL", script.ErrEvalFalse},
+
+		// Arithmetic and boolean logic
+		{"Add", "OP_2 OP_3 OP_ADD OP_5 OP_NUMEQUAL", nil},
+		{"Sub to negative", "OP_2 OP_3 OP_SUB OP_1NEGATE OP_NUMEQUAL", nil},
+		{"Add overflow", maxNum + " OP_1 OP_ADD", script.ErrNumberOverflow},
+		{"Sub overflow", minNum + " OP_1 OP_SUB", script.ErrNumberOverflow},
+		{"Not of zero", "OP_0 OP_NOT", nil},
+		{"Not of non-zero", "OP_5 OP_NOT", script.ErrEvalFalse},
+		{"BoolAnd", "OP_1 OP_2 OP_BOOLAND", nil},
+		{"BoolAnd with zero", "OP_1 OP_0 OP_BOOLAND", script.ErrEvalFalse},
+		{"BoolOr", "OP_0 OP_2 OP_BOOLOR", nil},
+		{"BoolOr of zeros", "OP_0 OP_0 OP_BOOLOR", script.ErrEvalFalse},
+		{"NumEqual ignores encoding of zero", "OP_0 OP_0 OP_NUMEQUAL", nil},
+		{"NumEqualVerify fails", "OP_1 OP_2 OP_NUMEQUALVERIFY OP_1", script.ErrVerifyFailed},
+		{"LessThan", "OP_2 OP_3 OP_LESSTHAN", nil},
+		{"LessThan equal values", "OP_3 OP_3 OP_LESSTHAN", script.ErrEvalFalse},
+		{"GreaterThan", "OP_3 OP_2 OP_GREATERTHAN", nil},
+		{"LessThanOrEqual", "OP_3 OP_3 OP_LESSTHANOREQUAL", nil},
+		{"GreaterThanOrEqual", "OP_2 OP_3 OP_GREATERTHANOREQUAL", script.ErrEvalFalse},
+		{"Min", "OP_2 OP_1NEGATE OP_MIN OP_1NEGATE OP_NUMEQUAL", nil},
+		{"Max", "OP_2 OP_1NEGATE OP_MAX OP_2 OP_NUMEQUAL", nil},
+		{"Within", "OP_3 OP_2 OP_4 OP_WITHIN", nil},
+		{"Within is exclusive above", "OP_4 OP_2 OP_4 OP_WITHIN", script.ErrEvalFalse},
+		{"Number too long", "010203040506070809 OP_1 OP_ADD", script.ErrInvalidNumber},
+		{"Number not minimal", "0100 OP_1 OP_ADD", script.ErrInvalidNumber},
+		{"Arithmetic on empty stack", "OP_1 OP_ADD", script.ErrStackUnderflow},
+
+		// Hashes
+		{"SHA256", "616263 OP_SHA256 " + hex.EncodeToString(sha256Of("abc")) + " OP_EQUAL", nil},
+		{"AddrHash", "616263 OP_ADDRHASH " + hex.EncodeToString(sha256Of("abc")[:20]) + " OP_EQUAL", nil},
+
+		// Height and time of the spending block (100 and 1_700_000_000_000)
+		{"Height", "OP_HEIGHT 64 OP_NUMEQUAL", nil},
+		{"Height comparison", "OP_HEIGHT 65 OP_LESSTHAN", nil},
+		{"Time", "OP_TIME " + hex.EncodeToString(script.EncodeNum(1_700_000_000_000)) + " OP_NUMEQUAL", nil},
+		{"CheckHeightVerify reached", "64 OP_CHECKHEIGHTVERIFY", nil},
+		{"CheckHeightVerify not reached", "65 OP_CHECKHEIGHTVERIFY", script.ErrLockNotReached},
+		{"CheckHeightVerify negative", "OP_1NEGATE OP_CHECKHEIGHTVERIFY", script.ErrNegativeLock},
+		{"CheckTimeVerify reached", hex.EncodeToString(script.EncodeNum(1_600_000_000_000)) + " OP_CHECKTIMEVERIFY", nil},
+		{"CheckTimeVerify not reached", hex.EncodeToString(script.EncodeNum(1_800_000_000_000)) + " OP_CHECKTIMEVERIFY", script.ErrLockNotReached},
+		{"CheckHeightVerify on empty stack", "OP_CHECKHEIGHTVERIFY", script.ErrStackUnderflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResult(t, script.Execute(assemble(t, tt.asm), nil, newContext()), tt.expected)
+		})
+	}
+}
+
+func sha256Of(data string) []byte {
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+func TestExecute_CheckSig(t *testing.T) {
+	keyPair := generateKeyPair(t)
+	other := generateKeyPair(t)
+
+	single, err := script.SingleKeyScript(keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	validSig := keyPair.Sign(sigHash[:])
+	otherSig := other.Sign(sigHash[:])
+
+	tests := []struct {
+		name     string
+		script   script.Script
+		witness  [][]byte
+		expected error
+	}{
+		{"Valid signature", single, [][]byte{validSig}, nil},
+		{"Empty signature", single, [][]byte{{}}, script.ErrEvalFalse},
+		{"Signature of another key", single, [][]byte{otherSig}, script.ErrInvalidSignature},
+		{"Missing witness", single, nil, script.ErrStackUnderflow},
+		{"Invalid public key", assemble(t, "0102 OP_CHECKSIG"), [][]byte{validSig}, script.ErrInvalidPublicKey},
+		{"CheckSigVerify", assemble(t, keyPair.GetPublicKeyHex()+" OP_CHECKSIGVERIFY OP_1"), [][]byte{validSig}, nil},
+		{"CheckSigVerify with empty signature", assemble(t, keyPair.GetPublicKeyHex()+" OP_CHECKSIGVERIFY OP_1"), [][]byte{{}}, script.ErrVerifyFailed},
+		{
+			"Key hash",
+			assemble(t, "OP_DUP OP_ADDRHASH "+keyPair.Address+" OP_EQUALVERIFY OP_CHECKSIG"),
+			[][]byte{validSig, keyPair.PublicKey},
+			nil,
+		},
+		{
+			"Key hash with another key",
+			assemble(t, "OP_DUP OP_ADDRHASH "+keyPair.Address+" OP_EQUALVERIFY OP_CHECKSIG"),
+			[][]byte{otherSig, other.PublicKey},
+			script.ErrVerifyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResult(t, script.Execute(tt.script, tt.witness, newContext()), tt.expected)
+		})
+	}
+
+	// The signature commits to the spending tx
+	otherHash := sha256.Sum256([]byte("another tx"))
+	ctx := newContext()
+	ctx.SigHash = otherHash[:]
+	checkResult(t, script.Execute(single, [][]byte{validSig}, ctx), script.ErrInvalidSignature)
+}
+
+func TestExecute_CheckMultisig(t *testing.T) {
+	keys := []*CryptoGraphy.KeyPair{generateKeyPair(t), generateKeyPair(t), generateKeyPair(t)}
+	pubKeys := []string{keys[0].GetPublicKeyHex(), keys[1].GetPublicKeyHex(), keys[2].GetPublicKeyHex()}
+
+	twoOfThree, err := script.MultisigScript(2, pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	sigs := make([][]byte, len(keys))
+	for idx, keyPair := range keys {
+		sigs[idx] = keyPair.Sign(sigHash[:])
+	}
+
+	tests := []struct {
+		name     string
+		witness  [][]byte
+		expected error
+	}{
+		{"First and second key", [][]byte{sigs[0], sigs[1]}, nil},
+		{"First and third key", [][]byte{sigs[0], sigs[2]}, nil},
+		{"Second and third key", [][]byte{sigs[1], sigs[2]}, nil},
+		{"Out of key order", [][]byte{sigs[2], sigs[0]}, script.ErrInvalidSignature},
+		{"Same signature twice", [][]byte{sigs[1], sigs[1]}, script.ErrInvalidSignature},
+		{"Empty signatures", [][]byte{{}, {}}, script.ErrEvalFalse},
+		{"One signature", [][]byte{sigs[0]}, script.ErrStackUnderflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResult(t, script.Execute(twoOfThree, tt.witness, newContext()), tt.expected)
+		})
+	}
+
+	if _, err := script.MultisigScript(4, pubKeys); err == nil {
+		t.Error("Expected threshold above the key count to be rejected")
+	}
+
+	tooManyKeys := assemble(t, "OP_0 OP_0 "+hex.EncodeToString(script.EncodeNum(script.MaxMultisigKeys+1))+" OP_CHECKMULTISIG")
+	checkResult(t, script.Execute(tooManyKeys, nil, newContext()), script.ErrInvalidKeyCount)
+
+	moreSigsThanKeys := assemble(t, "OP_2 "+pubKeys[0]+" OP_1 OP_CHECKMULTISIG")
+	checkResult(t, script.Execute(moreSigsThanKeys, [][]byte{sigs[0], sigs[0]}, newContext()), script.ErrInvalidKeyCount)
+
+	verify := assemble(t, "OP_1 "+pubKeys[0]+" OP_1 OP_CHECKMULTISIGVERIFY OP_1")
+	checkResult(t, script.Execute(verify, [][]byte{sigs[0]}, newContext()), nil)
+}
+
+func TestExecute_Templates(t *testing.T) {
+	keyPair := generateKeyPair(t)
+	sig := keyPair.Sign(sigHash[:])
+
+	preimage := []byte("swap secret")
+	hashLock, err := script.HashLockScript(hex.EncodeToString(sha256Of(string(preimage))), keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	checkResult(t, script.Execute(hashLock, [][]byte{sig, preimage}, newContext()), nil)
+	checkResult(t, script.Execute(hashLock, [][]byte{sig, []byte("wrong")}, newContext()), script.ErrVerifyFailed)
+
+	heightLock, err := script.HeightLockScript(150, keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	checkResult(t, script.Execute(heightLock, [][]byte{sig}, newContext()), script.ErrLockNotReached)
+
+	ctx := newContext()
+	ctx.Height = 150
+	checkResult(t, script.Execute(heightLock, [][]byte{sig}, ctx), nil)
+}
+
+func TestExecute_Limits(t *testing.T) {
+	ops := func(count int) string {
+		return strings.Repeat("OP_NOP ", count) + "OP_1"
+	}
+
+	checkResult(t, script.Execute(assemble(t, ops(script.MaxOps)), nil, newContext()), nil)
+	checkResult(t, script.Execute(assemble(t, ops(script.MaxOps+1)), nil, newContext()), script.ErrOpLimit)
+
+	// Push opcodes do not count
+	pushes := strings.Repeat("OP_1 OP_DROP ", script.MaxOps-1) + "OP_1"
+	checkResult(t, script.Execute(assemble(t, pushes), nil, newContext()), nil)
+
+	// Skipped opcodes count as well
+	skipped := "OP_0 OP_IF " + strings.Repeat("OP_NOP ", script.MaxOps) + "OP_ENDIF OP_1"
+	checkResult(t, script.Execute(assemble(t, skipped), nil, newContext()), script.ErrOpLimit)
+
+	// OP_CHECKMULTISIG counts its keys
+	keyPair := generateKeyPair(t)
+	keys := make([]string, script.MaxMultisigKeys)
+	for idx := range keys {
+		keys[idx] = keyPair.GetPublicKeyHex()
+	}
+
+	multisig, err := script.MultisigScript(1, keys)
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	multisigs := bytes.Repeat(append(append(script.Script{}, multisig...), byte(script.OpDrop)), 10)
+	witness := make([][]byte, 10)
+	for idx := range witness {
+		witness[idx] = keyPair.Sign(sigHash[:])
+	}
+	checkResult(t, script.Execute(append(multisigs, byte(script.OpTrue)), witness, newContext()), script.ErrOpLimit)
+
+	tooLarge := bytes.Repeat([]byte{byte(script.OpNop)}, script.MaxScriptSize+1)
+	checkResult(t, script.Execute(tooLarge, nil, newContext()), script.ErrScriptTooLarge)
+
+	if _, err := script.NewBuilder().AddData(make([]byte, script.MaxElementSize+1)).Script(); !errors.Is(err, script.ErrElementTooLarge) {
+		t.Errorf("Expected %v, got %v", script.ErrElementTooLarge, err)
+	}
+
+	bigWitness := [][]byte{make([]byte, script.MaxElementSize+1)}
+	checkResult(t, script.Execute(assemble(t, "OP_DROP OP_1"), bigWitness, newContext()), script.ErrElementTooLarge)
+
+	fullStack := make([][]byte, script.MaxStackSize)
+	checkResult(t, script.Execute(assemble(t, "OP_1"), fullStack, newContext()), script.ErrStackOverflow)
+	checkResult(t, script.Execute(assemble(t, "OP_1"), append(fullStack, nil), newContext()), script.ErrStackOverflow)
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      script.Script
+		expected error
+	}{
+		{"Unknown opcode", script.Script{0xff}, script.ErrUnknownOpcode},
+		{"Unknown opcode in skipped branch", script.Script{byte(script.OpFalse), byte(script.OpIf), 0xff, byte(script.OpEndIf), byte(script.OpTrue)}, script.ErrUnknownOpcode},
+		{"Truncated push", script.Script{0x05, 0x01}, script.ErrMalformedPush},
+		{"Missing PUSHDATA1 length", script.Script{byte(script.OpPushData1)}, script.ErrMalformedPush},
+		{"Truncated PUSHDATA2", script.Script{byte(script.OpPushData2), 0x10, 0x00, 0x01}, script.ErrMalformedPush},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResult(t, script.Execute(tt.raw, nil, newContext()), tt.expected)
+
+			if _, err := script.Disassemble(tt.raw); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected disassembly to fail with %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNumEncoding(t *testing.T) {
+	values := []int64{0, 1, -1, 16, 127, -127, 128, -128, 255, 256, 32767, -32768, 1 << 40, math.MaxInt64, -math.MaxInt64}
+
+	for _, value := range values {
+		encoded := script.EncodeNum(value)
+
+		decoded, err := script.DecodeNum(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode %d (%x): %v", value, encoded, err)
+		}
+
+		if decoded != value {
+			t.Errorf("Expected %d, got %d from %x", value, decoded, encoded)
+		}
+	}
+
+	expected := map[int64]string{0: "", 1: "01", -1: "81", 127: "7f", 128: "8000", -128: "8080", 256: "0001"}
+	for value, encodedHex := range expected {
+		if got := hex.EncodeToString(script.EncodeNum(value)); got != encodedHex {
+			t.Errorf("Expected %d to encode as %q, got %q", value, encodedHex, got)
+		}
+	}
+
+	for _, invalid := range []string{"00", "80", "0100", "7f00", "010203040506070809"} {
+		raw, _ := hex.DecodeString(invalid)
+		if _, err := script.DecodeNum(raw); !errors.Is(err, script.ErrInvalidNumber) {
+			t.Errorf("Expected %s to be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	keyPair := generateKeyPair(t)
+
+	heightLock, err := script.HeightLockScript(150, keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	asm, err := script.Disassemble(heightLock)
+	if err != nil {
+		t.Fatalf("Failed to disassemble: %v", err)
+	}
+
+	expected := "9600 OP_CHECKHEIGHTVERIFY OP_DROP " + keyPair.GetPublicKeyHex() + " OP_CHECKSIG"
+	if asm != expected {
+		t.Errorf("Expected %q, got %q", expected, asm)
+	}
+
+	scripts := []string{
+		"OP_0 OP_1NEGATE OP_1 OP_16 OP_IF OP_NOTIF OP_ELSE OP_ENDIF OP_VERIFY OP_RETURN OP_NOP",
+		"OP_DROP OP_DUP OP_OVER OP_SWAP OP_SIZE OP_EQUAL OP_EQUALVERIFY OP_NOT OP_ADD OP_SUB",
+		"OP_BOOLAND OP_BOOLOR OP_NUMEQUAL OP_NUMEQUALVERIFY OP_LESSTHAN OP_GREATERTHAN",
+		"OP_LESSTHANOREQUAL OP_GREATERTHANOREQUAL OP_MIN OP_MAX OP_WITHIN OP_SHA256 OP_ADDRHASH",
+		"OP_CHECKSIG OP_CHECKSIGVERIFY OP_CHECKMULTISIG OP_CHECKMULTISIGVERIFY",
+		"OP_CHECKHEIGHTVERIFY OP_CHECKTIMEVERIFY OP_HEIGHT OP_TIME",
+		strings.Repeat("ab", 75) + " " + strings.Repeat("cd", 76) + " " + strings.Repeat("ef", 300),
+	}
+
+	for _, asm := range scripts {
+		s := assemble(t, asm)
+
+		disassembled, err := script.Disassemble(s)
+		if err != nil {
+			t.Fatalf("Failed to disassemble %q: %v", asm, err)
+		}
+
+		if disassembled != asm {
+			t.Errorf("Expected %q, got %q", asm, disassembled)
+		}
+
+		if !bytes.Equal(assemble(t, disassembled), s) {
+			t.Errorf("Reassembling %q changed the script", asm)
+		}
+	}
+
+	aliases := assemble(t, "OP_FALSE OP_TRUE")
+	if !bytes.Equal(aliases, script.Script{byte(script.OpFalse), byte(script.OpTrue)}) {
+		t.Errorf("Unexpected script for aliases: %x", aliases)
+	}
+
+	for _, invalid := range []string{"OP_FOO", "OP_PUSHDATA1", "xyz"} {
+		if _, err := script.Assemble(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestScriptAddress(t *testing.T) {
+	keyPair := generateKeyPair(t)
+
+	single, err := script.SingleKeyScript(keyPair.GetPublicKeyHex())
+	if err != nil {
+		t.Fatalf("Failed to build script: %v", err)
+	}
+
+	again, _ := script.SingleKeyScript(keyPair.GetPublicKeyHex())
+	if single.Address() != again.Address() {
+		t.Error("Same script should map to the same address")
+	}
+
+	if single.Address() == keyPair.Address {
+		t.Error("Script address should differ from the key address")
+	}
+
+	heightLock, _ := script.HeightLockScript(1, keyPair.GetPublicKeyHex())
+	if single.Address() == heightLock.Address() {
+		t.Error("Different scripts should map to different addresses")
+	}
+
+	decoded, err := script.Decode(single.String())
+	if err != nil || !bytes.Equal(decoded, single) {
+		t.Errorf("Hex round trip failed: %v", err)
+	}
+}